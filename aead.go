@@ -1,14 +1,14 @@
 package natrium
 
 // #cgo darwin CFLAGS: -I/usr/local/include
-// #cgo darwin LDFLAGS: /usr/local/lib/libsodium.a
-// #cgo linux windows android LDFLAGS: -Wl,-Bstatic -lsodium -Wl,-Bdynamic
 // #include <stdio.h>
 // #include <sodium.h>
 import "C"
 import (
 	"crypto/cipher"
+	"encoding/binary"
 	"errors"
+	"fmt"
 )
 
 type natrAEAD struct {
@@ -42,7 +42,7 @@ func (ctx *natrAEAD) Open(dst, nonce, ciphertext, data []byte) ([]byte, error) {
 		g2cbt(ciphertext), C.ulonglong(len(ciphertext)), g2cbt(data), C.ulonglong(len(data)),
 		g2cbt(nonce), g2cbt(ctx.key[:]))
 	if rv != 0 {
-		return nil, errors.New("MAC error")
+		return nil, ErrDecryptionFailed
 	}
 	return append(dst, out...), nil
 }
@@ -74,6 +74,199 @@ func AEAD(key []byte) cipher.AEAD {
 	return &natrAEAD{arr}
 }
 
+// AEADKey represents a key for XChaCha20-Poly1305-IETF authenticated
+// encryption with associated data. Its 24-byte nonces are long enough to be
+// generated randomly with NewNonce. As with SecretKey, use Clone rather than
+// assignment to get a copy of the key.
+type AEADKey []byte
+
+// String returns a redacted placeholder so the key is not printed by accident.
+func (k AEADKey) String() string {
+	return redacted("aeadkey", k)
+}
+
+// Format implements fmt.Formatter, printing String for every verb.
+func (k AEADKey) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, k.String())
+}
+
+// AEADKeyLength is the length of an AEADKey.
+const AEADKeyLength int = C.crypto_aead_xchacha20poly1305_ietf_KEYBYTES
+
+// AEADNonceLength is the length of the nonce passed to AEADKey.Seal and Open.
+const AEADNonceLength int = C.crypto_aead_xchacha20poly1305_ietf_NPUBBYTES
+
+// AEADTagLength is the length of the authentication tag AEADKey.Seal adds to
+// a message.
+const AEADTagLength int = C.crypto_aead_xchacha20poly1305_ietf_ABYTES
+
+// NewAEADKey returns a length-checked copy of b as an AEADKey.
+func NewAEADKey(b []byte) (AEADKey, error) {
+	return copyKey(b, AEADKeyLength, "AEAD key")
+}
+
+// AEADKeyFromHex parses an AEAD key written in hex.
+func AEADKeyFromHex(s string) (AEADKey, error) {
+	return keyFromHex(s, "", AEADKeyLength, "AEAD key")
+}
+
+// MustAEADKeyFromHex is like AEADKeyFromHex, but panics on error.
+func MustAEADKeyFromHex(s string) AEADKey {
+	return mustKey(AEADKeyFromHex(s))
+}
+
+// Equal reports, in constant time, whether two AEAD keys are the same.
+func (k AEADKey) Equal(other AEADKey) bool {
+	return MemCmp(k, other)
+}
+
+// Clone returns an independent copy of the key.
+func (k AEADKey) Clone() AEADKey {
+	return append(AEADKey(nil), k...)
+}
+
+// GenerateAEADKey generates a random AEADKey.
+func GenerateAEADKey() AEADKey {
+	mustInit()
+	toret := make([]byte, AEADKeyLength)
+	RandBytes(toret)
+	return toret
+}
+
+// NewNonce returns a random nonce of AEADNonceLength bytes for use with the
+// key. At 24 bytes, random nonces are safe no matter how many messages are
+// sealed with one key.
+func (k AEADKey) NewNonce() []byte {
+	return RandomBytes(AEADNonceLength)
+}
+
+// Destroy wipes the key from memory, like SecretKey.Destroy.
+func (k AEADKey) Destroy() {
+	wipe(k)
+}
+
+func (k AEADKey) check(nonce []byte) {
+	if len(k) != AEADKeyLength {
+		panic("AEAD key has the wrong length")
+	}
+	if len(nonce) != AEADNonceLength {
+		panic("AEAD nonce has the wrong length")
+	}
+}
+
+// Seal encrypts and authenticates a message, additionally authenticating but
+// not encrypting the associated data ad, which may be nil.
+func (k AEADKey) Seal(message, ad, nonce []byte) []byte {
+	k.check(nonce)
+	out := make([]byte, len(message)+AEADTagLength)
+	rv := C.crypto_aead_xchacha20poly1305_ietf_encrypt(g2cbt(out), nil,
+		g2cbt(message), C.ulonglong(len(message)), g2cbt(ad), C.ulonglong(len(ad)),
+		nil, g2cbt(nonce), g2cbt(k))
+	if rv != 0 {
+		panic("crypto_aead_xchacha20poly1305_ietf_encrypt returned non-zero")
+	}
+	return out
+}
+
+// CiphertextLen returns the length of the output of Seal for a message of
+// plaintextLen bytes. The associated data is not part of the output.
+func (k AEADKey) CiphertextLen(plaintextLen int) int {
+	return plaintextLen + AEADTagLength
+}
+
+// Open decrypts and verifies a ciphertext produced by Seal with the same
+// associated data.
+func (k AEADKey) Open(ciphertext, ad, nonce []byte) ([]byte, error) {
+	k.check(nonce)
+	if len(ciphertext) < AEADTagLength {
+		return nil, fmt.Errorf("%w: ciphertext too short", ErrDecryptionFailed)
+	}
+	out := make([]byte, len(ciphertext)-AEADTagLength)
+	rv := C.crypto_aead_xchacha20poly1305_ietf_decrypt(g2cbt(out), nil, nil,
+		g2cbt(ciphertext), C.ulonglong(len(ciphertext)), g2cbt(ad), C.ulonglong(len(ad)),
+		g2cbt(nonce), g2cbt(k))
+	if rv != 0 {
+		return nil, ErrDecryptionFailed
+	}
+	return out, nil
+}
+
+// SealDetached is like Seal, but returns the authentication tag separately
+// from the ciphertext, which is exactly as long as the message.
+func (k AEADKey) SealDetached(message, ad, nonce []byte) (ciphertext, tag []byte) {
+	k.check(nonce)
+	ciphertext = make([]byte, len(message))
+	tag = make([]byte, AEADTagLength)
+	rv := C.crypto_aead_xchacha20poly1305_ietf_encrypt_detached(g2cbt(ciphertext),
+		g2cbt(tag), nil, g2cbt(message), C.ulonglong(len(message)),
+		g2cbt(ad), C.ulonglong(len(ad)), nil, g2cbt(nonce), g2cbt(k))
+	if rv != 0 {
+		panic("crypto_aead_xchacha20poly1305_ietf_encrypt_detached returned non-zero")
+	}
+	return ciphertext, tag
+}
+
+// OpenDetached decrypts and verifies a ciphertext and tag produced by
+// SealDetached.
+func (k AEADKey) OpenDetached(ciphertext, tag, ad, nonce []byte) ([]byte, error) {
+	k.check(nonce)
+	if len(tag) != AEADTagLength {
+		return nil, fmt.Errorf("%w: AEAD tag has the wrong length", ErrDecryptionFailed)
+	}
+	out := make([]byte, len(ciphertext))
+	rv := C.crypto_aead_xchacha20poly1305_ietf_decrypt_detached(g2cbt(out), nil,
+		g2cbt(ciphertext), C.ulonglong(len(ciphertext)), g2cbt(tag),
+		g2cbt(ad), C.ulonglong(len(ad)), g2cbt(nonce), g2cbt(k))
+	if rv != 0 {
+		return nil, ErrDecryptionFailed
+	}
+	return out, nil
+}
+
+// AEADCommitmentLength is the number of bytes SealCommitting adds to a
+// message on top of AEADTagLength.
+const AEADCommitmentLength = 32
+
+// commitment returns the key commitment for nonce: BLAKE2b-256 of the label
+// "natrium aead commitment" followed by the nonce, keyed with k.
+func (k AEADKey) commitment(nonce []byte) []byte {
+	return genericHash(append([]byte("natrium aead commitment"), nonce...), k, AEADCommitmentLength)
+}
+
+// SealCommitting is like Seal, but makes the ciphertext key-committing by
+// prepending a commitment to the key and nonce, so the output is
+// AEADCommitmentLength+AEADTagLength bytes longer than the message.
+//
+// Poly1305, like GCM's GHASH, is not collision resistant in the key: someone
+// who picks the keys can craft one ciphertext that opens successfully, to
+// different plaintexts, under several of them. That matters whenever the
+// attacker can influence which key a recipient tries, as with password-derived
+// keys, multi-recipient envelopes or key rotation, where it enables
+// partitioning-oracle attacks and messages that read differently to different
+// recipients. With the commitment, a ciphertext opens under at most one key.
+//
+// The commitment is the 32-byte BLAKE2b of "natrium aead commitment" followed
+// by the nonce, keyed with k, and the rest is the output of Seal.
+func (k AEADKey) SealCommitting(message, ad, nonce []byte) []byte {
+	k.check(nonce)
+	return append(k.commitment(nonce), k.Seal(message, ad, nonce)...)
+}
+
+// OpenCommitting decrypts and verifies a ciphertext produced by
+// SealCommitting. The commitment is compared in constant time before the
+// ciphertext is authenticated, and a mismatch gives ErrDecryptionFailed just
+// like a bad tag.
+func (k AEADKey) OpenCommitting(ciphertext, ad, nonce []byte) ([]byte, error) {
+	k.check(nonce)
+	if len(ciphertext) < AEADCommitmentLength+AEADTagLength {
+		return nil, fmt.Errorf("%w: ciphertext too short", ErrDecryptionFailed)
+	}
+	if !MemCmp(ciphertext[:AEADCommitmentLength], k.commitment(nonce)) {
+		return nil, ErrDecryptionFailed
+	}
+	return k.Open(ciphertext[AEADCommitmentLength:], ad, nonce)
+}
+
 type dummyAEAD struct{}
 
 func (ctx *dummyAEAD) Seal(dst, nonce, plaintext, data []byte) []byte {
@@ -97,3 +290,108 @@ func init() {
 	_AEADNonceLength = int(C.crypto_aead_chacha20poly1305_npubbytes())
 	_AEADOverheadBytes = int(C.crypto_aead_chacha20poly1305_abytes())
 }
+
+// GobEncode implements the gob.GobEncoder interface.
+func (k AEADKey) GobEncode() ([]byte, error) {
+	return encodeKeyGob(gobAEADKey, k)
+}
+
+// GobDecode implements the gob.GobDecoder interface, rejecting anything that
+// is not an AEAD key of the right length.
+func (k *AEADKey) GobDecode(data []byte) error {
+	raw, err := decodeKeyGob(data, gobAEADKey, AEADKeyLength, "AEAD key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, returning a
+// copy of the raw key.
+func (k AEADKey) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), k...), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. The
+// data is length-checked and copied, never aliased.
+func (k *AEADKey) UnmarshalBinary(data []byte) error {
+	raw, err := copyKey(data, AEADKeyLength, "AEAD key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, encoding the
+// key as lowercase hex.
+func (k AEADKey) MarshalText() ([]byte, error) {
+	return []byte(BinToHex(k)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, reversing
+// MarshalText.
+func (k *AEADKey) UnmarshalText(text []byte) error {
+	raw, err := unmarshalKeyText(text, AEADKeyLength, "AEAD key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// AEADKeyPEMType is the PEM block type used by Encode with EncodingPEM.
+const AEADKeyPEMType = "NATRIUM AEAD KEY"
+
+// Encode serializes the key in the given encoding, as raw bytes, hex, PEM or
+// base64. Every encoding holds the secret in the clear.
+func (k AEADKey) Encode(enc Encoding) ([]byte, error) {
+	return encodeKey(k, enc, AEADKeyPEMType, AEADKeyLength, "AEAD key")
+}
+
+// DecodeAEADKey parses a key written by Encode with the same encoding. Data of
+// the wrong length, or a PEM block of another type, gives an error.
+func DecodeAEADKey(data []byte, enc Encoding) (AEADKey, error) {
+	return decodeKey(data, enc, AEADKeyPEMType, AEADKeyLength, "AEAD key")
+}
+
+// PackAD encodes a list of fields as associated data, each preceded by its
+// length as an 8-byte big-endian number, so that no field is too long to
+// encode. Because of the lengths, different lists never encode to the same
+// bytes, as they easily could by simple concatenation: ("ab", "c") and
+// ("a", "bc") give different associated data.
+func PackAD(fields ...[]byte) []byte {
+	n := 0
+	for _, f := range fields {
+		n += 8 + len(f)
+	}
+	toret := make([]byte, 0, n)
+	var length [8]byte
+	for _, f := range fields {
+		binary.BigEndian.PutUint64(length[:], uint64(len(f)))
+		toret = append(append(toret, length[:]...), f...)
+	}
+	return toret
+}
+
+// UnpackAD reverses PackAD, returning an error if ad is not a well-formed
+// encoding. The fields alias ad.
+func UnpackAD(ad []byte) ([][]byte, error) {
+	var fields [][]byte
+	for len(ad) > 0 {
+		if len(ad) < 8 {
+			return nil, errMalformedAD
+		}
+		n := binary.BigEndian.Uint64(ad)
+		ad = ad[8:]
+		if n > uint64(len(ad)) {
+			return nil, errMalformedAD
+		}
+		fields = append(fields, ad[:n:n])
+		ad = ad[n:]
+	}
+	return fields, nil
+}
+
+var errMalformedAD = errors.New("malformed packed associated data")
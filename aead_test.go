@@ -1,8 +1,10 @@
 package natrium
 
 import (
+	"bytes"
 	"crypto/cipher"
 	"crypto/aes"
+	"errors"
 	"testing"
 )
 
@@ -79,3 +81,129 @@ func BenchmarkAEAD_AES(b *testing.B) {
 		thingy.Seal(make([]byte, 0), nonce, tocrypt, nil)
 	}
 }
+
+func TestAEADKey(t *testing.T) {
+	key := GenerateAEADKey()
+	nonce := key.NewNonce()
+	ad := []byte("header")
+	ciphertext := key.Seal([]byte("Hello World"), ad, nonce)
+	plaintext, err := key.Open(ciphertext, ad, nonce)
+	if err != nil || string(plaintext) != "Hello World" {
+		t.FailNow()
+	}
+	if _, err := key.Open(ciphertext, []byte("headex"), nonce); err == nil {
+		t.FailNow()
+	}
+	ciphertext[0] ^= 1
+	if _, err := key.Open(ciphertext, ad, nonce); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestAEADDetached(t *testing.T) {
+	key := GenerateAEADKey()
+	nonce := key.NewNonce()
+	message := []byte("Hello World")
+	ciphertext, tag := key.SealDetached(message, []byte("ad"), nonce)
+	if len(ciphertext) != len(message) || len(tag) != AEADTagLength {
+		t.FailNow()
+	}
+	combined := key.Seal(message, []byte("ad"), nonce)
+	if string(combined) != string(ciphertext)+string(tag) {
+		t.FailNow()
+	}
+	plain, err := key.OpenDetached(ciphertext, tag, []byte("ad"), nonce)
+	if err != nil || string(plain) != "Hello World" {
+		t.FailNow()
+	}
+	tag[0] ^= 1
+	if _, err := key.OpenDetached(ciphertext, tag, []byte("ad"), nonce); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestPackAD(t *testing.T) {
+	if bytes.Equal(PackAD([]byte("ab"), []byte("c")), PackAD([]byte("a"), []byte("bc"))) {
+		t.FailNow()
+	}
+	ad := PackAD([]byte{1}, nil, []byte("message"), []byte{0, 0, 0, 42})
+	fields, err := UnpackAD(ad)
+	if err != nil || len(fields) != 4 || fields[0][0] != 1 || len(fields[1]) != 0 ||
+		string(fields[2]) != "message" || fields[3][3] != 42 {
+		t.FailNow()
+	}
+	if fields, err := UnpackAD(PackAD()); err != nil || len(fields) != 0 {
+		t.FailNow()
+	}
+	for _, bad := range [][]byte{ad[:len(ad)-1], ad[:6], {0, 0, 0, 0, 0, 0, 0, 5, 1},
+		{255, 255, 255, 255, 255, 255, 255, 255, 1}} {
+		if _, err := UnpackAD(bad); err == nil {
+			t.FailNow()
+		}
+	}
+	key := GenerateAEADKey()
+	nonce := key.NewNonce()
+	ct := key.Seal([]byte("hi"), PackAD([]byte("v1"), []byte("seq 7")), nonce)
+	if _, err := key.Open(ct, PackAD([]byte("v1"), []byte("seq 8")), nonce); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestAEADCommitting(t *testing.T) {
+	key := GenerateAEADKey()
+	nonce := key.NewNonce()
+	ct := key.SealCommitting([]byte("Hello World"), []byte("ad"), nonce)
+	if len(ct) != 11+AEADCommitmentLength+AEADTagLength {
+		t.FailNow()
+	}
+	pt, err := key.OpenCommitting(ct, []byte("ad"), nonce)
+	if err != nil || string(pt) != "Hello World" {
+		t.FailNow()
+	}
+	commitment, _ := GenericHashKeyed(append([]byte("natrium aead commitment"), nonce...), key, 32)
+	if !bytes.Equal(ct[:AEADCommitmentLength], commitment) {
+		t.FailNow()
+	}
+	pt, err = key.Open(ct[AEADCommitmentLength:], []byte("ad"), nonce)
+	if err != nil || string(pt) != "Hello World" {
+		t.FailNow()
+	}
+	if _, err := GenerateAEADKey().OpenCommitting(ct, []byte("ad"), nonce); err != ErrDecryptionFailed {
+		t.FailNow()
+	}
+	if _, err := key.OpenCommitting(ct, nil, nonce); err != ErrDecryptionFailed {
+		t.FailNow()
+	}
+	for _, i := range []int{0, AEADCommitmentLength, len(ct) - 1} {
+		tampered := append([]byte(nil), ct...)
+		tampered[i] ^= 1
+		if _, err := key.OpenCommitting(tampered, []byte("ad"), nonce); err != ErrDecryptionFailed {
+			t.FailNow()
+		}
+	}
+	if _, err := key.OpenCommitting(ct[:AEADCommitmentLength+AEADTagLength-1], nil, nonce); !errors.Is(err, ErrDecryptionFailed) {
+		t.FailNow()
+	}
+}
+
+func TestAEADCiphertextLen(t *testing.T) {
+	key := GenerateAEADKey()
+	for _, n := range []int{0, 1, 1000} {
+		if len(key.Seal(make([]byte, n), []byte("ad"), key.NewNonce())) != key.CiphertextLen(n) {
+			t.FailNow()
+		}
+	}
+}
+
+func TestAEADKeyEncode(t *testing.T) {
+	key := GenerateAEADKey()
+	checkEncoding(t, []encodingCase{
+		{key, AEADKeyPEMType, key.Encode, func(b []byte, e Encoding) ([]byte, error) { return DecodeAEADKey(b, e) }},
+	})
+}
+
+func TestAEADKeyGob(t *testing.T) {
+	key := GenerateAEADKey()
+	var got AEADKey
+	checkGob(t, key, key, &got, func() []byte { return got })
+}
@@ -0,0 +1,110 @@
+package natrium
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// AEADSeqPrefixLength is the length of the random prefix that a SeqEncryptor
+// puts at the start of every nonce.
+const AEADSeqPrefixLength = AEADNonceLength - 8
+
+// AEADSeqMaxMessages is the most messages a single SeqEncryptor can seal. The
+// counter is 64 bits wide, so this is a limit no program reaches; it exists so
+// that the counter can never wrap around.
+const AEADSeqMaxMessages uint64 = 1<<64 - 1
+
+// AEADSeqOverhead is the number of bytes SeqEncryptor.Seal adds to a message.
+const AEADSeqOverhead = AEADNonceLength + AEADTagLength
+
+// SeqEncryptor seals a sequence of messages under one AEADKey, choosing the
+// nonces itself so that none is ever repeated. It is safe for concurrent use;
+// concurrent calls to Seal are given consecutive counters in some order.
+type SeqEncryptor struct {
+	key    AEADKey
+	mu     sync.Mutex
+	prefix []byte
+	next   uint64
+}
+
+// SeqDecryptor opens, in order, the messages sealed by one SeqEncryptor. It
+// is safe for concurrent use.
+type SeqDecryptor struct {
+	key     AEADKey
+	mu      sync.Mutex
+	prefix  []byte
+	expect  uint64
+	started bool
+}
+
+var errSeqExhausted = errors.New("sequential encryptor has sealed all the messages it can")
+
+// NewSequentialEncryptor returns a SeqEncryptor for the key, with a fresh
+// random nonce prefix. Each Seal returns
+//
+//	16 bytes  random prefix, the same for every message of the encryptor
+//	8 bytes   message counter, big-endian, starting at zero
+//	rest      output of AEADKey.Seal with those 24 bytes as the nonce
+//
+// so AEADSeqOverhead bytes more than the message. The random prefix keeps the
+// nonces of different encryptors under the same key apart, and the counter
+// those of one encryptor, so no nonce is ever reused. At most
+// AEADSeqMaxMessages messages can be sealed, after which Seal returns an
+// error.
+func (k AEADKey) NewSequentialEncryptor() *SeqEncryptor {
+	return &SeqEncryptor{key: k, prefix: RandomBytes(AEADSeqPrefixLength)}
+}
+
+// NewSequentialDecryptor returns a SeqDecryptor for the key. It adopts the
+// prefix of the first message it opens, which must carry counter zero, and
+// then accepts only the following counters, one by one, with that prefix.
+func (k AEADKey) NewSequentialDecryptor() *SeqDecryptor {
+	return &SeqDecryptor{key: k}
+}
+
+// Seal encrypts and authenticates the next message in the sequence, along
+// with the associated data ad, which is not encrypted and may be nil.
+func (se *SeqEncryptor) Seal(message, ad []byte) ([]byte, error) {
+	se.mu.Lock()
+	if se.next == AEADSeqMaxMessages {
+		se.mu.Unlock()
+		return nil, errSeqExhausted
+	}
+	nonce := make([]byte, AEADNonceLength)
+	copy(nonce, se.prefix)
+	binary.BigEndian.PutUint64(nonce[AEADSeqPrefixLength:], se.next)
+	se.next++
+	se.mu.Unlock()
+	return append(nonce, se.key.Seal(message, ad, nonce)...), nil
+}
+
+// Open decrypts and verifies the next message in the sequence. A message that
+// is forged, replayed, out of order, or from another encryptor gives an error
+// wrapping ErrDecryptionFailed, and leaves the decryptor waiting for the same
+// message as before, so a dropped or reordered message stops the sequence for
+// good.
+func (sd *SeqDecryptor) Open(ciphertext, ad []byte) ([]byte, error) {
+	if len(ciphertext) < AEADSeqOverhead {
+		return nil, fmt.Errorf("%w: ciphertext too short", ErrDecryptionFailed)
+	}
+	nonce := ciphertext[:AEADNonceLength]
+	counter := binary.BigEndian.Uint64(nonce[AEADSeqPrefixLength:])
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	if counter != sd.expect || (sd.started && !MemCmp(nonce[:AEADSeqPrefixLength], sd.prefix)) {
+		return nil, fmt.Errorf("%w: message replayed, out of order or from another sequence",
+			ErrDecryptionFailed)
+	}
+	out, err := sd.key.Open(ciphertext[AEADNonceLength:], ad, nonce)
+	if err != nil {
+		return nil, err
+	}
+	if !sd.started {
+		sd.prefix = append([]byte(nil), nonce[:AEADSeqPrefixLength]...)
+		sd.started = true
+	}
+	sd.expect++
+	return out, nil
+}
@@ -0,0 +1,94 @@
+package natrium
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestSeqAEAD(t *testing.T) {
+	key := GenerateAEADKey()
+	enc, dec := key.NewSequentialEncryptor(), key.NewSequentialDecryptor()
+	var frames [][]byte
+	for i := 0; i < 3; i++ {
+		ct, err := enc.Seal([]byte{byte(i)}, []byte("ad"))
+		if err != nil || len(ct) != 1+AEADSeqOverhead {
+			t.FailNow()
+		}
+		frames = append(frames, ct)
+	}
+	if !bytes.Equal(frames[0][:AEADSeqPrefixLength], frames[2][:AEADSeqPrefixLength]) {
+		t.FailNow()
+	}
+	// out of order, then in order, then replayed
+	if _, err := dec.Open(frames[1], []byte("ad")); !errors.Is(err, ErrDecryptionFailed) {
+		t.FailNow()
+	}
+	for i, ct := range frames {
+		plain, err := dec.Open(ct, []byte("ad"))
+		if err != nil || !bytes.Equal(plain, []byte{byte(i)}) {
+			t.FailNow()
+		}
+	}
+	if _, err := dec.Open(frames[2], []byte("ad")); !errors.Is(err, ErrDecryptionFailed) {
+		t.FailNow()
+	}
+	// the next message of another encryptor under the same key is refused
+	other := key.NewSequentialEncryptor()
+	for i := 0; i < 4; i++ {
+		ct, _ := other.Seal(nil, []byte("ad"))
+		if i == 3 {
+			if _, err := dec.Open(ct, []byte("ad")); !errors.Is(err, ErrDecryptionFailed) {
+				t.FailNow()
+			}
+		}
+	}
+	// a forged message does not advance the decryptor
+	next, _ := enc.Seal([]byte("x"), nil)
+	bad := append([]byte(nil), next...)
+	bad[len(bad)-1] ^= 1
+	if _, err := dec.Open(bad, nil); !errors.Is(err, ErrDecryptionFailed) {
+		t.FailNow()
+	}
+	if _, err := dec.Open(next, nil); err != nil {
+		t.FailNow()
+	}
+	if _, err := dec.Open(next[:AEADSeqOverhead-1], nil); !errors.Is(err, ErrDecryptionFailed) {
+		t.FailNow()
+	}
+}
+
+func TestSeqAEADExhausted(t *testing.T) {
+	enc := GenerateAEADKey().NewSequentialEncryptor()
+	enc.next = AEADSeqMaxMessages - 1
+	if _, err := enc.Seal(nil, nil); err != nil {
+		t.FailNow()
+	}
+	if _, err := enc.Seal(nil, nil); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestSeqAEADConcurrent(t *testing.T) {
+	key := GenerateAEADKey()
+	enc := key.NewSequentialEncryptor()
+	frames := make([][]byte, 100)
+	var wg sync.WaitGroup
+	for i := range frames {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			frames[i], _ = enc.Seal(nil, nil)
+		}(i)
+	}
+	wg.Wait()
+	// every counter was handed out exactly once
+	seen := make(map[string]bool)
+	for _, ct := range frames {
+		seen[string(ct[:AEADNonceLength])] = true
+	}
+	if len(seen) != len(frames) {
+		t.FailNow()
+	}
+}
@@ -1,15 +1,55 @@
 package natrium
 
 // #cgo darwin CFLAGS: -I/usr/local/include
-// #cgo darwin LDFLAGS: /usr/local/lib/libsodium.a
-// #cgo linux windows android LDFLAGS: -Wl,-Bstatic -lsodium -Wl,-Bdynamic
 // #include <stdio.h>
 // #include <sodium.h>
 import "C"
-import "unsafe"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
 
 // PasswordSaltLen gives the length of the salt parameter to StretchKey
-var PasswordSaltLen int
+const PasswordSaltLen int = C.crypto_pwhash_SALTBYTES
+
+// PwhashSaltLength gives the length of the salt parameter to DeriveKeyFromPassword.
+const PwhashSaltLength int = C.crypto_pwhash_SALTBYTES
+
+// PwhashBytesMin is the shortest key DeriveKeyFromPassword can derive.
+const PwhashBytesMin int = C.crypto_pwhash_BYTES_MIN
+
+// PwhashBytesMax is the longest key DeriveKeyFromPassword can derive, the
+// 2^32-1 bytes Argon2 allows, or the largest int if that is smaller.
+const PwhashBytesMax int = min(1<<32-1, maxInt)
+
+// maxInt is the largest int, kept untyped so that it can cap the length
+// limits above on platforms where int is 32 bits.
+const maxInt = 1<<(32<<(^uint(0)>>63)-1) - 1
+
+// Named presets for the opsLimit and memLimit parameters of the
+// password-hashing functions. Interactive is suitable for online logins,
+// Moderate for somewhat more sensitive uses, and Sensitive for keys protecting
+// highly valuable secrets, where a derivation may take several seconds.
+const (
+	PwhashOpsInteractive uint64 = C.crypto_pwhash_OPSLIMIT_INTERACTIVE
+	PwhashMemInteractive uint64 = C.crypto_pwhash_MEMLIMIT_INTERACTIVE
+	PwhashOpsModerate    uint64 = C.crypto_pwhash_OPSLIMIT_MODERATE
+	PwhashMemModerate    uint64 = C.crypto_pwhash_MEMLIMIT_MODERATE
+	PwhashOpsSensitive   uint64 = C.crypto_pwhash_OPSLIMIT_SENSITIVE
+	PwhashMemSensitive   uint64 = C.crypto_pwhash_MEMLIMIT_SENSITIVE
+)
+
+// PwhashAlg selects the Argon2 variant used to hash a password.
+type PwhashAlg int
+
+// The Argon2 variants, both at version 1.3. Argon2id, the default, should be
+// preferred unless a standard or regulation specifically requires Argon2i.
+const (
+	Argon2i13  PwhashAlg = C.crypto_pwhash_ALG_ARGON2I13
+	Argon2id13 PwhashAlg = C.crypto_pwhash_ALG_ARGON2ID13
+)
 
 // StretchKey uses the Argon2 algorithm to create a 256-bit key based upon a password and a salt. This function is deterministic given a certain opslimit and memlimit.
 func StretchKey(pwd []byte, salt []byte, opslimit int, memlimit int) []byte {
@@ -19,24 +59,202 @@ func StretchKey(pwd []byte, salt []byte, opslimit int, memlimit int) []byte {
 	if len(salt) != PasswordSaltLen {
 		panic("wrong salt length for crypto_pwhash")
 	}
-	toret := make([]byte, 32)
-	retval := C.crypto_pwhash(g2cbt(toret), 32, (*C.char)(unsafe.Pointer(g2cbt(pwd))),
-		C.ulonglong(len(pwd)), g2cbt(salt), C.ulonglong(opslimit),
-		C.size_t(memlimit), C.crypto_pwhash_ALG_DEFAULT)
-	if retval != 0 {
+	toret, err := DeriveKeyFromPassword(pwd, salt, 32, uint64(opslimit), uint64(memlimit))
+	if err != nil {
 		panic("crypto_pwhash returned non-zero!")
 	}
 	return toret
 }
 
+// DeriveKeyFromPassword uses the Argon2id algorithm to derive an outLen-byte
+// key from a password and a PwhashSaltLength-byte salt. The result is
+// deterministic given the same parameters, and can be used directly as a
+// SecretKey or AEADKey when outLen is 32. Longer outputs can be split into
+// several keys; outLen must be between PwhashBytesMin and PwhashBytesMax.
+// The length is an input to Argon2, so a shorter output is not a prefix of a
+// longer one.
+func DeriveKeyFromPassword(password, salt []byte, outLen int, opsLimit, memLimit uint64) ([]byte, error) {
+	return DeriveKeyFromPasswordAlg(password, salt, outLen, opsLimit, memLimit, Argon2id13)
+}
+
+// DeriveKeyFromPasswordAlg is like DeriveKeyFromPassword, but uses the given
+// Argon2 variant. Argon2i needs an opsLimit of at least 3.
+func DeriveKeyFromPasswordAlg(password, salt []byte, outLen int, opsLimit, memLimit uint64, alg PwhashAlg) ([]byte, error) {
+	if len(salt) != PwhashSaltLength {
+		return nil, errors.New("wrong salt length for crypto_pwhash")
+	}
+	if outLen < PwhashBytesMin || outLen > PwhashBytesMax {
+		return nil, fmt.Errorf("crypto_pwhash output length must be between %v and %v",
+			PwhashBytesMin, PwhashBytesMax)
+	}
+	toret := make([]byte, outLen)
+	retval := C.crypto_pwhash(g2cbt(toret), C.ulonglong(outLen), g2cst(password),
+		C.ulonglong(len(password)), g2cbt(salt), C.ulonglong(opsLimit),
+		C.size_t(memLimit), C.int(alg))
+	if retval != 0 {
+		return nil, errors.New("crypto_pwhash failed (invalid parameters or out of memory)")
+	}
+	return toret, nil
+}
+
 // PasswordHash uses the Argon2 algorithm to create an ASCII string which includes opslimit, memlimit, a random salt, and a memory-hard hash. It's designed to be stored in databases and directly used with PasswordVerify.
 func PasswordHash(pwd []byte, opslimit int, memlimit int) string {
+	toret, err := HashPassword(pwd, uint64(opslimit), uint64(memlimit))
+	if err != nil {
+		panic("crypto_pwhash_str returned non-zero!")
+	}
+	return toret
+}
+
+// PasswordVerify verifies that the given password corresponds to the given salted hash string (of the format returned by PasswordHash).
+func PasswordVerify(pwd []byte, hash string) bool {
+	return VerifyPassword(hash, pwd)
+}
+
+// HashPassword uses the Argon2id algorithm to create an ASCII string embedding
+// the algorithm, opsLimit, memLimit, a random salt, and a memory-hard hash of
+// the password. It's designed to be stored directly in a database and checked
+// with VerifyPassword.
+func HashPassword(password []byte, opsLimit, memLimit uint64) (string, error) {
+	return HashPasswordAlg(password, opsLimit, memLimit, Argon2id13)
+}
+
+// HashPasswordAlg is like HashPassword, but uses the given Argon2 variant.
+// The variant is recorded in the hash string, so VerifyPassword checks hashes
+// of either kind.
+func HashPasswordAlg(password []byte, opsLimit, memLimit uint64, alg PwhashAlg) (string, error) {
 	out := make([]byte, C.crypto_pwhash_STRBYTES)
-	retval := C.crypto_pwhash_str(g2cst(out), g2cst(pwd), C.ulonglong(len(pwd)),
-		C.ulonglong(opslimit), C.size_t(memlimit))
+	retval := C.crypto_pwhash_str_alg(g2cst(out), g2cst(password), C.ulonglong(len(password)),
+		C.ulonglong(opsLimit), C.size_t(memLimit), C.int(alg))
 	if retval != 0 {
-		panic("crypto_pwhash_str returned non-zero!")
+		return "", errors.New("crypto_pwhash_str failed (invalid parameters or out of memory)")
 	}
+	return cstring(out), nil
+}
+
+// HashPasswordInteractive is HashPassword with the Interactive presets.
+func HashPasswordInteractive(password []byte) (string, error) {
+	return HashPassword(password, PwhashOpsInteractive, PwhashMemInteractive)
+}
+
+// HashPasswordModerate is HashPassword with the Moderate presets, which take
+// noticeably longer and need 256 MiB of memory.
+func HashPasswordModerate(password []byte) (string, error) {
+	return HashPassword(password, PwhashOpsModerate, PwhashMemModerate)
+}
+
+// HashPasswordSensitive is HashPassword with the Sensitive presets. Hashing
+// needs 1 GiB of memory and may take several seconds.
+func HashPasswordSensitive(password []byte) (string, error) {
+	return HashPassword(password, PwhashOpsSensitive, PwhashMemSensitive)
+}
+
+// VerifyPassword reports whether the password matches a hash string produced
+// by HashPassword. The comparison is constant-time, and a malformed hash
+// string simply fails to verify.
+func VerifyPassword(hash string, password []byte) bool {
+	if len(hash) >= C.crypto_pwhash_STRBYTES {
+		return false
+	}
+	haha := []byte(hash)
+	haha = append(haha, 0)
+	return C.crypto_pwhash_str_verify(g2cst(haha), g2cst(password), C.ulonglong(len(password))) == 0
+}
+
+// VerifyPasswordAlg is like VerifyPassword, but only accepts hash strings of
+// the given Argon2 variant, for deployments that must not accept the other.
+func VerifyPasswordAlg(hash string, password []byte, alg PwhashAlg) bool {
+	if len(hash) >= C.crypto_pwhash_STRBYTES {
+		return false
+	}
+	haha := append([]byte(hash), 0)
+	switch alg {
+	case Argon2i13:
+		return C.crypto_pwhash_argon2i_str_verify(g2cst(haha), g2cst(password),
+			C.ulonglong(len(password))) == 0
+	case Argon2id13:
+		return C.crypto_pwhash_argon2id_str_verify(g2cst(haha), g2cst(password),
+			C.ulonglong(len(password))) == 0
+	default:
+		return false
+	}
+}
+
+// PasswordNeedsRehash reports whether a hash string produced by HashPassword
+// was computed with parameters other than opsLimit and memLimit, in which case
+// the password should be rehashed the next time it is verified. An error is
+// returned for an unparseable hash string.
+func PasswordNeedsRehash(hash string, opsLimit, memLimit uint64) (bool, error) {
+	if len(hash) >= C.crypto_pwhash_STRBYTES {
+		return false, errors.New("malformed password hash string")
+	}
+	haha := []byte(hash)
+	haha = append(haha, 0)
+	switch C.crypto_pwhash_str_needs_rehash(g2cst(haha), C.ulonglong(opsLimit), C.size_t(memLimit)) {
+	case 0:
+		return false, nil
+	case 1:
+		return true, nil
+	default:
+		return false, errors.New("malformed password hash string")
+	}
+}
+
+// pwhashStringKeyLength is the length of the Argon2 output that
+// DeriveKeyFromPasswordHashString expands, chosen to differ from the 32 bytes
+// crypto_pwhash_str stores.
+const pwhashStringKeyLength = 64
+
+// DeriveKeyFromPasswordHashString derives an outLen-byte key from a password
+// using the algorithm, parameters and salt embedded in a hash string from
+// HashPassword, so that a login can also unlock encrypted data without a salt
+// being stored separately. It does not check the password: call
+// VerifyPassword first, since a wrong password just gives a different key.
+// outLen must be between 1 and ExpandMax, and an unparseable hash string gives
+// an error.
+//
+// The hash string itself must never be usable as the key, and re-running
+// crypto_pwhash with the stored length would give exactly the hash it
+// contains. Instead, Argon2 is run with the stored parameters and salt for 64
+// bytes of output, which are unrelated to the stored 32, and the result is
+// passed to Expand with the info "natrium password hash key".
+func DeriveKeyFromPasswordHashString(hash string, password []byte, outLen int) ([]byte, error) {
+	malformed := errors.New("malformed password hash string")
+	fields := strings.Split(hash, "$")
+	if len(fields) != 6 || fields[0] != "" || fields[2] != "v=19" {
+		return nil, malformed
+	}
+	var alg PwhashAlg
+	switch fields[1] {
+	case "argon2i":
+		alg = Argon2i13
+	case "argon2id":
+		alg = Argon2id13
+	default:
+		return nil, malformed
+	}
+	var memKiB, opsLimit, lanes uint64
+	if _, err := fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &memKiB, &opsLimit, &lanes); err != nil ||
+		fields[3] != fmt.Sprintf("m=%d,t=%d,p=%d", memKiB, opsLimit, lanes) || lanes != 1 {
+		return nil, malformed
+	}
+	salt, err := Base64ToBin(fields[4], Base64OriginalNoPadding)
+	if err != nil || len(salt) != PwhashSaltLength {
+		return nil, malformed
+	}
+	if outLen < 1 || outLen > ExpandMax {
+		return nil, fmt.Errorf("key length must be between 1 and %v", ExpandMax)
+	}
+	raw, err := DeriveKeyFromPasswordAlg(password, salt, pwhashStringKeyLength, opsLimit, memKiB*1024, alg)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(raw)
+	return Expand(raw, []byte("natrium password hash key"), outLen)
+}
+
+// cstring converts a NUL-terminated C string in a buffer to a Go string.
+func cstring(out []byte) string {
 	for i := range out {
 		if out[i] == 0 {
 			return string(out[:i])
@@ -45,13 +263,39 @@ func PasswordHash(pwd []byte, opslimit int, memlimit int) string {
 	return string(out[:len(out)-1])
 }
 
-// PasswordVerify verifies that the given password corresponds to the given salted hash string (of the format returned by PasswordHash).
-func PasswordVerify(pwd []byte, hash string) bool {
-	haha := []byte(hash)
-	haha = append(haha, 0)
-	return C.crypto_pwhash_str_verify(g2cst(haha), g2cst(pwd), C.ulonglong(len(pwd))) == 0
-}
+// pwhashTuneMaxOps bounds the search in TunePwhashParams, in case the clock
+// misbehaves.
+const pwhashTuneMaxOps = 64
 
-func init() {
-	PasswordSaltLen = int(C.crypto_pwhash_SALTBYTES)
+// TunePwhashParams picks Argon2id parameters for which a derivation takes
+// about targetDuration on the current machine, by timing real derivations.
+// Memory is raised first, from PwhashMemInteractive up to PwhashMemModerate,
+// and then the number of passes, until a derivation takes at least the
+// target. The interactive memory limit and a single pass are never gone
+// below, even if they are slower than the target.
+//
+// Tuning takes several times targetDuration and uses the CPU and memory it
+// measures, so it belongs at deploy or configuration time, with the result
+// stored; never call it while handling a request.
+func TunePwhashParams(targetDuration time.Duration) (opsLimit, memLimit uint64) {
+	password := []byte("natrium tuning password")
+	salt := make([]byte, PwhashSaltLength)
+	opsLimit, memLimit = 1, PwhashMemInteractive
+	prevOps, prevMem := opsLimit, memLimit
+	for {
+		start := time.Now()
+		if _, err := DeriveKeyFromPassword(password, salt, 32, opsLimit, memLimit); err != nil {
+			// most likely out of memory, so settle for the previous step
+			return prevOps, prevMem
+		}
+		if time.Since(start) >= targetDuration || opsLimit >= pwhashTuneMaxOps {
+			return opsLimit, memLimit
+		}
+		prevOps, prevMem = opsLimit, memLimit
+		if memLimit < PwhashMemModerate {
+			memLimit *= 2
+		} else {
+			opsLimit++
+		}
+	}
 }
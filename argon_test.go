@@ -1,6 +1,11 @@
 package natrium
 
-import "testing"
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
 
 func BenchmarkArgon(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -9,3 +14,169 @@ func BenchmarkArgon(b *testing.B) {
 		PasswordHash(pwd, 5, 64*1024*1024)
 	}
 }
+
+func TestDeriveKeyFromPassword(t *testing.T) {
+	salt := make([]byte, PwhashSaltLength)
+	a, err := DeriveKeyFromPassword([]byte("hunter2"), salt, 32, 1, 8192)
+	if err != nil {
+		t.FailNow()
+	}
+	if HexEncode(a) != "8315324b07f486af074d47a9d137668c1fc304a159cc9acd6ba5cf3adf7df577" {
+		t.FailNow()
+	}
+	b, err := DeriveKeyFromPassword([]byte("hunter2"), salt, 32, 1, 8192)
+	if err != nil || CTCompare(a, b) != 0 {
+		t.FailNow()
+	}
+	if CTCompare(a, StretchKey([]byte("hunter2"), salt, 1, 8192)) != 0 {
+		t.FailNow()
+	}
+	if _, err := DeriveKeyFromPassword([]byte("hunter2"), salt[1:], 32, 1, 8192); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword([]byte("hunter2"), 1, 8192)
+	if err != nil {
+		t.FailNow()
+	}
+	if !VerifyPassword(hash, []byte("hunter2")) || VerifyPassword(hash, []byte("hunter3")) {
+		t.FailNow()
+	}
+	if VerifyPassword("$argon2id$garbage", []byte("hunter2")) || VerifyPassword("", nil) {
+		t.FailNow()
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	weak, _ := HashPassword([]byte("hunter2"), 1, 8192)
+	strong, _ := HashPassword([]byte("hunter2"), 2, 16384)
+	if rehash, err := PasswordNeedsRehash(weak, 2, 16384); err != nil || !rehash {
+		t.FailNow()
+	}
+	if rehash, err := PasswordNeedsRehash(strong, 2, 16384); err != nil || rehash {
+		t.FailNow()
+	}
+	if _, err := PasswordNeedsRehash("garbage", 2, 16384); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestHashPasswordInteractive(t *testing.T) {
+	hash, err := HashPasswordInteractive([]byte("hunter2"))
+	if err != nil || !VerifyPassword(hash, []byte("hunter2")) {
+		t.FailNow()
+	}
+	rehash, err := PasswordNeedsRehash(hash, PwhashOpsInteractive, PwhashMemInteractive)
+	if err != nil || rehash {
+		t.FailNow()
+	}
+}
+
+func TestPasswordAlgorithms(t *testing.T) {
+	password := []byte("hunter2")
+	argon2i, err := HashPasswordAlg(password, 3, 8192, Argon2i13)
+	if err != nil || !strings.HasPrefix(argon2i, "$argon2i$") {
+		t.FailNow()
+	}
+	argon2id, err := HashPasswordAlg(password, 3, 8192, Argon2id13)
+	if err != nil || !strings.HasPrefix(argon2id, "$argon2id$") {
+		t.FailNow()
+	}
+	if !VerifyPassword(argon2i, password) || !VerifyPassword(argon2id, password) {
+		t.FailNow()
+	}
+	if !VerifyPasswordAlg(argon2i, password, Argon2i13) || VerifyPasswordAlg(argon2i, password, Argon2id13) {
+		t.FailNow()
+	}
+	if !VerifyPasswordAlg(argon2id, password, Argon2id13) || VerifyPasswordAlg(argon2id, password, Argon2i13) {
+		t.FailNow()
+	}
+	salt := make([]byte, PwhashSaltLength)
+	a, _ := DeriveKeyFromPasswordAlg(password, salt, 32, 3, 8192, Argon2i13)
+	b, _ := DeriveKeyFromPasswordAlg(password, salt, 32, 3, 8192, Argon2id13)
+	if len(a) != 32 || MemCmp(a, b) {
+		t.FailNow()
+	}
+}
+
+func TestDeriveKeyLengths(t *testing.T) {
+	password := []byte("correct horse")
+	salt := make([]byte, PwhashSaltLength)
+	var keys [][]byte
+	for _, n := range []int{16, 32, 64} {
+		key, err := DeriveKeyFromPassword(password, salt, n, PwhashOpsInteractive, 8192)
+		if err != nil || len(key) != n {
+			t.FailNow()
+		}
+		keys = append(keys, key)
+	}
+	if strings.HasPrefix(string(keys[1]), string(keys[0])) ||
+		strings.HasPrefix(string(keys[2]), string(keys[1])) {
+		t.FailNow()
+	}
+	for _, n := range []int{-1, 0, PwhashBytesMin - 1} {
+		if _, err := DeriveKeyFromPassword(password, salt, n, PwhashOpsInteractive, 8192); err == nil {
+			t.FailNow()
+		}
+	}
+}
+
+func TestTunePwhashParams(t *testing.T) {
+	if testing.Short() {
+		t.Skip("tuning runs real derivations")
+	}
+	ops, mem := TunePwhashParams(50 * time.Millisecond)
+	if ops < 1 || mem < PwhashMemInteractive || mem > PwhashMemModerate {
+		t.FailNow()
+	}
+	start := time.Now()
+	if _, err := HashPassword([]byte("password"), ops, mem); err != nil {
+		t.FailNow()
+	}
+	// the target is only approximate, and machines are noisy
+	if time.Since(start) > 5*time.Second {
+		t.FailNow()
+	}
+}
+
+func TestDeriveKeyFromPasswordHashString(t *testing.T) {
+	password := []byte("hunter2")
+	hash, err := HashPassword(password, 1, 8192)
+	if err != nil || !VerifyPassword(hash, password) {
+		t.FailNow()
+	}
+	a, err := DeriveKeyFromPasswordHashString(hash, password, 32)
+	if err != nil || len(a) != 32 {
+		t.FailNow()
+	}
+	b, _ := DeriveKeyFromPasswordHashString(hash, password, 32)
+	if !bytes.Equal(a, b) {
+		t.FailNow()
+	}
+	// the key is not the hash stored in the string
+	fields := strings.Split(hash, "$")
+	stored, _ := Base64ToBin(fields[5], Base64OriginalNoPadding)
+	if bytes.Equal(a, stored) {
+		t.FailNow()
+	}
+	salt, _ := Base64ToBin(fields[4], Base64OriginalNoPadding)
+	raw, _ := DeriveKeyFromPassword(password, salt, 64, 1, 8192)
+	if want, _ := Expand(raw, []byte("natrium password hash key"), 32); !bytes.Equal(a, want) {
+		t.FailNow()
+	}
+	other, _ := HashPassword(password, 1, 8192)
+	if c, _ := DeriveKeyFromPasswordHashString(other, password, 32); bytes.Equal(a, c) {
+		t.FailNow()
+	}
+	if c, _ := DeriveKeyFromPasswordHashString(hash, []byte("hunter3"), 32); bytes.Equal(a, c) {
+		t.FailNow()
+	}
+	for _, bad := range []string{"", "$argon2id$garbage", strings.Replace(hash, "p=1", "p=2", 1),
+		strings.Replace(hash, "argon2id", "scrypt", 1), strings.Replace(hash, "m=8", "m=08", 1)} {
+		if _, err := DeriveKeyFromPasswordHashString(bad, password, 32); err == nil {
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package natrium
+
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #include <stdio.h>
+// #include <sodium.h>
+import "C"
+import "hash"
+
+// AuthKeyLength is the length of the key passed to Auth.
+const AuthKeyLength int = C.crypto_auth_KEYBYTES
+
+// AuthTagLength is the length of the tag returned by Auth.
+const AuthTagLength int = C.crypto_auth_BYTES
+
+// Auth computes an HMAC-SHA512-256 tag of a message under an
+// AuthKeyLength-byte key.
+func Auth(message, key []byte) []byte {
+	if len(key) != AuthKeyLength {
+		panic("auth key has the wrong length")
+	}
+	out := make([]byte, AuthTagLength)
+	rv := C.crypto_auth(g2cbt(out), g2cbt(message), C.ulonglong(len(message)), g2cbt(key))
+	if rv != 0 {
+		panic("crypto_auth returned non-zero")
+	}
+	return out
+}
+
+// AuthVerify checks, in constant time, that tag is the Auth tag of message
+// under key. Malformed tags and keys simply fail to verify.
+func AuthVerify(tag, message, key []byte) bool {
+	if len(tag) != AuthTagLength || len(key) != AuthKeyLength {
+		return false
+	}
+	return C.crypto_auth_verify(g2cbt(tag), g2cbt(message), C.ulonglong(len(message)), g2cbt(key)) == 0
+}
+
+// HMACSHA256Length is the length of the tag returned by HMACSHA256.
+const HMACSHA256Length int = C.crypto_auth_hmacsha256_BYTES
+
+// HMACSHA512Length is the length of the tag returned by HMACSHA512.
+const HMACSHA512Length int = C.crypto_auth_hmacsha512_BYTES
+
+// HMACSHA256 computes the standard HMAC-SHA-256 of a message, for
+// interoperating with systems that do not use Auth's truncated HMAC-SHA-512.
+// As the HMAC specification allows, the key may have any length, though keys
+// shorter than HMACSHA256Length bytes are weak.
+func HMACSHA256(message, key []byte) []byte {
+	var state C.crypto_auth_hmacsha256_state
+	out := make([]byte, HMACSHA256Length)
+	if C.crypto_auth_hmacsha256_init(&state, g2cbt(key), C.size_t(len(key))) != 0 ||
+		C.crypto_auth_hmacsha256_update(&state, g2cbt(message), C.ulonglong(len(message))) != 0 ||
+		C.crypto_auth_hmacsha256_final(&state, g2cbt(out)) != 0 {
+		panic("crypto_auth_hmacsha256 returned non-zero")
+	}
+	return out
+}
+
+// HMACSHA256Verify checks, in constant time, that tag is the HMACSHA256 of
+// message under key.
+func HMACSHA256Verify(tag, message, key []byte) bool {
+	return MemCmp(tag, HMACSHA256(message, key))
+}
+
+// HMACSHA512 computes the full, untruncated HMAC-SHA-512 of a message. Like
+// HMACSHA256, it accepts keys of any length.
+func HMACSHA512(message, key []byte) []byte {
+	var state C.crypto_auth_hmacsha512_state
+	out := make([]byte, HMACSHA512Length)
+	if C.crypto_auth_hmacsha512_init(&state, g2cbt(key), C.size_t(len(key))) != 0 ||
+		C.crypto_auth_hmacsha512_update(&state, g2cbt(message), C.ulonglong(len(message))) != 0 ||
+		C.crypto_auth_hmacsha512_final(&state, g2cbt(out)) != 0 {
+		panic("crypto_auth_hmacsha512 returned non-zero")
+	}
+	return out
+}
+
+// HMACSHA512Verify checks, in constant time, that tag is the HMACSHA512 of
+// message under key.
+func HMACSHA512Verify(tag, message, key []byte) bool {
+	return MemCmp(tag, HMACSHA512(message, key))
+}
+
+type hmacSHA256 struct {
+	state, initial C.crypto_auth_hmacsha256_state
+}
+
+// NewHMACSHA256 returns a hash.Hash computing HMACSHA256 under key
+// incrementally, so that large inputs can be authenticated without holding
+// them in memory. Reset returns it to the freshly keyed state.
+func NewHMACSHA256(key []byte) hash.Hash {
+	toret := new(hmacSHA256)
+	rv := C.crypto_auth_hmacsha256_init(&toret.initial, g2cbt(key), C.size_t(len(key)))
+	if rv != 0 {
+		panic("crypto_auth_hmacsha256_init returned non-zero")
+	}
+	toret.Reset()
+	return toret
+}
+
+func (h *hmacSHA256) Write(b []byte) (int, error) {
+	rv := C.crypto_auth_hmacsha256_update(&h.state, g2cbt(b), C.ulonglong(len(b)))
+	if rv != 0 {
+		panic("crypto_auth_hmacsha256_update returned non-zero")
+	}
+	return len(b), nil
+}
+
+func (h *hmacSHA256) Sum(b []byte) []byte {
+	tmp := h.state
+	out := make([]byte, HMACSHA256Length)
+	rv := C.crypto_auth_hmacsha256_final(&tmp, g2cbt(out))
+	if rv != 0 {
+		panic("crypto_auth_hmacsha256_final returned non-zero")
+	}
+	return append(b, out...)
+}
+
+func (h *hmacSHA256) Reset() {
+	h.state = h.initial
+}
+
+func (h *hmacSHA256) Size() int {
+	return HMACSHA256Length
+}
+
+func (h *hmacSHA256) BlockSize() int {
+	return 64
+}
+
+type hmacSHA512 struct {
+	state, initial C.crypto_auth_hmacsha512_state
+}
+
+// NewHMACSHA512 is the HMACSHA512 counterpart of NewHMACSHA256.
+func NewHMACSHA512(key []byte) hash.Hash {
+	toret := new(hmacSHA512)
+	rv := C.crypto_auth_hmacsha512_init(&toret.initial, g2cbt(key), C.size_t(len(key)))
+	if rv != 0 {
+		panic("crypto_auth_hmacsha512_init returned non-zero")
+	}
+	toret.Reset()
+	return toret
+}
+
+func (h *hmacSHA512) Write(b []byte) (int, error) {
+	rv := C.crypto_auth_hmacsha512_update(&h.state, g2cbt(b), C.ulonglong(len(b)))
+	if rv != 0 {
+		panic("crypto_auth_hmacsha512_update returned non-zero")
+	}
+	return len(b), nil
+}
+
+func (h *hmacSHA512) Sum(b []byte) []byte {
+	tmp := h.state
+	out := make([]byte, HMACSHA512Length)
+	rv := C.crypto_auth_hmacsha512_final(&tmp, g2cbt(out))
+	if rv != 0 {
+		panic("crypto_auth_hmacsha512_final returned non-zero")
+	}
+	return append(b, out...)
+}
+
+func (h *hmacSHA512) Reset() {
+	h.state = h.initial
+}
+
+func (h *hmacSHA512) Size() int {
+	return HMACSHA512Length
+}
+
+func (h *hmacSHA512) BlockSize() int {
+	return 128
+}
@@ -0,0 +1,102 @@
+package natrium
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"io"
+	"testing"
+)
+
+func TestAuth(t *testing.T) {
+	key := RandomBytes(AuthKeyLength)
+	message := []byte("Hello World")
+	tag := Auth(message, key)
+	std := hmac.New(sha512.New, key)
+	std.Write(message)
+	if CTCompare(tag, std.Sum(nil)[:AuthTagLength]) != 0 {
+		t.FailNow()
+	}
+	if !AuthVerify(tag, message, key) || AuthVerify(tag, []byte("Hello"), key) ||
+		AuthVerify(tag[1:], message, key) {
+		t.FailNow()
+	}
+}
+
+func TestHMACRFC4231(t *testing.T) {
+	long := bytes.Repeat([]byte{0xaa}, 131)
+	vectors := []struct {
+		key, data      []byte
+		sha256, sha512 string
+	}{
+		// test case 1
+		{bytes.Repeat([]byte{0x0b}, 20), []byte("Hi There"),
+			"b0344c61d8db38535ca8afceaf0bf12b881dc200c9833da726e9376c2e32cff7",
+			"87aa7cdea5ef619d4ff0b4241a1d6cb02379f4e2ce4ec2787ad0b30545e17cde" +
+				"daa833b7d6b8a702038b274eaea3f4e4be9d914eeb61f1702e696c203a126854"},
+		// test case 2
+		{[]byte("Jefe"), []byte("what do ya want for nothing?"),
+			"5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+			"164b7a7bfcf819e2e395fbe73b56e0a387bd64222e831fd610270cd7ea250554" +
+				"9758bf75c05a994a6d034f65f8f0e6fdcaeab1a34d4a6b4b636e070a38bce737"},
+		// test case 6, a key longer than the block size
+		{long, []byte("Test Using Larger Than Block-Size Key - Hash Key First"),
+			"60e431591ee0b67f0d8a26aacbf5b77f8e0bc6213728c5140546040f0ee37f54",
+			"80b24263c7c1a3ebb71493c1dd7be8b49b46d1f41b4aeec1121b013783f8f352" +
+				"6b56d037e05f2598bd0fd2215d6a1e5295e64f73f63f0aec8b915a985d786598"},
+	}
+	for _, v := range vectors {
+		if BinToHex(HMACSHA256(v.data, v.key)) != v.sha256 ||
+			BinToHex(HMACSHA512(v.data, v.key)) != v.sha512 {
+			t.FailNow()
+		}
+		tag, _ := HexToBin(v.sha256)
+		if !HMACSHA256Verify(tag, v.data, v.key) || HMACSHA256Verify(tag[1:], v.data, v.key) {
+			t.FailNow()
+		}
+		tag, _ = HexToBin(v.sha512)
+		if !HMACSHA512Verify(tag, v.data, v.key) || HMACSHA512Verify(tag, v.data[1:], v.key) {
+			t.FailNow()
+		}
+	}
+}
+
+func TestHMACStreaming(t *testing.T) {
+	key := RandomBytes(45)
+	data := RandomBytes(10000)
+	pairs := []struct {
+		ours, std hash.Hash
+	}{
+		{NewHMACSHA256(key), hmac.New(sha256.New, key)},
+		{NewHMACSHA512(key), hmac.New(sha512.New, key)},
+	}
+	for _, p := range pairs {
+		if p.ours.Size() != p.std.Size() || p.ours.BlockSize() != p.std.BlockSize() {
+			t.FailNow()
+		}
+		io.Copy(p.ours, bytes.NewReader(data[:5000]))
+		p.std.Write(data[:5000])
+		if !bytes.Equal(p.ours.Sum(nil), p.std.Sum(nil)) {
+			t.FailNow()
+		}
+		p.ours.Write(data[5000:])
+		p.std.Write(data[5000:])
+		if !bytes.Equal(p.ours.Sum([]byte("x"))[1:], p.std.Sum(nil)) {
+			t.FailNow()
+		}
+		p.ours.Reset()
+		p.std.Reset()
+		p.ours.Write(data[:7])
+		p.std.Write(data[:7])
+		if !bytes.Equal(p.ours.Sum(nil), p.std.Sum(nil)) {
+			t.FailNow()
+		}
+	}
+	h := NewHMACSHA256(key)
+	h.Write(data)
+	if !bytes.Equal(h.Sum(nil), HMACSHA256(data, key)) {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,675 @@
+package natrium
+
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #include <stdio.h>
+// #include <sodium.h>
+import "C"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// BoxPublic represents a Curve25519 public key used for public-key
+// authenticated encryption.
+type BoxPublic []byte
+
+// BoxPrivate represents a Curve25519 private key used for public-key
+// authenticated encryption. Assignment aliases it, as explained for
+// EdDSAPrivate; use Clone for a copy that survives Destroy.
+type BoxPrivate []byte
+
+// String returns the key in hex, prefixed with its kind.
+func (k BoxPublic) String() string {
+	return fmt.Sprintf("boxpub:%x", []byte(k))
+}
+
+// String returns a redacted placeholder so the key is not printed by accident.
+func (k BoxPrivate) String() string {
+	return redacted("boxprv", k)
+}
+
+// Format implements fmt.Formatter, printing String for every verb.
+func (k BoxPrivate) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, k.String())
+}
+
+// HexString returns the key as "boxprv:" followed by hex, which
+// BoxPrivateFromHex parses back.
+func (k BoxPrivate) HexString() string {
+	return fmt.Sprintf("boxprv:%x", []byte(k))
+}
+
+// BoxPublicLength is the length of a box public key.
+const BoxPublicLength int = C.crypto_box_PUBLICKEYBYTES
+
+// BoxPrivateLength is the length of a box private key.
+const BoxPrivateLength int = C.crypto_box_SECRETKEYBYTES
+
+// BoxNonceLength is the length of the nonce passed to Seal and Open.
+const BoxNonceLength int = C.crypto_box_NONCEBYTES
+
+// BoxMACLength is the number of bytes Seal adds to a message.
+const BoxMACLength int = C.crypto_box_MACBYTES
+
+// BoxSharedLength is the length of a BoxShared.
+const BoxSharedLength int = C.crypto_box_BEFORENMBYTES
+
+// BoxSealOverhead is the number of bytes BoxPublic.Seal adds to a message.
+const BoxSealOverhead int = C.crypto_box_SEALBYTES
+
+// BoxPublicArray is a BoxPublic of fixed size, like EdDSAPublicArray.
+type BoxPublicArray [C.crypto_box_PUBLICKEYBYTES]byte
+
+// BoxPrivateArray is a BoxPrivate of fixed size.
+type BoxPrivateArray [C.crypto_box_SECRETKEYBYTES]byte
+
+// From fills the array with the given public key, failing if it has the wrong
+// length.
+func (a *BoxPublicArray) From(k BoxPublic) error {
+	if len(k) != len(a) {
+		return keyLengthError("box public key", len(k), len(a))
+	}
+	copy(a[:], k)
+	return nil
+}
+
+// To returns a copy of the key as a BoxPublic.
+func (a *BoxPublicArray) To() BoxPublic {
+	return append(BoxPublic(nil), a[:]...)
+}
+
+// From fills the array with the given private key, failing if it has the wrong
+// length.
+func (a *BoxPrivateArray) From(k BoxPrivate) error {
+	if len(k) != len(a) {
+		return keyLengthError("box private key", len(k), len(a))
+	}
+	copy(a[:], k)
+	return nil
+}
+
+// To returns a copy of the key as a BoxPrivate.
+func (a *BoxPrivateArray) To() BoxPrivate {
+	return append(BoxPrivate(nil), a[:]...)
+}
+
+// NewBoxPublic returns a length-checked copy of b as a box public key.
+func NewBoxPublic(b []byte) (BoxPublic, error) {
+	return copyKey(b, BoxPublicLength, "box public key")
+}
+
+// BoxPublicFromHex parses a box public key written in hex, optionally
+// prefixed with "boxpub:" like the output of String.
+func BoxPublicFromHex(s string) (BoxPublic, error) {
+	return keyFromHex(s, "boxpub:", BoxPublicLength, "box public key")
+}
+
+// MustBoxPublicFromHex is like BoxPublicFromHex, but panics on error.
+func MustBoxPublicFromHex(s string) BoxPublic {
+	return mustKey(BoxPublicFromHex(s))
+}
+
+// NewBoxPrivate returns a length-checked copy of b as a box private key.
+func NewBoxPrivate(b []byte) (BoxPrivate, error) {
+	return copyKey(b, BoxPrivateLength, "box private key")
+}
+
+// BoxPrivateFromHex parses a box private key written in hex, optionally
+// prefixed with "boxprv:".
+func BoxPrivateFromHex(s string) (BoxPrivate, error) {
+	return keyFromHex(s, "boxprv:", BoxPrivateLength, "box private key")
+}
+
+// MustBoxPrivateFromHex is like BoxPrivateFromHex, but panics on error.
+func MustBoxPrivateFromHex(s string) BoxPrivate {
+	return mustKey(BoxPrivateFromHex(s))
+}
+
+// Equal reports whether two box public keys are the same.
+func (k BoxPublic) Equal(other BoxPublic) bool {
+	return MemCmp(k, other)
+}
+
+// Clone returns a copy of the key with its own backing array.
+func (k BoxPublic) Clone() BoxPublic {
+	return append(BoxPublic(nil), k...)
+}
+
+// Valid reports whether k has the right length and is not one of the
+// low-order Curve25519 points, with which no shared key can be computed. It is
+// a cheap filter for garbage from the network, not authentication: a valid
+// key says nothing about whether the peer holds the matching private key.
+func (k BoxPublic) Valid() bool {
+	if len(k) != BoxPublicLength {
+		return false
+	}
+	scalar := RandomBytes(ScalarMultScalarBytes)
+	defer wipe(scalar)
+	q, err := ScalarMult(scalar, k)
+	if err != nil {
+		return false
+	}
+	wipe(q)
+	return true
+}
+
+// Fingerprint is like EdDSAPublic.Fingerprint, but hashes "natrium box "
+// followed by the key.
+func (k BoxPublic) Fingerprint() string {
+	return fingerprint("natrium box ", k)
+}
+
+// Equal reports whether two box private keys are the same, in constant time.
+func (k BoxPrivate) Equal(other BoxPrivate) bool {
+	return MemCmp(k, other)
+}
+
+// Clone returns an independent copy of the private key.
+func (k BoxPrivate) Clone() BoxPrivate {
+	return append(BoxPrivate(nil), k...)
+}
+
+// BoxGenerateKey generates a box private key.
+func BoxGenerateKey() BoxPrivate {
+	mustInit()
+	// like crypto_box_keypair, which draws the private key with
+	// randombytes_buf, but honouring SetRandSource
+	priv := make([]byte, BoxPrivateLength)
+	RandBytes(priv)
+	return priv
+}
+
+// Destroy wipes the private key from memory, like EdDSAPrivate.Destroy.
+func (k BoxPrivate) Destroy() {
+	wipe(k)
+}
+
+// PublicKey derives the public key corresponding to the box private key, like
+// EdDSAPrivate.PublicKey. Any 32 bytes are a valid private key, since
+// ScalarMultBase clamps the scalar itself.
+func (k BoxPrivate) PublicKey() BoxPublic {
+	if len(k) != BoxPrivateLength {
+		panic("box private key has the wrong length")
+	}
+	return ScalarMultBase(k)
+}
+
+// NewNonce returns a random nonce of BoxNonceLength bytes for Seal. Box uses
+// 24-byte XSalsa20 nonces, so random ones are safe to use for any number of
+// messages between the same pair of keys.
+func (k BoxPrivate) NewNonce() []byte {
+	return RandomBytes(BoxNonceLength)
+}
+
+func (k BoxPrivate) checkBox(nonce []byte, other BoxPublic) {
+	if len(k) != BoxPrivateLength {
+		panic("box private key has the wrong length")
+	}
+	if len(other) != BoxPublicLength {
+		panic("box public key has the wrong length")
+	}
+	if len(nonce) != BoxNonceLength {
+		panic("box nonce has the wrong length")
+	}
+}
+
+// Seal encrypts and authenticates a message to the given public key. The
+// nonce must be BoxNonceLength bytes long, and must never be reused for the
+// same pair of keys. Seal panics if to is a low-order point; use SealSafe
+// when the public key comes from an untrusted peer.
+func (k BoxPrivate) Seal(message, nonce []byte, to BoxPublic) []byte {
+	out, err := k.SealSafe(message, nonce, to)
+	if err != nil {
+		panic(err.Error())
+	}
+	return out
+}
+
+// SealSafe is like Seal, but returns ErrLowOrderPoint instead of panicking
+// when to is a low-order point, which would make the shared key predictable.
+func (k BoxPrivate) SealSafe(message, nonce []byte, to BoxPublic) ([]byte, error) {
+	k.checkBox(nonce, to)
+	out := make([]byte, len(message)+BoxMACLength)
+	rv := C.crypto_box_easy(g2cbt(out), g2cbt(message), C.ulonglong(len(message)),
+		g2cbt(nonce), g2cbt(to), g2cbt(k))
+	if rv != 0 {
+		return nil, ErrLowOrderPoint
+	}
+	return out, nil
+}
+
+// lowOrder reports whether the shared key between k and other cannot be
+// computed, which for a well-formed private key means that other is a
+// low-order point.
+func (k BoxPrivate) lowOrder(other BoxPublic) bool {
+	shared := make([]byte, C.crypto_box_BEFORENMBYTES)
+	defer wipe(shared)
+	return C.crypto_box_beforenm(g2cbt(shared), g2cbt(other), g2cbt(k)) != 0
+}
+
+// Open decrypts and verifies a ciphertext produced by Seal from the given
+// public key. A low-order public key gives ErrLowOrderPoint rather than
+// ErrDecryptionFailed.
+func (k BoxPrivate) Open(ciphertext, nonce []byte, from BoxPublic) ([]byte, error) {
+	k.checkBox(nonce, from)
+	if len(ciphertext) < BoxMACLength {
+		return nil, fmt.Errorf("%w: ciphertext too short", ErrDecryptionFailed)
+	}
+	out := make([]byte, len(ciphertext)-BoxMACLength)
+	rv := C.crypto_box_open_easy(g2cbt(out), g2cbt(ciphertext), C.ulonglong(len(ciphertext)),
+		g2cbt(nonce), g2cbt(from), g2cbt(k))
+	if rv != 0 {
+		if k.lowOrder(from) {
+			return nil, ErrLowOrderPoint
+		}
+		return nil, ErrDecryptionFailed
+	}
+	return out, nil
+}
+
+// BoxAutoOverhead is the number of bytes SealAuto adds to a message: the
+// nonce and the MAC.
+const BoxAutoOverhead = BoxNonceLength + BoxMACLength
+
+// SealAuto is like Seal, but picks a random nonce itself. The result is the
+// 24-byte nonce followed by the output of Seal, that is, the 16-byte MAC and
+// then the encrypted message, for BoxAutoOverhead bytes more than the message.
+// Random nonces of this size are safe, so SealAuto is the simplest way to use
+// box correctly.
+func (k BoxPrivate) SealAuto(message []byte, to BoxPublic) []byte {
+	nonce := k.NewNonce()
+	return append(nonce, k.Seal(message, nonce, to)...)
+}
+
+// OpenAuto decrypts and verifies a ciphertext produced by SealAuto from the
+// given public key.
+func (k BoxPrivate) OpenAuto(ciphertext []byte, from BoxPublic) ([]byte, error) {
+	if len(ciphertext) < BoxAutoOverhead {
+		return nil, fmt.Errorf("%w: ciphertext too short", ErrDecryptionFailed)
+	}
+	return k.Open(ciphertext[BoxNonceLength:], ciphertext[:BoxNonceLength], from)
+}
+
+// SealDetached is like Seal, but returns the MAC separately from the
+// ciphertext, which is as long as the message. Like Seal, it panics if to
+// is a low-order point; use SealDetachedSafe for an untrusted peer.
+func (k BoxPrivate) SealDetached(message, nonce []byte, to BoxPublic) (ciphertext, mac []byte) {
+	ciphertext, mac, err := k.SealDetachedSafe(message, nonce, to)
+	if err != nil {
+		panic(err.Error())
+	}
+	return ciphertext, mac
+}
+
+// SealDetachedSafe is like SealDetached, but returns ErrLowOrderPoint instead
+// of panicking.
+func (k BoxPrivate) SealDetachedSafe(message, nonce []byte, to BoxPublic) (ciphertext, mac []byte, err error) {
+	k.checkBox(nonce, to)
+	ciphertext = make([]byte, len(message))
+	mac = make([]byte, BoxMACLength)
+	rv := C.crypto_box_detached(g2cbt(ciphertext), g2cbt(mac), g2cbt(message),
+		C.ulonglong(len(message)), g2cbt(nonce), g2cbt(to), g2cbt(k))
+	if rv != 0 {
+		return nil, nil, ErrLowOrderPoint
+	}
+	return ciphertext, mac, nil
+}
+
+// OpenDetached decrypts and verifies a ciphertext and MAC produced by
+// SealDetached from the given public key.
+func (k BoxPrivate) OpenDetached(ciphertext, mac, nonce []byte, from BoxPublic) ([]byte, error) {
+	k.checkBox(nonce, from)
+	if len(mac) != BoxMACLength {
+		return nil, fmt.Errorf("%w: box MAC has the wrong length", ErrDecryptionFailed)
+	}
+	out := make([]byte, len(ciphertext))
+	rv := C.crypto_box_open_detached(g2cbt(out), g2cbt(ciphertext), g2cbt(mac),
+		C.ulonglong(len(ciphertext)), g2cbt(nonce), g2cbt(from), g2cbt(k))
+	if rv != 0 {
+		if k.lowOrder(from) {
+			return nil, ErrLowOrderPoint
+		}
+		return nil, ErrDecryptionFailed
+	}
+	return out, nil
+}
+
+// Seal anonymously encrypts a message to the public key. A fresh ephemeral
+// key pair is generated for every message and its public half is included in
+// the ciphertext, so the recipient needs only their own key pair to open it
+// and learns nothing about who sent it.
+func (k BoxPublic) Seal(message []byte) []byte {
+	if len(k) != BoxPublicLength {
+		panic("box public key has the wrong length")
+	}
+	out := make([]byte, len(message)+BoxSealOverhead)
+	rv := C.crypto_box_seal(g2cbt(out), g2cbt(message), C.ulonglong(len(message)), g2cbt(k))
+	if rv != 0 {
+		panic("crypto_box_seal returned non-zero")
+	}
+	return out
+}
+
+// SealedLen returns the length of the sealed box BoxPublic.Seal makes from an
+// n-byte message, which includes the ephemeral public key and the MAC.
+func SealedLen(n int) int {
+	return n + BoxSealOverhead
+}
+
+// ErrSealedBoxTooShort is returned by SealOpen and SealEphemeralPublic for
+// input too short to be a sealed box at all. It wraps ErrDecryptionFailed, so
+// checking for that still catches every failure.
+var ErrSealedBoxTooShort = fmt.Errorf("%w: sealed box too short to hold an ephemeral key and MAC",
+	ErrDecryptionFailed)
+
+// SealOpen decrypts a ciphertext produced by BoxPublic.Seal for this key. It
+// is safe to call on arbitrary bytes from the network. Input shorter than
+// BoxSealOverhead gives ErrSealedBoxTooShort; a forged or corrupted
+// ciphertext, one sealed to another key, or one carrying a low-order
+// ephemeral key gives ErrDecryptionFailed itself.
+func (k BoxPrivate) SealOpen(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < BoxSealOverhead {
+		return nil, ErrSealedBoxTooShort
+	}
+	out := make([]byte, len(ciphertext)-BoxSealOverhead)
+	rv := C.crypto_box_seal_open(g2cbt(out), g2cbt(ciphertext), C.ulonglong(len(ciphertext)),
+		g2cbt(k.PublicKey()), g2cbt(k))
+	if rv != 0 {
+		return nil, ErrDecryptionFailed
+	}
+	return out, nil
+}
+
+// SealEphemeralPublic returns a copy of the ephemeral public key that
+// BoxPublic.Seal generated for a sealed box, which is its first BoxPublicLength
+// bytes. A new key is drawn for every message, so it can tell sealed boxes
+// apart or match up copies of the same one, but it does not identify the
+// sender and is not authenticated until SealOpen succeeds. Input too short to
+// be a sealed box gives ErrSealedBoxTooShort, and an ephemeral key that is a
+// low-order point gives ErrLowOrderPoint.
+func SealEphemeralPublic(ciphertext []byte) (BoxPublic, error) {
+	if len(ciphertext) < BoxSealOverhead {
+		return nil, ErrSealedBoxTooShort
+	}
+	toret := BoxPublic(append([]byte(nil), ciphertext[:BoxPublicLength]...))
+	if !toret.Valid() {
+		return nil, ErrLowOrderPoint
+	}
+	return toret, nil
+}
+
+// SharedKey derives an outLen-byte key shared between our private key and
+// their public key, which the other side obtains by calling SharedKey with
+// its own private key and our public key. Unlike ScalarMult, the output is
+// safe to use directly as a key. outLen must be between SubkeyBytesMin and
+// SubkeyBytesMax.
+//
+// The construction, for interoperability, is as follows. Let q be
+// X25519(priv, pub), and lo and hi the two public keys in lexicographic
+// order. Then a 32-byte master key is computed as unkeyed
+// BLAKE2b-256(q || lo || hi), and the result is crypto_kdf_derive_from_key
+// with subkey id 1 and the given context under that master key.
+func SharedKey(priv BoxPrivate, pub BoxPublic, context [8]byte, outLen int) ([]byte, error) {
+	if len(priv) != BoxPrivateLength {
+		return nil, keyLengthError("box private key", len(priv), BoxPrivateLength)
+	}
+	if len(pub) != BoxPublicLength {
+		return nil, keyLengthError("box public key", len(pub), BoxPublicLength)
+	}
+	q, err := ScalarMult(priv, pub)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(q)
+	lo, hi := []byte(priv.PublicKey()), []byte(pub)
+	if bytes.Compare(lo, hi) > 0 {
+		lo, hi = hi, lo
+	}
+	input := append(append(append([]byte(nil), q...), lo...), hi...)
+	defer wipe(input)
+	master := MasterKey(GenericHash(input, MasterKeyLength))
+	defer master.Destroy()
+	return master.Subkey(1, context, outLen)
+}
+
+// BoxShared represents a shared key precomputed from a box key pair, for
+// exchanging many messages with the same peer without repeating the
+// Curve25519 computation each time. Copies made by assignment share memory;
+// see Clone.
+type BoxShared []byte
+
+// String returns a redacted placeholder so the key is not printed by accident.
+func (k BoxShared) String() string {
+	return redacted("boxshr", k)
+}
+
+// Format implements fmt.Formatter, printing String for every verb.
+func (k BoxShared) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, k.String())
+}
+
+// Precompute derives the shared key between our private key and their public
+// key. Sealing with the result is equivalent to sealing with the two keys.
+func Precompute(priv BoxPrivate, publ BoxPublic) BoxShared {
+	priv.checkBox(make([]byte, BoxNonceLength), publ)
+	toret := make([]byte, BoxSharedLength)
+	rv := C.crypto_box_beforenm(g2cbt(toret), g2cbt(publ), g2cbt(priv))
+	if rv != 0 {
+		panic("crypto_box_beforenm returned non-zero")
+	}
+	return toret
+}
+
+// Seal encrypts and authenticates a message using the shared key. It is safe
+// to call concurrently from several goroutines.
+func (k BoxShared) Seal(message, nonce []byte) []byte {
+	if len(k) != BoxSharedLength {
+		panic("box shared key has the wrong length")
+	}
+	if len(nonce) != BoxNonceLength {
+		panic("box nonce has the wrong length")
+	}
+	out := make([]byte, len(message)+BoxMACLength)
+	rv := C.crypto_box_easy_afternm(g2cbt(out), g2cbt(message), C.ulonglong(len(message)),
+		g2cbt(nonce), g2cbt(k))
+	if rv != 0 {
+		panic("crypto_box_easy_afternm returned non-zero")
+	}
+	return out
+}
+
+// Open decrypts and verifies a ciphertext using the shared key. It is safe to
+// call concurrently from several goroutines. A shared key of the wrong length
+// gives an error wrapping ErrInvalidKeyLength.
+func (k BoxShared) Open(ciphertext, nonce []byte) ([]byte, error) {
+	if len(k) != BoxSharedLength {
+		return nil, keyLengthError("box shared key", len(k), BoxSharedLength)
+	}
+	if len(nonce) != BoxNonceLength {
+		panic("box nonce has the wrong length")
+	}
+	if len(ciphertext) < BoxMACLength {
+		return nil, fmt.Errorf("%w: ciphertext too short", ErrDecryptionFailed)
+	}
+	out := make([]byte, len(ciphertext)-BoxMACLength)
+	rv := C.crypto_box_open_easy_afternm(g2cbt(out), g2cbt(ciphertext), C.ulonglong(len(ciphertext)),
+		g2cbt(nonce), g2cbt(k))
+	if rv != 0 {
+		return nil, ErrDecryptionFailed
+	}
+	return out, nil
+}
+
+// Destroy wipes the shared key from memory. The key must not be used
+// afterwards.
+func (k BoxShared) Destroy() {
+	wipe(k)
+}
+
+// Clone returns a copy of the shared key that Destroy on k will not wipe.
+func (k BoxShared) Clone() BoxShared {
+	return append(BoxShared(nil), k...)
+}
+
+// MarshalJSON implements the MarshalJSON interface.
+func (k BoxPublic) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]byte(k))
+}
+
+// UnmarshalJSON implements the UnmarshalJSON interface, reversing MarshalJSON.
+func (k *BoxPublic) UnmarshalJSON(data []byte) error {
+	raw, err := unmarshalKeyJSON(data, BoxPublicLength, "box public key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalJSON implements the MarshalJSON interface. As with EdDSAPrivate, the
+// secret key is encoded in full.
+func (k BoxPrivate) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]byte(k))
+}
+
+// UnmarshalJSON implements the UnmarshalJSON interface, reversing MarshalJSON.
+func (k *BoxPrivate) UnmarshalJSON(data []byte) error {
+	raw, err := unmarshalKeyJSON(data, BoxPrivateLength, "box private key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// GobEncode implements the gob.GobEncoder interface.
+func (k BoxPublic) GobEncode() ([]byte, error) {
+	return encodeKeyGob(gobBoxPublic, k)
+}
+
+// GobDecode implements the gob.GobDecoder interface, rejecting anything that
+// is not a box public key of the right length.
+func (k *BoxPublic) GobDecode(data []byte) error {
+	raw, err := decodeKeyGob(data, gobBoxPublic, BoxPublicLength, "box public key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// GobEncode implements the gob.GobEncoder interface.
+func (k BoxPrivate) GobEncode() ([]byte, error) {
+	return encodeKeyGob(gobBoxPrivate, k)
+}
+
+// GobDecode implements the gob.GobDecoder interface, rejecting anything that
+// is not a box private key of the right length.
+func (k *BoxPrivate) GobDecode(data []byte) error {
+	raw, err := decodeKeyGob(data, gobBoxPrivate, BoxPrivateLength, "box private key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, returning a
+// copy of the raw key.
+func (k BoxPublic) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), k...), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. The
+// data is length-checked and copied, never aliased.
+func (k *BoxPublic) UnmarshalBinary(data []byte) error {
+	raw, err := copyKey(data, BoxPublicLength, "box public key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, returning a
+// copy of the raw key.
+func (k BoxPrivate) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), k...), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. The
+// data is length-checked and copied, never aliased.
+func (k *BoxPrivate) UnmarshalBinary(data []byte) error {
+	raw, err := copyKey(data, BoxPrivateLength, "box private key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, encoding the
+// key as lowercase hex.
+func (k BoxPublic) MarshalText() ([]byte, error) {
+	return []byte(BinToHex(k)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, reversing
+// MarshalText.
+func (k *BoxPublic) UnmarshalText(text []byte) error {
+	raw, err := unmarshalKeyText(text, BoxPublicLength, "box public key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, encoding the
+// key as lowercase hex.
+func (k BoxPrivate) MarshalText() ([]byte, error) {
+	return []byte(BinToHex(k)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, reversing
+// MarshalText.
+func (k *BoxPrivate) UnmarshalText(text []byte) error {
+	raw, err := unmarshalKeyText(text, BoxPrivateLength, "box private key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// PEM block types used by Encode with EncodingPEM.
+const (
+	BoxPublicPEMType  = "NATRIUM BOX PUBLIC KEY"
+	BoxPrivatePEMType = "NATRIUM BOX PRIVATE KEY"
+)
+
+// Encode serializes the public key in the given encoding, as raw bytes, hex,
+// PEM or base64. PEM blocks have type BoxPublicPEMType.
+func (k BoxPublic) Encode(enc Encoding) ([]byte, error) {
+	return encodeKey(k, enc, BoxPublicPEMType, BoxPublicLength, "box public key")
+}
+
+// DecodeBoxPublic parses a public key written by Encode with the same
+// encoding. Data of the wrong length, or a PEM block of another type, gives an
+// error.
+func DecodeBoxPublic(data []byte, enc Encoding) (BoxPublic, error) {
+	return decodeKey(data, enc, BoxPublicPEMType, BoxPublicLength, "box public key")
+}
+
+// Encode serializes the private key in the given encoding, like
+// BoxPublic.Encode. Every encoding holds the secret in the clear.
+func (k BoxPrivate) Encode(enc Encoding) ([]byte, error) {
+	return encodeKey(k, enc, BoxPrivatePEMType, BoxPrivateLength, "box private key")
+}
+
+// DecodeBoxPrivate parses a private key written by Encode with the same
+// encoding.
+func DecodeBoxPrivate(data []byte, enc Encoding) (BoxPrivate, error) {
+	return decodeKey(data, enc, BoxPrivatePEMType, BoxPrivateLength, "box private key")
+}
@@ -0,0 +1,349 @@
+package natrium
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestBox(t *testing.T) {
+	alice := BoxGenerateKey()
+	bob := BoxGenerateKey()
+	nonce := make([]byte, BoxNonceLength)
+	RandBytes(nonce)
+	message := []byte("Hello World")
+	ciphertext := alice.Seal(message, nonce, bob.PublicKey())
+	if len(ciphertext) != len(message)+BoxMACLength {
+		t.FailNow()
+	}
+	plaintext, err := bob.Open(ciphertext, nonce, alice.PublicKey())
+	if err != nil || string(plaintext) != string(message) {
+		t.FailNow()
+	}
+	ciphertext[3] ^= 1
+	if _, err := bob.Open(ciphertext, nonce, alice.PublicKey()); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestBoxFromEdDSA(t *testing.T) {
+	alice := EdDSAGenerateKey()
+	bob := EdDSAGenerateKey()
+	bobBox, err := bob.PublicKey().ToCurve25519()
+	if err != nil {
+		t.FailNow()
+	}
+	aliceBox, err := alice.PublicKey().ToCurve25519()
+	if err != nil {
+		t.FailNow()
+	}
+	nonce := make([]byte, BoxNonceLength)
+	ciphertext := alice.ToCurve25519().Seal([]byte("Hello World"), nonce, bobBox)
+	plaintext, err := bob.ToCurve25519().Open(ciphertext, nonce, aliceBox)
+	if err != nil || string(plaintext) != "Hello World" {
+		t.FailNow()
+	}
+}
+
+func TestBoxJSON(t *testing.T) {
+	priv := BoxGenerateKey()
+	bts, err := json.Marshal([]interface{}{priv, priv.PublicKey()})
+	if err != nil {
+		t.FailNow()
+	}
+	var decodedPriv BoxPrivate
+	var decodedPubl BoxPublic
+	err = json.Unmarshal(bts, &[]interface{}{&decodedPriv, &decodedPubl})
+	if err != nil {
+		t.FailNow()
+	}
+	if CTCompare(decodedPriv, priv) != 0 || CTCompare(decodedPubl, priv.PublicKey()) != 0 {
+		t.FailNow()
+	}
+}
+
+func TestBoxSeal(t *testing.T) {
+	priv := BoxGenerateKey()
+	message := []byte("Hello World")
+	ciphertext := priv.PublicKey().Seal(message)
+	if len(ciphertext) != len(message)+BoxSealOverhead {
+		t.FailNow()
+	}
+	plaintext, err := priv.SealOpen(ciphertext)
+	if err != nil || string(plaintext) != string(message) {
+		t.FailNow()
+	}
+	if _, err := BoxGenerateKey().SealOpen(ciphertext); err == nil {
+		t.FailNow()
+	}
+	for _, bad := range [][]byte{nil, {}, ciphertext[:BoxSealOverhead-1], make([]byte, BoxSealOverhead)} {
+		if _, err := priv.SealOpen(bad); !errors.Is(err, ErrDecryptionFailed) {
+			t.FailNow()
+		}
+	}
+	for _, i := range []int{0, BoxPublicLength, len(ciphertext) - 1} {
+		tampered := append([]byte(nil), ciphertext...)
+		tampered[i] ^= 1
+		if _, err := priv.SealOpen(tampered); err != ErrDecryptionFailed {
+			t.FailNow()
+		}
+	}
+	// an empty message seals to exactly the overhead
+	empty, err := priv.SealOpen(priv.PublicKey().Seal(nil))
+	if err != nil || len(empty) != 0 {
+		t.FailNow()
+	}
+}
+
+func TestSealEphemeralPublic(t *testing.T) {
+	priv := BoxGenerateKey()
+	ciphertext := priv.PublicKey().Seal([]byte("Hello World"))
+	eph, err := SealEphemeralPublic(ciphertext)
+	if err != nil || !bytes.Equal(eph, ciphertext[:BoxPublicLength]) {
+		t.FailNow()
+	}
+	other, _ := SealEphemeralPublic(priv.PublicKey().Seal([]byte("Hello World")))
+	if eph.Equal(other) {
+		t.FailNow()
+	}
+	short := ciphertext[:BoxSealOverhead-1]
+	if _, err := SealEphemeralPublic(short); err != ErrSealedBoxTooShort {
+		t.FailNow()
+	}
+	if _, err := priv.SealOpen(short); err != ErrSealedBoxTooShort || !errors.Is(err, ErrDecryptionFailed) {
+		t.FailNow()
+	}
+	tampered := append([]byte(nil), ciphertext...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := SealEphemeralPublic(tampered); err != nil {
+		t.FailNow()
+	}
+	if _, err := priv.SealOpen(tampered); err != ErrDecryptionFailed {
+		t.FailNow()
+	}
+	if _, err := SealEphemeralPublic(make([]byte, BoxSealOverhead)); err != ErrLowOrderPoint {
+		t.FailNow()
+	}
+}
+
+func TestBoxShared(t *testing.T) {
+	alice := BoxGenerateKey()
+	bob := BoxGenerateKey()
+	nonce := make([]byte, BoxNonceLength)
+	RandBytes(nonce)
+	shared := Precompute(alice, bob.PublicKey())
+	ciphertext := shared.Seal([]byte("Hello World"), nonce)
+	plaintext, err := bob.Open(ciphertext, nonce, alice.PublicKey())
+	if err != nil || string(plaintext) != "Hello World" {
+		t.FailNow()
+	}
+	plaintext, err = Precompute(bob, alice.PublicKey()).Open(ciphertext, nonce)
+	if err != nil || string(plaintext) != "Hello World" {
+		t.FailNow()
+	}
+	shared.Destroy()
+	for _, b := range shared {
+		if b != 0 {
+			t.FailNow()
+		}
+	}
+	var zero BoxShared
+	if _, err := zero.Open(ciphertext, nonce); !errors.Is(err, ErrInvalidKeyLength) {
+		t.FailNow()
+	}
+	defer func() {
+		if recover() == nil {
+			t.FailNow()
+		}
+	}()
+	shared[:16].Seal([]byte("Hello World"), nonce)
+}
+
+func BenchmarkBox(b *testing.B) {
+	alice := BoxGenerateKey()
+	bob := BoxGenerateKey().PublicKey()
+	nonce := make([]byte, BoxNonceLength)
+	message := make([]byte, 1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		alice.Seal(message, nonce, bob)
+	}
+}
+
+func BenchmarkBox_Shared(b *testing.B) {
+	shared := Precompute(BoxGenerateKey(), BoxGenerateKey().PublicKey())
+	nonce := make([]byte, BoxNonceLength)
+	message := make([]byte, 1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		shared.Seal(message, nonce)
+	}
+}
+
+func TestNewBoxKeys(t *testing.T) {
+	priv := BoxGenerateKey()
+	if _, err := NewBoxPrivate(priv); err != nil {
+		t.FailNow()
+	}
+	if _, err := NewBoxPublic(priv.PublicKey()[1:]); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestBoxBinary(t *testing.T) {
+	priv := BoxGenerateKey()
+	data, _ := priv.MarshalBinary()
+	var got BoxPrivate
+	if got.UnmarshalBinary(data) != nil || !got.Equal(priv) {
+		t.FailNow()
+	}
+	data[0] ^= 1
+	if !got.Equal(priv) {
+		t.FailNow()
+	}
+	var publ BoxPublic
+	if publ.UnmarshalBinary(data[:31]) == nil {
+		t.FailNow()
+	}
+}
+
+func TestBoxDetached(t *testing.T) {
+	alice := BoxGenerateKey()
+	bob := BoxGenerateKey()
+	nonce := RandomBytes(BoxNonceLength)
+	message := []byte("Hello World")
+	ciphertext, mac := alice.SealDetached(message, nonce, bob.PublicKey())
+	if len(ciphertext) != len(message) || len(mac) != BoxMACLength {
+		t.FailNow()
+	}
+	plaintext, err := bob.OpenDetached(ciphertext, mac, nonce, alice.PublicKey())
+	if err != nil || string(plaintext) != string(message) {
+		t.FailNow()
+	}
+	mac[0] ^= 1
+	if _, err := bob.OpenDetached(ciphertext, mac, nonce, alice.PublicKey()); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestBoxAuto(t *testing.T) {
+	alice := BoxGenerateKey()
+	bob := BoxGenerateKey()
+	ct := alice.SealAuto([]byte("hello"), bob.PublicKey())
+	if len(ct) != 5+BoxAutoOverhead {
+		t.FailNow()
+	}
+	pt, err := bob.OpenAuto(ct, alice.PublicKey())
+	if err != nil || string(pt) != "hello" {
+		t.FailNow()
+	}
+	// the layout is the nonce followed by the output of Seal
+	pt, err = bob.Open(ct[BoxNonceLength:], ct[:BoxNonceLength], alice.PublicKey())
+	if err != nil || string(pt) != "hello" {
+		t.FailNow()
+	}
+	if bytes.Equal(ct[:BoxNonceLength], alice.SealAuto([]byte("hello"), bob.PublicKey())[:BoxNonceLength]) {
+		t.FailNow()
+	}
+	ct[len(ct)-1] ^= 1
+	if _, err := bob.OpenAuto(ct, alice.PublicKey()); err != ErrDecryptionFailed {
+		t.FailNow()
+	}
+	if _, err := bob.OpenAuto(ct[:BoxAutoOverhead-1], alice.PublicKey()); !errors.Is(err, ErrDecryptionFailed) {
+		t.FailNow()
+	}
+}
+
+func TestBoxNewNonce(t *testing.T) {
+	priv := BoxGenerateKey()
+	nonce := priv.NewNonce()
+	if len(nonce) != BoxNonceLength || MemCmp(nonce, priv.NewNonce()) {
+		t.FailNow()
+	}
+}
+
+func TestBoxLowOrder(t *testing.T) {
+	priv := BoxGenerateKey()
+	nonce := priv.NewNonce()
+	// a point of order 8, and the all-zero point
+	points := []string{
+		"e0eb7a7c3b41b8ae1656e3faf19fc46ada098deb9c32b1fd866205165f49b800",
+		"0000000000000000000000000000000000000000000000000000000000000000",
+	}
+	for _, point := range points {
+		bad, _ := HexToBin(point)
+		if _, err := priv.SealSafe([]byte("Hello"), nonce, bad); !errors.Is(err, ErrLowOrderPoint) {
+			t.FailNow()
+		}
+		if _, _, err := priv.SealDetachedSafe([]byte("Hello"), nonce, bad); !errors.Is(err, ErrLowOrderPoint) {
+			t.FailNow()
+		}
+		if _, err := priv.Open(make([]byte, 40), nonce, bad); !errors.Is(err, ErrLowOrderPoint) {
+			t.FailNow()
+		}
+		if _, err := ScalarMult(priv, bad); !errors.Is(err, ErrLowOrderPoint) {
+			t.FailNow()
+		}
+		if BoxPublic(bad).Valid() {
+			t.FailNow()
+		}
+	}
+	if _, err := priv.Open(make([]byte, 40), nonce, BoxGenerateKey().PublicKey()); !errors.Is(err, ErrDecryptionFailed) {
+		t.FailNow()
+	}
+	if !priv.PublicKey().Valid() || priv.PublicKey()[1:].Valid() {
+		t.FailNow()
+	}
+}
+
+func TestBoxPublicKey(t *testing.T) {
+	alice := BoxGenerateKey()
+	bob := BoxPrivate(RandomBytes(BoxPrivateLength))
+	if len(bob.PublicKey()) != BoxPublicLength || !bob.PublicKey().Equal(ScalarMultBase(bob)) {
+		t.FailNow()
+	}
+	nonce := alice.NewNonce()
+	plaintext, err := bob.Open(alice.Seal([]byte("Hello"), nonce, bob.PublicKey()), nonce, alice.PublicKey())
+	if err != nil || string(plaintext) != "Hello" {
+		t.FailNow()
+	}
+}
+
+func TestSharedKey(t *testing.T) {
+	alice := BoxPrivate(bytes.Repeat([]byte{1}, BoxPrivateLength))
+	bob := BoxPrivate(bytes.Repeat([]byte{2}, BoxPrivateLength))
+	context := [8]byte{'t', 'e', 's', 't'}
+	a, err := SharedKey(alice, bob.PublicKey(), context, 32)
+	if err != nil {
+		t.FailNow()
+	}
+	b, err := SharedKey(bob, alice.PublicKey(), context, 32)
+	if err != nil || !MemCmp(a, b) {
+		t.FailNow()
+	}
+	if BinToHex(a) != "d333752356e3b27d44febd5b34ca59b0ef62d3db33a66eb0f2191553f6320235" {
+		t.FailNow()
+	}
+	if _, err := SharedKey(alice, make([]byte, BoxPublicLength), context, 32); !errors.Is(err, ErrLowOrderPoint) {
+		t.FailNow()
+	}
+}
+
+func TestSealedLen(t *testing.T) {
+	publ := BoxGenerateKey().PublicKey()
+	for _, n := range []int{0, 1, 1000} {
+		if len(publ.Seal(make([]byte, n))) != SealedLen(n) {
+			t.FailNow()
+		}
+	}
+}
+
+func TestBoxEncode(t *testing.T) {
+	priv := BoxGenerateKey()
+	publ := priv.PublicKey()
+	checkEncoding(t, []encodingCase{
+		{publ, BoxPublicPEMType, publ.Encode, func(b []byte, e Encoding) ([]byte, error) { return DecodeBoxPublic(b, e) }},
+		{priv, BoxPrivatePEMType, priv.Encode, func(b []byte, e Encoding) ([]byte, error) { return DecodeBoxPrivate(b, e) }},
+	})
+}
@@ -0,0 +1,529 @@
+package natrium
+
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #include <stdio.h>
+// #include <sodium.h>
+import "C"
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// XChaChaBoxPublic is a Curve25519 public key for the XChaCha20-Poly1305
+// variant of box, crypto_box_curve25519xchacha20poly1305. Its methods mirror
+// those of BoxPublic.
+//
+// The keys of the two variants are the same Curve25519 keys, so converting
+// between BoxPublic and XChaChaBoxPublic is allowed, but the ciphertexts are
+// NOT compatible: a message sealed by one variant can only be opened by the
+// same variant. Both ends must agree on which one they use.
+type XChaChaBoxPublic []byte
+
+// XChaChaBoxPrivate is the private key of the XChaCha20-Poly1305 box, the
+// counterpart of BoxPrivate. As with XChaChaBoxPublic, the key itself works
+// with either variant, but what it seals does not.
+type XChaChaBoxPrivate []byte
+
+// String returns the key in hex, prefixed with its kind.
+func (k XChaChaBoxPublic) String() string {
+	return fmt.Sprintf("xboxpub:%x", []byte(k))
+}
+
+// String returns a redacted placeholder so the key is not printed by accident.
+func (k XChaChaBoxPrivate) String() string {
+	return redacted("xboxprv", k)
+}
+
+// Format implements fmt.Formatter, printing String for every verb.
+func (k XChaChaBoxPrivate) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, k.String())
+}
+
+// HexString returns the key as "xboxprv:" followed by hex, which
+// XChaChaBoxPrivateFromHex parses back.
+func (k XChaChaBoxPrivate) HexString() string {
+	return fmt.Sprintf("xboxprv:%x", []byte(k))
+}
+
+// XChaChaBoxPublicLength is the length of an XChaChaBoxPublic.
+const XChaChaBoxPublicLength int = C.crypto_box_curve25519xchacha20poly1305_PUBLICKEYBYTES
+
+// XChaChaBoxPrivateLength is the length of an XChaChaBoxPrivate.
+const XChaChaBoxPrivateLength int = C.crypto_box_curve25519xchacha20poly1305_SECRETKEYBYTES
+
+// XChaChaBoxNonceLength is the length of the nonce passed to
+// XChaChaBoxPrivate.Seal and Open.
+const XChaChaBoxNonceLength int = C.crypto_box_curve25519xchacha20poly1305_NONCEBYTES
+
+// XChaChaBoxMACLength is the number of bytes XChaChaBoxPrivate.Seal adds to a
+// message.
+const XChaChaBoxMACLength int = C.crypto_box_curve25519xchacha20poly1305_MACBYTES
+
+// XChaChaBoxSealOverhead is the number of bytes XChaChaBoxPublic.Seal adds to a
+// message.
+const XChaChaBoxSealOverhead int = C.crypto_box_curve25519xchacha20poly1305_SEALBYTES
+
+// XChaChaBoxAutoOverhead is the number of bytes XChaChaBoxPrivate.SealAuto adds
+// to a message.
+const XChaChaBoxAutoOverhead = XChaChaBoxNonceLength + XChaChaBoxMACLength
+
+// XChaChaBoxSharedLength is the length of an XChaChaBoxShared.
+const XChaChaBoxSharedLength int = C.crypto_box_curve25519xchacha20poly1305_BEFORENMBYTES
+
+// NewXChaChaBoxPublic returns a length-checked copy of b as an
+// XChaChaBoxPublic.
+func NewXChaChaBoxPublic(b []byte) (XChaChaBoxPublic, error) {
+	return copyKey(b, XChaChaBoxPublicLength, "XChaCha20 box public key")
+}
+
+// XChaChaBoxPublicFromHex parses a public key written in hex, optionally
+// prefixed with "xboxpub:".
+func XChaChaBoxPublicFromHex(s string) (XChaChaBoxPublic, error) {
+	return keyFromHex(s, "xboxpub:", XChaChaBoxPublicLength, "XChaCha20 box public key")
+}
+
+// NewXChaChaBoxPrivate returns a length-checked copy of b as an
+// XChaChaBoxPrivate.
+func NewXChaChaBoxPrivate(b []byte) (XChaChaBoxPrivate, error) {
+	return copyKey(b, XChaChaBoxPrivateLength, "XChaCha20 box private key")
+}
+
+// XChaChaBoxPrivateFromHex parses a private key written in hex, optionally
+// prefixed with "xboxprv:".
+func XChaChaBoxPrivateFromHex(s string) (XChaChaBoxPrivate, error) {
+	return keyFromHex(s, "xboxprv:", XChaChaBoxPrivateLength, "XChaCha20 box private key")
+}
+
+// PEM block types used by Encode with EncodingPEM.
+const (
+	XChaChaBoxPublicPEMType  = "NATRIUM XCHACHA BOX PUBLIC KEY"
+	XChaChaBoxPrivatePEMType = "NATRIUM XCHACHA BOX PRIVATE KEY"
+)
+
+// Encode serializes the public key in the given encoding, as raw bytes, hex,
+// PEM or base64. PEM blocks have type XChaChaBoxPublicPEMType.
+func (k XChaChaBoxPublic) Encode(enc Encoding) ([]byte, error) {
+	return encodeKey(k, enc, XChaChaBoxPublicPEMType, XChaChaBoxPublicLength, "XChaCha20 box public key")
+}
+
+// DecodeXChaChaBoxPublic parses a public key written by Encode with the same
+// encoding. Data of the wrong length, or a PEM block of another type, gives an
+// error.
+func DecodeXChaChaBoxPublic(data []byte, enc Encoding) (XChaChaBoxPublic, error) {
+	return decodeKey(data, enc, XChaChaBoxPublicPEMType, XChaChaBoxPublicLength, "XChaCha20 box public key")
+}
+
+// Encode serializes the private key in the given encoding, like
+// XChaChaBoxPublic.Encode. Every encoding holds the secret in the clear.
+func (k XChaChaBoxPrivate) Encode(enc Encoding) ([]byte, error) {
+	return encodeKey(k, enc, XChaChaBoxPrivatePEMType, XChaChaBoxPrivateLength, "XChaCha20 box private key")
+}
+
+// DecodeXChaChaBoxPrivate parses a private key written by Encode with the same
+// encoding.
+func DecodeXChaChaBoxPrivate(data []byte, enc Encoding) (XChaChaBoxPrivate, error) {
+	return decodeKey(data, enc, XChaChaBoxPrivatePEMType, XChaChaBoxPrivateLength, "XChaCha20 box private key")
+}
+
+// XChaChaBoxGenerateKey generates a private key for the XChaCha20 box.
+func XChaChaBoxGenerateKey() XChaChaBoxPrivate {
+	return XChaChaBoxPrivate(BoxGenerateKey())
+}
+
+// Equal reports, in constant time, whether two public keys are the same.
+func (k XChaChaBoxPublic) Equal(other XChaChaBoxPublic) bool {
+	return MemCmp(k, other)
+}
+
+// Clone returns an independent copy of the public key.
+func (k XChaChaBoxPublic) Clone() XChaChaBoxPublic {
+	return append(XChaChaBoxPublic(nil), k...)
+}
+
+// Valid is like BoxPublic.Valid.
+func (k XChaChaBoxPublic) Valid() bool {
+	return BoxPublic(k).Valid()
+}
+
+// Equal reports, in constant time, whether two private keys are the same.
+func (k XChaChaBoxPrivate) Equal(other XChaChaBoxPrivate) bool {
+	return MemCmp(k, other)
+}
+
+// Clone returns an independent copy of the private key.
+func (k XChaChaBoxPrivate) Clone() XChaChaBoxPrivate {
+	return append(XChaChaBoxPrivate(nil), k...)
+}
+
+// Destroy wipes the private key from memory.
+func (k XChaChaBoxPrivate) Destroy() {
+	wipe(k)
+}
+
+// PublicKey derives the public key, which is the same as that of the
+// BoxPrivate with the same bytes.
+func (k XChaChaBoxPrivate) PublicKey() XChaChaBoxPublic {
+	return XChaChaBoxPublic(BoxPrivate(k).PublicKey())
+}
+
+// NewNonce returns a random nonce of XChaChaBoxNonceLength bytes.
+func (k XChaChaBoxPrivate) NewNonce() []byte {
+	return RandomBytes(XChaChaBoxNonceLength)
+}
+
+func (k XChaChaBoxPrivate) checkBox(nonce []byte, other XChaChaBoxPublic) {
+	if len(k) != XChaChaBoxPrivateLength {
+		panic("XChaCha20 box private key has the wrong length")
+	}
+	if len(other) != XChaChaBoxPublicLength {
+		panic("XChaCha20 box public key has the wrong length")
+	}
+	if len(nonce) != XChaChaBoxNonceLength {
+		panic("XChaCha20 box nonce has the wrong length")
+	}
+}
+
+// lowOrder is like BoxPrivate.lowOrder. The two variants derive their shared
+// keys from the same X25519 output, so they fail on the same points.
+func (k XChaChaBoxPrivate) lowOrder(other XChaChaBoxPublic) bool {
+	return BoxPrivate(k).lowOrder(BoxPublic(other))
+}
+
+// Seal encrypts and authenticates a message to the given public key with
+// crypto_box_curve25519xchacha20poly1305_easy. Like BoxPrivate.Seal, it
+// panics if to is a low-order point.
+func (k XChaChaBoxPrivate) Seal(message, nonce []byte, to XChaChaBoxPublic) []byte {
+	out, err := k.SealSafe(message, nonce, to)
+	if err != nil {
+		panic(err.Error())
+	}
+	return out
+}
+
+// SealSafe is like Seal, but returns ErrLowOrderPoint instead of panicking.
+func (k XChaChaBoxPrivate) SealSafe(message, nonce []byte, to XChaChaBoxPublic) ([]byte, error) {
+	k.checkBox(nonce, to)
+	out := make([]byte, len(message)+XChaChaBoxMACLength)
+	rv := C.crypto_box_curve25519xchacha20poly1305_easy(g2cbt(out), g2cbt(message),
+		C.ulonglong(len(message)), g2cbt(nonce), g2cbt(to), g2cbt(k))
+	if rv != 0 {
+		return nil, ErrLowOrderPoint
+	}
+	return out, nil
+}
+
+// Open decrypts and verifies a ciphertext produced by Seal from the given
+// public key. Errors are as for BoxPrivate.Open.
+func (k XChaChaBoxPrivate) Open(ciphertext, nonce []byte, from XChaChaBoxPublic) ([]byte, error) {
+	k.checkBox(nonce, from)
+	if len(ciphertext) < XChaChaBoxMACLength {
+		return nil, fmt.Errorf("%w: ciphertext too short", ErrDecryptionFailed)
+	}
+	out := make([]byte, len(ciphertext)-XChaChaBoxMACLength)
+	rv := C.crypto_box_curve25519xchacha20poly1305_open_easy(g2cbt(out), g2cbt(ciphertext),
+		C.ulonglong(len(ciphertext)), g2cbt(nonce), g2cbt(from), g2cbt(k))
+	if rv != 0 {
+		if k.lowOrder(from) {
+			return nil, ErrLowOrderPoint
+		}
+		return nil, ErrDecryptionFailed
+	}
+	return out, nil
+}
+
+// SealAuto is like Seal, but prepends a random nonce to the output, in the
+// layout of BoxPrivate.SealAuto.
+func (k XChaChaBoxPrivate) SealAuto(message []byte, to XChaChaBoxPublic) []byte {
+	nonce := k.NewNonce()
+	return append(nonce, k.Seal(message, nonce, to)...)
+}
+
+// OpenAuto decrypts and verifies a ciphertext produced by SealAuto.
+func (k XChaChaBoxPrivate) OpenAuto(ciphertext []byte, from XChaChaBoxPublic) ([]byte, error) {
+	if len(ciphertext) < XChaChaBoxAutoOverhead {
+		return nil, fmt.Errorf("%w: ciphertext too short", ErrDecryptionFailed)
+	}
+	return k.Open(ciphertext[XChaChaBoxNonceLength:], ciphertext[:XChaChaBoxNonceLength], from)
+}
+
+// SealDetached is like Seal, but returns the MAC separately from the
+// ciphertext. Like Seal, it panics if to is a low-order point.
+func (k XChaChaBoxPrivate) SealDetached(message, nonce []byte, to XChaChaBoxPublic) (ciphertext, mac []byte) {
+	ciphertext, mac, err := k.SealDetachedSafe(message, nonce, to)
+	if err != nil {
+		panic(err.Error())
+	}
+	return ciphertext, mac
+}
+
+// SealDetachedSafe is like SealDetached, but returns ErrLowOrderPoint instead
+// of panicking.
+func (k XChaChaBoxPrivate) SealDetachedSafe(message, nonce []byte, to XChaChaBoxPublic) (ciphertext, mac []byte, err error) {
+	k.checkBox(nonce, to)
+	ciphertext = make([]byte, len(message))
+	mac = make([]byte, XChaChaBoxMACLength)
+	rv := C.crypto_box_curve25519xchacha20poly1305_detached(g2cbt(ciphertext), g2cbt(mac),
+		g2cbt(message), C.ulonglong(len(message)), g2cbt(nonce), g2cbt(to), g2cbt(k))
+	if rv != 0 {
+		return nil, nil, ErrLowOrderPoint
+	}
+	return ciphertext, mac, nil
+}
+
+// OpenDetached decrypts and verifies a ciphertext and MAC produced by
+// SealDetached.
+func (k XChaChaBoxPrivate) OpenDetached(ciphertext, mac, nonce []byte, from XChaChaBoxPublic) ([]byte, error) {
+	k.checkBox(nonce, from)
+	if len(mac) != XChaChaBoxMACLength {
+		return nil, fmt.Errorf("%w: box MAC has the wrong length", ErrDecryptionFailed)
+	}
+	out := make([]byte, len(ciphertext))
+	rv := C.crypto_box_curve25519xchacha20poly1305_open_detached(g2cbt(out), g2cbt(ciphertext),
+		g2cbt(mac), C.ulonglong(len(ciphertext)), g2cbt(nonce), g2cbt(from), g2cbt(k))
+	if rv != 0 {
+		if k.lowOrder(from) {
+			return nil, ErrLowOrderPoint
+		}
+		return nil, ErrDecryptionFailed
+	}
+	return out, nil
+}
+
+// Seal anonymously encrypts a message to the public key with
+// crypto_box_curve25519xchacha20poly1305_seal, like BoxPublic.Seal.
+func (k XChaChaBoxPublic) Seal(message []byte) []byte {
+	if len(k) != XChaChaBoxPublicLength {
+		panic("XChaCha20 box public key has the wrong length")
+	}
+	out := make([]byte, len(message)+XChaChaBoxSealOverhead)
+	rv := C.crypto_box_curve25519xchacha20poly1305_seal(g2cbt(out), g2cbt(message),
+		C.ulonglong(len(message)), g2cbt(k))
+	if rv != 0 {
+		panic("crypto_box_curve25519xchacha20poly1305_seal returned non-zero")
+	}
+	return out
+}
+
+// SealOpen decrypts a ciphertext produced by XChaChaBoxPublic.Seal for this
+// key, with the same errors as BoxPrivate.SealOpen.
+func (k XChaChaBoxPrivate) SealOpen(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < XChaChaBoxSealOverhead {
+		return nil, ErrSealedBoxTooShort
+	}
+	out := make([]byte, len(ciphertext)-XChaChaBoxSealOverhead)
+	rv := C.crypto_box_curve25519xchacha20poly1305_seal_open(g2cbt(out), g2cbt(ciphertext),
+		C.ulonglong(len(ciphertext)), g2cbt(k.PublicKey()), g2cbt(k))
+	if rv != 0 {
+		return nil, ErrDecryptionFailed
+	}
+	return out, nil
+}
+
+// XChaChaBoxShared is a shared key precomputed from an XChaCha20 box key pair,
+// the counterpart of BoxShared. It only works with the XChaCha20 variant.
+type XChaChaBoxShared []byte
+
+// String returns a redacted placeholder so the key is not printed by accident.
+func (k XChaChaBoxShared) String() string {
+	return redacted("xboxshr", k)
+}
+
+// Format implements fmt.Formatter, printing String for every verb.
+func (k XChaChaBoxShared) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, k.String())
+}
+
+// XChaChaBoxPrecompute derives the shared key between our private key and
+// their public key with crypto_box_curve25519xchacha20poly1305_beforenm, like
+// Precompute. It panics if publ is a low-order point.
+func XChaChaBoxPrecompute(priv XChaChaBoxPrivate, publ XChaChaBoxPublic) XChaChaBoxShared {
+	priv.checkBox(make([]byte, XChaChaBoxNonceLength), publ)
+	toret := make([]byte, XChaChaBoxSharedLength)
+	rv := C.crypto_box_curve25519xchacha20poly1305_beforenm(g2cbt(toret), g2cbt(publ), g2cbt(priv))
+	if rv != 0 {
+		panic(ErrLowOrderPoint.Error())
+	}
+	return toret
+}
+
+// Seal encrypts and authenticates a message using the shared key. It is safe
+// to call concurrently from several goroutines.
+func (k XChaChaBoxShared) Seal(message, nonce []byte) []byte {
+	if len(k) != XChaChaBoxSharedLength {
+		panic("XChaCha20 box shared key has the wrong length")
+	}
+	if len(nonce) != XChaChaBoxNonceLength {
+		panic("XChaCha20 box nonce has the wrong length")
+	}
+	out := make([]byte, len(message)+XChaChaBoxMACLength)
+	rv := C.crypto_box_curve25519xchacha20poly1305_easy_afternm(g2cbt(out), g2cbt(message),
+		C.ulonglong(len(message)), g2cbt(nonce), g2cbt(k))
+	if rv != 0 {
+		panic("crypto_box_curve25519xchacha20poly1305_easy_afternm returned non-zero")
+	}
+	return out
+}
+
+// Open decrypts and verifies a ciphertext using the shared key. It is safe to
+// call concurrently from several goroutines. A shared key of the wrong length
+// gives an error wrapping ErrInvalidKeyLength.
+func (k XChaChaBoxShared) Open(ciphertext, nonce []byte) ([]byte, error) {
+	if len(k) != XChaChaBoxSharedLength {
+		return nil, keyLengthError("XChaCha20 box shared key", len(k), XChaChaBoxSharedLength)
+	}
+	if len(nonce) != XChaChaBoxNonceLength {
+		panic("XChaCha20 box nonce has the wrong length")
+	}
+	if len(ciphertext) < XChaChaBoxMACLength {
+		return nil, fmt.Errorf("%w: ciphertext too short", ErrDecryptionFailed)
+	}
+	out := make([]byte, len(ciphertext)-XChaChaBoxMACLength)
+	rv := C.crypto_box_curve25519xchacha20poly1305_open_easy_afternm(g2cbt(out), g2cbt(ciphertext),
+		C.ulonglong(len(ciphertext)), g2cbt(nonce), g2cbt(k))
+	if rv != 0 {
+		return nil, ErrDecryptionFailed
+	}
+	return out, nil
+}
+
+// Destroy wipes the shared key from memory. The key must not be used
+// afterwards.
+func (k XChaChaBoxShared) Destroy() {
+	wipe(k)
+}
+
+// Clone returns a copy of the shared key that Destroy on k will not wipe.
+func (k XChaChaBoxShared) Clone() XChaChaBoxShared {
+	return append(XChaChaBoxShared(nil), k...)
+}
+
+// MarshalJSON implements the MarshalJSON interface.
+func (k XChaChaBoxPublic) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]byte(k))
+}
+
+// UnmarshalJSON implements the UnmarshalJSON interface, reversing MarshalJSON.
+func (k *XChaChaBoxPublic) UnmarshalJSON(data []byte) error {
+	raw, err := unmarshalKeyJSON(data, XChaChaBoxPublicLength, "XChaCha20 box public key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// GobEncode implements the gob.GobEncoder interface.
+func (k XChaChaBoxPublic) GobEncode() ([]byte, error) {
+	return encodeKeyGob(gobXChaChaBoxPublic, k)
+}
+
+// GobDecode implements the gob.GobDecoder interface, rejecting anything that
+// is not an XChaCha20 box public key of the right length.
+func (k *XChaChaBoxPublic) GobDecode(data []byte) error {
+	raw, err := decodeKeyGob(data, gobXChaChaBoxPublic, XChaChaBoxPublicLength, "XChaCha20 box public key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, returning a
+// copy of the raw key.
+func (k XChaChaBoxPublic) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), k...), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. The
+// data is length-checked and copied, never aliased.
+func (k *XChaChaBoxPublic) UnmarshalBinary(data []byte) error {
+	raw, err := copyKey(data, XChaChaBoxPublicLength, "XChaCha20 box public key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, encoding the
+// key as lowercase hex.
+func (k XChaChaBoxPublic) MarshalText() ([]byte, error) {
+	return []byte(BinToHex(k)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, reversing
+// MarshalText.
+func (k *XChaChaBoxPublic) UnmarshalText(text []byte) error {
+	raw, err := unmarshalKeyText(text, XChaChaBoxPublicLength, "XChaCha20 box public key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalJSON implements the MarshalJSON interface. As with BoxPrivate, the
+// secret key is encoded in full.
+func (k XChaChaBoxPrivate) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]byte(k))
+}
+
+// UnmarshalJSON implements the UnmarshalJSON interface, reversing MarshalJSON.
+func (k *XChaChaBoxPrivate) UnmarshalJSON(data []byte) error {
+	raw, err := unmarshalKeyJSON(data, XChaChaBoxPrivateLength, "XChaCha20 box private key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// GobEncode implements the gob.GobEncoder interface.
+func (k XChaChaBoxPrivate) GobEncode() ([]byte, error) {
+	return encodeKeyGob(gobXChaChaBoxPrivate, k)
+}
+
+// GobDecode implements the gob.GobDecoder interface, rejecting anything that
+// is not an XChaCha20 box private key of the right length.
+func (k *XChaChaBoxPrivate) GobDecode(data []byte) error {
+	raw, err := decodeKeyGob(data, gobXChaChaBoxPrivate, XChaChaBoxPrivateLength, "XChaCha20 box private key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, returning a
+// copy of the raw key.
+func (k XChaChaBoxPrivate) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), k...), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. The
+// data is length-checked and copied, never aliased.
+func (k *XChaChaBoxPrivate) UnmarshalBinary(data []byte) error {
+	raw, err := copyKey(data, XChaChaBoxPrivateLength, "XChaCha20 box private key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, encoding the
+// key as lowercase hex.
+func (k XChaChaBoxPrivate) MarshalText() ([]byte, error) {
+	return []byte(BinToHex(k)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, reversing
+// MarshalText.
+func (k *XChaChaBoxPrivate) UnmarshalText(text []byte) error {
+	raw, err := unmarshalKeyText(text, XChaChaBoxPrivateLength, "XChaCha20 box private key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
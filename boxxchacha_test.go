@@ -0,0 +1,178 @@
+package natrium
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestXChaChaBoxVector(t *testing.T) {
+	// computed with libsodium's crypto_box_curve25519xchacha20poly1305_easy
+	alice, bob := make([]byte, 32), make([]byte, 32)
+	for i := range alice {
+		alice[i] = byte(i + 1)
+		bob[i] = byte(0x80 + i)
+	}
+	nonce := make([]byte, XChaChaBoxNonceLength)
+	for i := range nonce {
+		nonce[i] = byte(0xa0 + i)
+	}
+	message := []byte("Hello from the XChaCha20 box")
+	apriv, bpriv := XChaChaBoxPrivate(alice), XChaChaBoxPrivate(bob)
+	ct := apriv.Seal(message, nonce, bpriv.PublicKey())
+	if BinToHex(ct) != "3ec5b6a7b6c31824ce7bc23affa4173553b3ce35707fb7c9060d69b62d01e6164a41e0d0dcb82e5cd97d1094" {
+		t.FailNow()
+	}
+	plain, err := bpriv.Open(ct, nonce, apriv.PublicKey())
+	if err != nil || !bytes.Equal(plain, message) {
+		t.FailNow()
+	}
+	// the XSalsa20 box of the same inputs is different, and does not open
+	if bytes.Equal(BoxPrivate(alice).Seal(message, nonce, BoxPublic(bpriv.PublicKey())), ct) {
+		t.FailNow()
+	}
+	if _, err := BoxPrivate(bob).Open(ct, nonce, BoxPublic(apriv.PublicKey())); !errors.Is(err, ErrDecryptionFailed) {
+		t.FailNow()
+	}
+}
+
+func TestXChaChaBox(t *testing.T) {
+	alice, bob := XChaChaBoxGenerateKey(), XChaChaBoxGenerateKey()
+	message := []byte("Hello World")
+	ct := alice.SealAuto(message, bob.PublicKey())
+	if len(ct) != len(message)+XChaChaBoxAutoOverhead {
+		t.FailNow()
+	}
+	plain, err := bob.OpenAuto(ct, alice.PublicKey())
+	if err != nil || !bytes.Equal(plain, message) {
+		t.FailNow()
+	}
+	ct[len(ct)-1] ^= 1
+	if _, err := bob.OpenAuto(ct, alice.PublicKey()); !errors.Is(err, ErrDecryptionFailed) {
+		t.FailNow()
+	}
+	nonce := alice.NewNonce()
+	c, mac := alice.SealDetached(message, nonce, bob.PublicKey())
+	plain, err = bob.OpenDetached(c, mac, nonce, alice.PublicKey())
+	if err != nil || !bytes.Equal(plain, message) {
+		t.FailNow()
+	}
+	if _, err := alice.SealSafe(message, nonce, make(XChaChaBoxPublic, XChaChaBoxPublicLength)); !errors.Is(err, ErrLowOrderPoint) {
+		t.FailNow()
+	}
+	if _, err := bob.Open(append(mac, c...), nonce, make(XChaChaBoxPublic, XChaChaBoxPublicLength)); !errors.Is(err, ErrLowOrderPoint) {
+		t.FailNow()
+	}
+	if _, _, err := alice.SealDetachedSafe(message, nonce, make(XChaChaBoxPublic, XChaChaBoxPublicLength)); !errors.Is(err, ErrLowOrderPoint) {
+		t.FailNow()
+	}
+}
+
+func TestXChaChaBoxSeal(t *testing.T) {
+	priv := XChaChaBoxGenerateKey()
+	message := []byte("Hello World")
+	sealed := priv.PublicKey().Seal(message)
+	if len(sealed) != len(message)+XChaChaBoxSealOverhead {
+		t.FailNow()
+	}
+	plain, err := priv.SealOpen(sealed)
+	if err != nil || !bytes.Equal(plain, message) {
+		t.FailNow()
+	}
+	if _, err := BoxPrivate(priv).SealOpen(sealed); !errors.Is(err, ErrDecryptionFailed) {
+		t.FailNow()
+	}
+	if _, err := priv.SealOpen(sealed[:10]); !errors.Is(err, ErrSealedBoxTooShort) {
+		t.FailNow()
+	}
+}
+
+func TestXChaChaBoxKeyHex(t *testing.T) {
+	priv := XChaChaBoxGenerateKey()
+	parsed, err := XChaChaBoxPrivateFromHex(priv.HexString())
+	if err != nil || !parsed.Equal(priv) {
+		t.FailNow()
+	}
+	pub, err := XChaChaBoxPublicFromHex(priv.PublicKey().String())
+	if err != nil || !pub.Equal(priv.PublicKey()) || !pub.Valid() {
+		t.FailNow()
+	}
+}
+
+func TestXChaChaBoxEncode(t *testing.T) {
+	priv := XChaChaBoxGenerateKey()
+	publ := priv.PublicKey()
+	checkEncoding(t, []encodingCase{
+		{publ, XChaChaBoxPublicPEMType, publ.Encode, func(b []byte, e Encoding) ([]byte, error) { return DecodeXChaChaBoxPublic(b, e) }},
+		{priv, XChaChaBoxPrivatePEMType, priv.Encode, func(b []byte, e Encoding) ([]byte, error) { return DecodeXChaChaBoxPrivate(b, e) }},
+	})
+}
+
+func TestXChaChaBoxShared(t *testing.T) {
+	alice, bob := XChaChaBoxGenerateKey(), XChaChaBoxGenerateKey()
+	nonce := alice.NewNonce()
+	shared := XChaChaBoxPrecompute(alice, bob.PublicKey())
+	ciphertext := shared.Seal([]byte("Hello World"), nonce)
+	plaintext, err := bob.Open(ciphertext, nonce, alice.PublicKey())
+	if err != nil || string(plaintext) != "Hello World" {
+		t.FailNow()
+	}
+	plaintext, err = XChaChaBoxPrecompute(bob, alice.PublicKey()).Open(ciphertext, nonce)
+	if err != nil || string(plaintext) != "Hello World" {
+		t.FailNow()
+	}
+	// the XSalsa20 shared key of the same pair does not open it
+	if _, err := Precompute(BoxPrivate(bob), BoxPublic(alice.PublicKey())).Open(ciphertext, nonce); err == nil {
+		t.FailNow()
+	}
+	ciphertext[0] ^= 1
+	if _, err := shared.Open(ciphertext, nonce); !errors.Is(err, ErrDecryptionFailed) {
+		t.FailNow()
+	}
+	if fmt.Sprintf("%x", shared) != shared.String() {
+		t.FailNow()
+	}
+	clone := shared.Clone()
+	shared.Destroy()
+	if !isZero(shared) || isZero(clone) {
+		t.FailNow()
+	}
+	if _, err := XChaChaBoxShared(nil).Open(ciphertext, nonce); !errors.Is(err, ErrInvalidKeyLength) {
+		t.FailNow()
+	}
+}
+
+func TestXChaChaBoxMarshal(t *testing.T) {
+	priv := XChaChaBoxGenerateKey()
+	publ := priv.PublicKey()
+	bts, err := json.Marshal([]interface{}{priv, publ})
+	if err != nil {
+		t.FailNow()
+	}
+	var jpriv XChaChaBoxPrivate
+	var jpubl XChaChaBoxPublic
+	if json.Unmarshal(bts, &[]interface{}{&jpriv, &jpubl}) != nil || !jpriv.Equal(priv) || !jpubl.Equal(publ) {
+		t.FailNow()
+	}
+	data, _ := priv.MarshalBinary()
+	var bpriv XChaChaBoxPrivate
+	if bpriv.UnmarshalBinary(data) != nil || !bpriv.Equal(priv) || bpriv.UnmarshalBinary(data[:31]) == nil {
+		t.FailNow()
+	}
+	text, _ := publ.MarshalText()
+	var tpubl XChaChaBoxPublic
+	if tpubl.UnmarshalText(text) != nil || !tpubl.Equal(publ) || tpubl.UnmarshalText(text[2:]) == nil {
+		t.FailNow()
+	}
+	var gpubl XChaChaBoxPublic
+	checkGob(t, publ, publ, &gpubl, func() []byte { return gpubl })
+	var gpriv XChaChaBoxPrivate
+	checkGob(t, priv, priv, &gpriv, func() []byte { return gpriv })
+	// a box key's gob encoding is not accepted as an XChaCha20 box key
+	boxGob, _ := BoxPublic(publ).GobEncode()
+	if gpubl.GobDecode(boxGob) == nil {
+		t.FailNow()
+	}
+}
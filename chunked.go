@@ -0,0 +1,158 @@
+package natrium
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// AEADChunkSize is a reasonable chunk size for SealChunked.
+const AEADChunkSize = 64 * 1024
+
+// aeadMaxChunk bounds chunk sizes, so that OpenChunked never allocates an
+// unreasonable amount of memory because of a hostile header.
+const aeadMaxChunk = 1 << 24
+
+// aeadChunkedHeaderLength is the length of the base nonce and chunk size that
+// start every chunked ciphertext.
+const aeadChunkedHeaderLength = AEADNonceLength + 4
+
+// chunkNonce returns the nonce for chunk i: the base nonce with i, as an 8-byte
+// big-endian number, XORed into its last 8 bytes.
+func chunkNonce(base []byte, i uint64) []byte {
+	nonce := append([]byte(nil), base...)
+	tail := nonce[AEADNonceLength-8:]
+	binary.BigEndian.PutUint64(tail, binary.BigEndian.Uint64(tail)^i)
+	return nonce
+}
+
+// chunkAD is the associated data of a chunk, which marks whether it is the
+// last one.
+func chunkAD(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// SealChunked encrypts everything read from r onto w, in chunks of chunkSize
+// bytes of plaintext that are sealed independently with XChaCha20-Poly1305.
+// Unlike a secretstream, any chunk can be decrypted on its own given the key
+// and header, which suits formats that need random access. The output is:
+//
+//	24 bytes  random base nonce
+//	4 bytes   chunkSize, big-endian
+//	chunks    each chunkSize+AEADTagLength bytes, except the last, which
+//	          holds between 0 and chunkSize bytes of plaintext
+//
+// Chunk i, counting from zero, is sealed with the base nonce whose last 8
+// bytes are XORed with i as a big-endian number, and with a single byte of
+// associated data that is 1 for the last chunk and 0 for the others. Input of
+// exactly a multiple of chunkSize bytes ends with a full final chunk, and
+// empty input with a final chunk of no plaintext, so there is always one
+// marked chunk for OpenChunked to find.
+func (k AEADKey) SealChunked(r io.Reader, w io.Writer, chunkSize int) error {
+	if chunkSize <= 0 || chunkSize > aeadMaxChunk {
+		return fmt.Errorf("AEAD chunk size must be between 1 and %v", aeadMaxChunk)
+	}
+	base := k.NewNonce()
+	header := make([]byte, aeadChunkedHeaderLength)
+	copy(header, base)
+	binary.BigEndian.PutUint32(header[AEADNonceLength:], uint32(chunkSize))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	// one byte more than a chunk is read, so that the last chunk is known to
+	// be last before it is sealed
+	buf := make([]byte, chunkSize+1)
+	defer wipe(buf)
+	have := 0
+	for i := uint64(0); ; i++ {
+		n, err := io.ReadFull(r, buf[have:])
+		have += n
+		final := false
+		switch err {
+		case nil:
+		case io.EOF, io.ErrUnexpectedEOF:
+			final = true
+		default:
+			return err
+		}
+		chunk := buf[:have]
+		if !final {
+			chunk = buf[:chunkSize]
+		}
+		ct := k.Seal(chunk, chunkAD(final), chunkNonce(base, i))
+		if _, err := w.Write(ct); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+		buf[0] = buf[chunkSize]
+		have = 1
+	}
+}
+
+// OpenChunked decrypts a ciphertext produced by SealChunked from r onto w. It
+// returns nil only once the last chunk has been read and verified; tampering,
+// reordering or truncation gives an error wrapping ErrDecryptionFailed.
+//
+// Each chunk is verified in full before any of its plaintext reaches w, and
+// is then written with a single call to w.Write, so w only ever sees whole,
+// authentic chunks. OpenChunked stops at the first chunk that fails, without
+// reading further. The chunks before it were genuine, but the data as a whole
+// was not: whenever OpenChunked returns an error, everything it wrote to w is
+// invalid and must be discarded, never treated as a shorter but complete
+// plaintext. Callers that cannot undo writes should decrypt into a temporary
+// file or buffer and only use it once OpenChunked returns nil.
+func (k AEADKey) OpenChunked(r io.Reader, w io.Writer) error {
+	header := make([]byte, aeadChunkedHeaderLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("%w: chunked ciphertext has no header", ErrDecryptionFailed)
+		}
+		return err
+	}
+	base := header[:AEADNonceLength]
+	chunkSize := binary.BigEndian.Uint32(header[AEADNonceLength:])
+	if chunkSize == 0 || chunkSize > aeadMaxChunk {
+		return fmt.Errorf("%w: chunked ciphertext has an invalid chunk size", ErrDecryptionFailed)
+	}
+	frame := int(chunkSize) + AEADTagLength
+	buf := make([]byte, frame+1)
+	have := 0
+	for i := uint64(0); ; i++ {
+		n, err := io.ReadFull(r, buf[have:])
+		have += n
+		final := false
+		switch err {
+		case nil:
+		case io.EOF, io.ErrUnexpectedEOF:
+			final = true
+		default:
+			return err
+		}
+		chunk := buf[:have]
+		if !final {
+			chunk = buf[:frame]
+		}
+		if len(chunk) < AEADTagLength {
+			return fmt.Errorf("%w: chunked ciphertext truncated", ErrDecryptionFailed)
+		}
+		plain, err := k.Open(chunk, chunkAD(final), chunkNonce(base, i))
+		if err != nil {
+			return fmt.Errorf("%w: chunk %v of chunked ciphertext", err, i)
+		}
+		_, err = w.Write(plain)
+		wipe(plain)
+		if err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+		buf[0] = buf[frame]
+		have = 1
+	}
+}
@@ -0,0 +1,104 @@
+package natrium
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestAEADChunked(t *testing.T) {
+	key := GenerateAEADKey()
+	for _, size := range []int{0, 1, 99, 100, 101, 1000} {
+		message := RandomBytes(size)
+		var ct bytes.Buffer
+		if key.SealChunked(bytes.NewReader(message), &ct, 100) != nil {
+			t.FailNow()
+		}
+		chunks := (size + 99) / 100
+		if chunks == 0 {
+			chunks = 1
+		}
+		if ct.Len() != aeadChunkedHeaderLength+size+chunks*AEADTagLength {
+			t.FailNow()
+		}
+		var pt bytes.Buffer
+		if key.OpenChunked(bytes.NewReader(ct.Bytes()), &pt) != nil || !bytes.Equal(pt.Bytes(), message) {
+			t.FailNow()
+		}
+	}
+}
+
+func TestAEADChunkedTamper(t *testing.T) {
+	key := GenerateAEADKey()
+	var ct bytes.Buffer
+	if key.SealChunked(bytes.NewReader(RandomBytes(250)), &ct, 100) != nil {
+		t.FailNow()
+	}
+	good := ct.Bytes()
+	frame := 100 + AEADTagLength
+	// a chunk can be opened on its own
+	second := good[aeadChunkedHeaderLength+frame : aeadChunkedHeaderLength+2*frame]
+	if _, err := key.Open(second, []byte{0}, chunkNonce(good[:AEADNonceLength], 1)); err != nil {
+		t.FailNow()
+	}
+	bad := [][]byte{
+		nil,
+		good[:aeadChunkedHeaderLength],
+		good[:aeadChunkedHeaderLength+frame],
+		good[:aeadChunkedHeaderLength+2*frame],
+		good[:len(good)-1],
+		append(append([]byte(nil), good...), 0),
+		// chunks swapped
+		append(append(append([]byte(nil), good[:aeadChunkedHeaderLength]...), second...),
+			good[aeadChunkedHeaderLength:aeadChunkedHeaderLength+frame]...),
+	}
+	for _, i := range []int{0, AEADNonceLength, aeadChunkedHeaderLength + 5, len(good) - 1} {
+		tampered := append([]byte(nil), good...)
+		tampered[i] ^= 1
+		bad = append(bad, tampered)
+	}
+	for _, ct := range bad {
+		var pt bytes.Buffer
+		if err := key.OpenChunked(bytes.NewReader(ct), &pt); !errors.Is(err, ErrDecryptionFailed) {
+			t.FailNow()
+		}
+	}
+	if key.SealChunked(bytes.NewReader(nil), &ct, 0) == nil {
+		t.FailNow()
+	}
+}
+
+// chunkRecorder keeps every slice passed to Write separately.
+type chunkRecorder struct {
+	writes [][]byte
+}
+
+func (c *chunkRecorder) Write(p []byte) (int, error) {
+	c.writes = append(c.writes, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func TestAEADChunkedNoPlaintextAfterTamper(t *testing.T) {
+	key := GenerateAEADKey()
+	message := RandomBytes(450)
+	var ct bytes.Buffer
+	if key.SealChunked(bytes.NewReader(message), &ct, 100) != nil {
+		t.FailNow()
+	}
+	frame := 100 + AEADTagLength
+	tampered := ct.Bytes()
+	// flip a bit in the middle of the third chunk's ciphertext
+	tampered[aeadChunkedHeaderLength+2*frame+50] ^= 1
+	var out chunkRecorder
+	if err := key.OpenChunked(bytes.NewReader(tampered), &out); !errors.Is(err, ErrDecryptionFailed) {
+		t.FailNow()
+	}
+	if len(out.writes) != 2 {
+		t.FailNow()
+	}
+	for i, w := range out.writes {
+		if !bytes.Equal(w, message[i*100:(i+1)*100]) {
+			t.FailNow()
+		}
+	}
+}
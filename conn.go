@@ -0,0 +1,131 @@
+package natrium
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+)
+
+// ssConn is a net.Conn whose traffic goes through two secretstreams, one in
+// each direction. Reads and writes have separate locks, so one goroutine can
+// read while another writes, as net.Conn allows.
+type ssConn struct {
+	net.Conn
+	rmu sync.Mutex
+	r   *ssReader
+	wmu sync.Mutex
+	w   *ssWriter
+}
+
+// WrapConn returns a net.Conn that encrypts everything written to it and
+// decrypts everything read from it with secretstream under key, which both
+// ends must already share, for example from a key exchange. One end must pass
+// isInitiator true and the other false.
+//
+// Each direction has its own stream, under a subkey derived from key with
+// Expand, so that traffic cannot be reflected back to its sender. WrapConn
+// runs a short handshake before returning: the initiator sends the header of
+// its stream and then reads the responder's, and the responder does the
+// reverse. After that, every Write is sent at once as one or more frames in
+// the format of SecretStreamKey.NewWriter, and Read returns plaintext as each
+// frame verifies, however the frames are split across reads.
+//
+// Close sends a final frame before closing conn, so that the peer sees io.EOF
+// rather than ErrSecretStreamTruncated. Deadlines and addresses are those of
+// conn. A read that times out keeps any partial frame, and once the deadline
+// is moved, Read carries on where it stopped. A write that fails, timeouts
+// included, may have sent part of a frame, and leaves the stream in that
+// direction unusable.
+func WrapConn(conn net.Conn, key SecretStreamKey, isInitiator bool) (net.Conn, error) {
+	if len(key) != SecretStreamKeyLength {
+		return nil, keyLengthError("secretstream key", len(key), SecretStreamKeyLength)
+	}
+	initKey, err := Expand(key, []byte("natrium conn initiator"), SecretStreamKeyLength)
+	if err != nil {
+		return nil, err
+	}
+	respKey, err := Expand(key, []byte("natrium conn responder"), SecretStreamKeyLength)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(initKey)
+	defer wipe(respKey)
+	sendKey, recvKey := SecretStreamKey(initKey), SecretStreamKey(respKey)
+	if !isInitiator {
+		sendKey, recvKey = recvKey, sendKey
+	}
+	enc, header := sendKey.NewEncryptor()
+	peer := make([]byte, SecretStreamHeaderLength)
+	if isInitiator {
+		if _, err := conn.Write(header); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := io.ReadFull(conn, peer); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, errors.New("connection closed during secretstream handshake")
+		}
+		return nil, err
+	}
+	if !isInitiator {
+		if _, err := conn.Write(header); err != nil {
+			return nil, err
+		}
+	}
+	dec, err := recvKey.NewDecryptorChecked(peer)
+	if err != nil {
+		return nil, err
+	}
+	return &ssConn{
+		Conn: conn,
+		r:    &ssReader{dec: dec, r: conn},
+		w: &ssWriter{
+			enc:  enc,
+			w:    conn,
+			buf:  make([]byte, 0, SecretStreamChunkSize),
+			size: SecretStreamChunkSize,
+		},
+	}, nil
+}
+
+func (c *ssConn) Read(p []byte) (int, error) {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+	return c.r.Read(p)
+}
+
+func (c *ssConn) Write(p []byte) (int, error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.w.closed {
+		return 0, errWriterClosed
+	}
+	if c.w.err != nil {
+		return 0, c.w.err
+	}
+	n := 0
+	for len(p) > 0 {
+		take := c.w.size
+		if take > len(p) {
+			take = len(p)
+		}
+		c.w.buf = append(c.w.buf[:0], p[:take]...)
+		if c.w.err = c.w.frame(SecretStreamTagMessage); c.w.err != nil {
+			return n, c.w.err
+		}
+		p = p[take:]
+		n += take
+	}
+	return n, nil
+}
+
+func (c *ssConn) Close() error {
+	c.wmu.Lock()
+	err := c.w.Close()
+	c.wmu.Unlock()
+	if cerr := c.Conn.Close(); err == nil || err == errWriterClosed {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,107 @@
+package natrium
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestWrapConn(t *testing.T) {
+	key := GenerateSecretStreamKey()
+	a, b := net.Pipe()
+	done := make(chan net.Conn)
+	go func() {
+		conn, err := WrapConn(b, key, false)
+		if err != nil {
+			conn = nil
+		}
+		done <- conn
+	}()
+	client, err := WrapConn(a, key, true)
+	server := <-done
+	if err != nil || server == nil {
+		t.FailNow()
+	}
+	// checked before the client can close its end, which makes net.Pipe
+	// refuse deadlines
+	if server.SetReadDeadline(time.Time{}) != nil || server.LocalAddr() == nil {
+		t.FailNow()
+	}
+	message := RandomBytes(3*SecretStreamChunkSize + 5)
+	go func() {
+		client.Write(message[:10])
+		client.Write(message[10:])
+		client.Close()
+	}()
+	// read in small pieces that straddle frame boundaries
+	var got bytes.Buffer
+	buf := make([]byte, 1000)
+	for {
+		n, err := server.Read(buf)
+		got.Write(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.FailNow()
+		}
+	}
+	if !bytes.Equal(got.Bytes(), message) {
+		t.FailNow()
+	}
+	server.Close()
+}
+
+func TestWrapConnWrongKey(t *testing.T) {
+	a, b := net.Pipe()
+	go func() {
+		conn, err := WrapConn(b, GenerateSecretStreamKey(), false)
+		if err == nil {
+			conn.Write([]byte("Hello World"))
+		}
+		b.Close()
+	}()
+	client, err := WrapConn(a, GenerateSecretStreamKey(), true)
+	if err != nil {
+		t.FailNow()
+	}
+	if _, err := client.Read(make([]byte, 100)); err == nil {
+		t.FailNow()
+	}
+	client.Close()
+}
+
+func TestWrapConnReadTimeout(t *testing.T) {
+	key := GenerateSecretStreamKey()
+	a, b := net.Pipe()
+	done := make(chan net.Conn)
+	go func() {
+		conn, err := WrapConn(b, key, false)
+		if err != nil {
+			conn = nil
+		}
+		done <- conn
+	}()
+	client, err := WrapConn(a, key, true)
+	server := <-done
+	if err != nil || server == nil {
+		t.FailNow()
+	}
+	server.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	_, err = server.Read(make([]byte, 10))
+	if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		t.FailNow()
+	}
+	server.SetReadDeadline(time.Time{})
+	go func() {
+		client.Write([]byte("Hello World"))
+		client.Close()
+	}()
+	got, err := io.ReadAll(server)
+	if err != nil || string(got) != "Hello World" {
+		t.FailNow()
+	}
+	server.Close()
+}
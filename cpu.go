@@ -0,0 +1,66 @@
+package natrium
+
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #include <stdio.h>
+// #include <sodium.h>
+import "C"
+
+// The functions below report CPU features detected by libsodium when it was
+// initialized. A probe that does not apply to the current architecture simply
+// reports false.
+
+// HasNEON reports whether the CPU supports ARM NEON.
+func HasNEON() bool {
+	return C.sodium_runtime_has_neon() == 1
+}
+
+// HasSSE2 reports whether the CPU supports SSE2.
+func HasSSE2() bool {
+	return C.sodium_runtime_has_sse2() == 1
+}
+
+// HasSSE3 reports whether the CPU supports SSE3.
+func HasSSE3() bool {
+	return C.sodium_runtime_has_sse3() == 1
+}
+
+// HasSSSE3 reports whether the CPU supports SSSE3.
+func HasSSSE3() bool {
+	return C.sodium_runtime_has_ssse3() == 1
+}
+
+// HasSSE41 reports whether the CPU supports SSE4.1.
+func HasSSE41() bool {
+	return C.sodium_runtime_has_sse41() == 1
+}
+
+// HasAVX reports whether the CPU supports AVX.
+func HasAVX() bool {
+	return C.sodium_runtime_has_avx() == 1
+}
+
+// HasAVX2 reports whether the CPU supports AVX2.
+func HasAVX2() bool {
+	return C.sodium_runtime_has_avx2() == 1
+}
+
+// HasAVX512F reports whether the CPU supports AVX-512F.
+func HasAVX512F() bool {
+	return C.sodium_runtime_has_avx512f() == 1
+}
+
+// HasPCLMUL reports whether the CPU supports carry-less multiplication.
+func HasPCLMUL() bool {
+	return C.sodium_runtime_has_pclmul() == 1
+}
+
+// HasAESNI reports whether the CPU has the AES-NI instructions. On x86, this
+// and HasPCLMUL are what AES256GCMAvailable depends on.
+func HasAESNI() bool {
+	return C.sodium_runtime_has_aesni() == 1
+}
+
+// HasRDRAND reports whether the CPU has the RDRAND instruction.
+func HasRDRAND() bool {
+	return C.sodium_runtime_has_rdrand() == 1
+}
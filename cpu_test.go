@@ -0,0 +1,11 @@
+package natrium
+
+import "testing"
+
+func TestCPUFeatures(t *testing.T) {
+	probes := []func() bool{HasNEON, HasSSE2, HasSSE3, HasSSSE3, HasSSE41,
+		HasAVX, HasAVX2, HasAVX512F, HasPCLMUL, HasAESNI, HasRDRAND}
+	for _, probe := range probes {
+		probe()
+	}
+}
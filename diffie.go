@@ -1,10 +1,11 @@
 package natrium
 
-import "crypto/rand"
+import (
+	"errors"
+	"fmt"
+)
 
 // #cgo darwin CFLAGS: -I/usr/local/include
-// #cgo darwin LDFLAGS: /usr/local/lib/libsodium.a
-// #cgo linux windows android LDFLAGS: -Wl,-Bstatic -lsodium -Wl,-Bdynamic
 // #include <stdio.h>
 // #include <sodium.h>
 import "C"
@@ -12,16 +13,28 @@ import "C"
 // ECDHPublic represents a X25519 public key.
 type ECDHPublic []byte
 
-// ECDHPrivate represents a X25519 private key.
+// ECDHPrivate represents a X25519 private key. Being a slice, it is shared
+// rather than copied on assignment; Clone makes a real copy.
 type ECDHPrivate []byte
 
+// String returns a redacted placeholder so the key is not printed by accident.
+func (k ECDHPrivate) String() string {
+	return redacted("ecdhprv", k)
+}
+
+// Format implements fmt.Formatter, printing String for every verb.
+func (k ECDHPrivate) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, k.String())
+}
+
 // ECDHKeyLength represents the length of an ECDH public or private key.
-var ECDHKeyLength = C.crypto_scalarmult_BYTES
+const ECDHKeyLength int = C.crypto_scalarmult_BYTES
 
 // ECDHGenerateKey generates an ECDH private key.
 func ECDHGenerateKey() ECDHPrivate {
+	mustInit()
 	toret := make([]byte, ECDHKeyLength)
-	rand.Read(toret)
+	RandBytes(toret)
 	return toret
 }
 
@@ -53,3 +66,245 @@ func TripleECDH(ourAuth ECDHPrivate, theirAuth ECDHPublic, ourEph ECDHPrivate, t
 	}
 	return SecureHash(append(append(gAE, gEA...), gEE...), nil)
 }
+
+// ScalarMultBytes is the length of a Curve25519 point, as returned by
+// ScalarMult and ScalarMultBase.
+const ScalarMultBytes int = C.crypto_scalarmult_BYTES
+
+// ScalarMultScalarBytes is the length of a Curve25519 scalar (private key).
+const ScalarMultScalarBytes int = C.crypto_scalarmult_SCALARBYTES
+
+// ScalarMult computes the raw X25519 Diffie-Hellman function of our private
+// scalar and their public point. An error is returned if the result is all
+// zeros, which means pub is a low-order point chosen to force a predictable
+// secret.
+//
+// The output is not uniformly random and must be passed through a KDF, such
+// as GenericHash, before being used as a key.
+func ScalarMult(priv, pub []byte) ([]byte, error) {
+	if len(priv) != ScalarMultScalarBytes || len(pub) != ScalarMultBytes {
+		return nil, fmt.Errorf("%w: scalar or point is not %v bytes", ErrInvalidKeyLength, ECDHKeyLength)
+	}
+	toret := make([]byte, ScalarMultBytes)
+	rv := C.crypto_scalarmult(g2cbt(toret), g2cbt(priv), g2cbt(pub))
+	if rv != 0 {
+		return nil, ErrLowOrderPoint
+	}
+	return toret, nil
+}
+
+// ScalarMultBase computes the X25519 public point for a private scalar.
+func ScalarMultBase(priv []byte) []byte {
+	if len(priv) != ScalarMultScalarBytes {
+		panic("scalar has the wrong length")
+	}
+	toret := make([]byte, ScalarMultBytes)
+	rv := C.crypto_scalarmult_base(g2cbt(toret), g2cbt(priv))
+	if rv != 0 {
+		panic("crypto_scalarmult_base returned non-zero")
+	}
+	return toret
+}
+
+// Clone returns a copy of the public key that shares no memory with k.
+func (k ECDHPublic) Clone() ECDHPublic {
+	return append(ECDHPublic(nil), k...)
+}
+
+// Clone returns an independent copy of the private key.
+func (k ECDHPrivate) Clone() ECDHPrivate {
+	return append(ECDHPrivate(nil), k...)
+}
+
+// ECDHPublicFromHex parses an ECDH public key written in hex.
+func ECDHPublicFromHex(s string) (ECDHPublic, error) {
+	return keyFromHex(s, "", ECDHKeyLength, "ECDH public key")
+}
+
+// MustECDHPublicFromHex is like ECDHPublicFromHex, but panics on error.
+func MustECDHPublicFromHex(s string) ECDHPublic {
+	return mustKey(ECDHPublicFromHex(s))
+}
+
+// ECDHPrivateFromHex parses an ECDH private key written in hex.
+func ECDHPrivateFromHex(s string) (ECDHPrivate, error) {
+	return keyFromHex(s, "", ECDHKeyLength, "ECDH private key")
+}
+
+// MustECDHPrivateFromHex is like ECDHPrivateFromHex, but panics on error.
+func MustECDHPrivateFromHex(s string) ECDHPrivate {
+	return mustKey(ECDHPrivateFromHex(s))
+}
+
+// GobEncode implements the gob.GobEncoder interface.
+func (k ECDHPublic) GobEncode() ([]byte, error) {
+	return encodeKeyGob(gobECDHPublic, k)
+}
+
+// GobDecode implements the gob.GobDecoder interface, rejecting anything that
+// is not an ECDH public key of the right length.
+func (k *ECDHPublic) GobDecode(data []byte) error {
+	raw, err := decodeKeyGob(data, gobECDHPublic, ECDHKeyLength, "ECDH public key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// GobEncode implements the gob.GobEncoder interface.
+func (k ECDHPrivate) GobEncode() ([]byte, error) {
+	return encodeKeyGob(gobECDHPrivate, k)
+}
+
+// GobDecode implements the gob.GobDecoder interface, rejecting anything that
+// is not an ECDH private key of the right length.
+func (k *ECDHPrivate) GobDecode(data []byte) error {
+	raw, err := decodeKeyGob(data, gobECDHPrivate, ECDHKeyLength, "ECDH private key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, returning a
+// copy of the raw key.
+func (k ECDHPublic) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), k...), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. The
+// data is length-checked and copied, never aliased.
+func (k *ECDHPublic) UnmarshalBinary(data []byte) error {
+	raw, err := copyKey(data, ECDHKeyLength, "ECDH public key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, returning a
+// copy of the raw key.
+func (k ECDHPrivate) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), k...), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. The
+// data is length-checked and copied, never aliased.
+func (k *ECDHPrivate) UnmarshalBinary(data []byte) error {
+	raw, err := copyKey(data, ECDHKeyLength, "ECDH private key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, encoding the
+// key as lowercase hex.
+func (k ECDHPublic) MarshalText() ([]byte, error) {
+	return []byte(BinToHex(k)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, reversing
+// MarshalText.
+func (k *ECDHPublic) UnmarshalText(text []byte) error {
+	raw, err := unmarshalKeyText(text, ECDHKeyLength, "ECDH public key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, encoding the
+// key as lowercase hex.
+func (k ECDHPrivate) MarshalText() ([]byte, error) {
+	return []byte(BinToHex(k)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, reversing
+// MarshalText.
+func (k *ECDHPrivate) UnmarshalText(text []byte) error {
+	raw, err := unmarshalKeyText(text, ECDHKeyLength, "ECDH private key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// PEM block types used by Encode with EncodingPEM.
+const (
+	ECDHPublicPEMType  = "NATRIUM ECDH PUBLIC KEY"
+	ECDHPrivatePEMType = "NATRIUM ECDH PRIVATE KEY"
+)
+
+// Encode serializes the public key in the given encoding, as raw bytes, hex,
+// PEM or base64. PEM blocks have type ECDHPublicPEMType.
+func (k ECDHPublic) Encode(enc Encoding) ([]byte, error) {
+	return encodeKey(k, enc, ECDHPublicPEMType, ECDHKeyLength, "ECDH public key")
+}
+
+// DecodeECDHPublic parses a public key written by Encode with the same
+// encoding. Data of the wrong length, or a PEM block of another type, gives an
+// error.
+func DecodeECDHPublic(data []byte, enc Encoding) (ECDHPublic, error) {
+	return decodeKey(data, enc, ECDHPublicPEMType, ECDHKeyLength, "ECDH public key")
+}
+
+// Encode serializes the private key in the given encoding, like
+// ECDHPublic.Encode. Every encoding holds the secret in the clear.
+func (k ECDHPrivate) Encode(enc Encoding) ([]byte, error) {
+	return encodeKey(k, enc, ECDHPrivatePEMType, ECDHKeyLength, "ECDH private key")
+}
+
+// DecodeECDHPrivate parses a private key written by Encode with the same
+// encoding.
+func DecodeECDHPrivate(data []byte, enc Encoding) (ECDHPrivate, error) {
+	return decodeKey(data, enc, ECDHPrivatePEMType, ECDHKeyLength, "ECDH private key")
+}
+
+// x25519Vectors are the known-answer tests of RFC 7748, sections 5.2 and 6.1:
+// scalar, input point, expected output.
+var x25519Vectors = [][3]string{
+	{"a546e36bf0527c9d3b16154b82465edd62144c0ac1fc5a18506a2244ba449ac4",
+		"e6db6867583030db3594c1a424b15f7c726624ec26b3353b10a903a6d0ab1c4c",
+		"c3da55379de9c6908e94ea4df28d084f32eccf03491c71f754b4075577a28552"},
+	{"4b66e9d4d1b4673c5ad22691957d6af5c11b6421e0ea01d42ca4169e7918ba0d",
+		"e5210f12786811d3f4b7959d0538ae2c31dbe7106fc03c3efc4cd549c715a493",
+		"95cbde9476e8907d7aade45cb4b873f88b595a68799fa152e6f8f7647aac7957"},
+	// the Diffie-Hellman example, from both sides
+	{"77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a",
+		"de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f",
+		"4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742"},
+	{"5dab087e624a8a4b79e17f8b83800ee66f3bb1292618b6fd1c2f8b27ff88e0eb",
+		"8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a",
+		"4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742"},
+}
+
+// SelfTestX25519 checks the linked libsodium against the X25519 test vectors
+// of RFC 7748, returning an error describing the first mismatch. It takes
+// well under a millisecond, so it can be run as part of a startup health
+// check to catch a broken or mismatched build of the library.
+func SelfTestX25519() error {
+	for i, v := range x25519Vectors {
+		scalar, _ := HexToBin(v[0])
+		point, _ := HexToBin(v[1])
+		out, err := ScalarMult(scalar, point)
+		if err != nil {
+			return fmt.Errorf("X25519 self-test vector %v: %w", i, err)
+		}
+		if BinToHex(out) != v[2] {
+			return fmt.Errorf("X25519 self-test vector %v: got %v, want %v", i, BinToHex(out), v[2])
+		}
+	}
+	// the base point multiplication of section 6.1
+	alice, _ := HexToBin(x25519Vectors[2][0])
+	if BinToHex(ScalarMultBase(alice)) != x25519Vectors[3][1] {
+		return errors.New("X25519 self-test: wrong public key for the RFC 7748 example")
+	}
+	return nil
+}
@@ -17,3 +17,43 @@ func TestTripleECDH(t *testing.T) {
 		}
 	}
 }
+
+func TestScalarMult(t *testing.T) {
+	a := ECDHGenerateKey()
+	b := ECDHGenerateKey()
+	ab, err := ScalarMult(a, ScalarMultBase(b))
+	if err != nil {
+		t.FailNow()
+	}
+	ba, err := ScalarMult(b, ScalarMultBase(a))
+	if err != nil || CTCompare(ab, ba) != 0 || CTCompare(ab, ECDHSecret(a, b.PublicKey())) != 0 {
+		t.FailNow()
+	}
+	if _, err := ScalarMult(a, make([]byte, ScalarMultBytes)); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestSelfTestX25519(t *testing.T) {
+	if SelfTestX25519() != nil {
+		t.FailNow()
+	}
+}
+
+func TestECDHEncode(t *testing.T) {
+	priv := ECDHGenerateKey()
+	publ := priv.PublicKey()
+	checkEncoding(t, []encodingCase{
+		{publ, ECDHPublicPEMType, publ.Encode, func(b []byte, e Encoding) ([]byte, error) { return DecodeECDHPublic(b, e) }},
+		{priv, ECDHPrivatePEMType, priv.Encode, func(b []byte, e Encoding) ([]byte, error) { return DecodeECDHPrivate(b, e) }},
+	})
+}
+
+func TestECDHGob(t *testing.T) {
+	priv := ECDHGenerateKey()
+	publ := priv.PublicKey()
+	var gotPubl ECDHPublic
+	checkGob(t, publ, publ, &gotPubl, func() []byte { return gotPubl })
+	var gotPriv ECDHPrivate
+	checkGob(t, priv, priv, &gotPriv, func() []byte { return gotPriv })
+}
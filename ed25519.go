@@ -0,0 +1,136 @@
+package natrium
+
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #include <stdio.h>
+// #include <sodium.h>
+import "C"
+
+// Low-level arithmetic on the Ed25519 group, for building threshold schemes,
+// aggregate signatures and other protocols on top of the curve. None of it is
+// needed for ordinary signing; misusing it is easy, and the results are
+// points and scalars, not keys.
+
+// Ed25519PointLength is the length of an encoded Ed25519 point.
+const Ed25519PointLength int = C.crypto_core_ed25519_BYTES
+
+// Ed25519ScalarLength is the length of a scalar reduced modulo the group
+// order L.
+const Ed25519ScalarLength int = C.crypto_core_ed25519_SCALARBYTES
+
+// Ed25519NonReducedScalarLength is the length of the input to
+// Ed25519ScalarReduce, such as a SHA-512 digest.
+const Ed25519NonReducedScalarLength int = C.crypto_core_ed25519_NONREDUCEDSCALARBYTES
+
+func checkEd25519(b []byte, length int, what string) error {
+	if len(b) != length {
+		return keyLengthError(what, len(b), length)
+	}
+	return nil
+}
+
+// Ed25519IsValidPoint reports whether p is the canonical encoding of a point
+// in the prime-order subgroup, other than one of small order.
+func Ed25519IsValidPoint(p []byte) bool {
+	return len(p) == Ed25519PointLength && C.crypto_core_ed25519_is_valid_point(g2cbt(p)) == 1
+}
+
+// Ed25519PointAdd returns the sum of the points a and b, both of which must
+// pass Ed25519IsValidPoint.
+func Ed25519PointAdd(a, b []byte) ([]byte, error) {
+	if !Ed25519IsValidPoint(a) || !Ed25519IsValidPoint(b) {
+		return nil, ErrInvalidPoint
+	}
+	toret := make([]byte, Ed25519PointLength)
+	if C.crypto_core_ed25519_add(g2cbt(toret), g2cbt(a), g2cbt(b)) != 0 {
+		return nil, ErrInvalidPoint
+	}
+	return toret, nil
+}
+
+// Ed25519PointSub returns the difference a - b of two valid points.
+func Ed25519PointSub(a, b []byte) ([]byte, error) {
+	if !Ed25519IsValidPoint(a) || !Ed25519IsValidPoint(b) {
+		return nil, ErrInvalidPoint
+	}
+	toret := make([]byte, Ed25519PointLength)
+	if C.crypto_core_ed25519_sub(g2cbt(toret), g2cbt(a), g2cbt(b)) != 0 {
+		return nil, ErrInvalidPoint
+	}
+	return toret, nil
+}
+
+// Ed25519ScalarMult multiplies a valid point by a scalar. The scalar is used
+// as it is, without the clamping applied to Ed25519 private keys, so that the
+// group law holds for sums of scalars. ErrInvalidPoint is returned if the
+// point is invalid or the product is the identity, as it is for a zero scalar.
+func Ed25519ScalarMult(scalar, point []byte) ([]byte, error) {
+	if err := checkEd25519(scalar, Ed25519ScalarLength, "Ed25519 scalar"); err != nil {
+		return nil, err
+	}
+	if !Ed25519IsValidPoint(point) {
+		return nil, ErrInvalidPoint
+	}
+	toret := make([]byte, Ed25519PointLength)
+	if C.crypto_scalarmult_ed25519_noclamp(g2cbt(toret), g2cbt(scalar), g2cbt(point)) != 0 {
+		return nil, ErrInvalidPoint
+	}
+	return toret, nil
+}
+
+// Ed25519ScalarMultBase multiplies the standard base point by an unclamped
+// scalar, like Ed25519ScalarMult.
+func Ed25519ScalarMultBase(scalar []byte) ([]byte, error) {
+	if err := checkEd25519(scalar, Ed25519ScalarLength, "Ed25519 scalar"); err != nil {
+		return nil, err
+	}
+	toret := make([]byte, Ed25519PointLength)
+	if C.crypto_scalarmult_ed25519_base_noclamp(g2cbt(toret), g2cbt(scalar)) != 0 {
+		return nil, ErrInvalidPoint
+	}
+	return toret, nil
+}
+
+// Ed25519ScalarRandom returns a random non-zero scalar modulo L.
+func Ed25519ScalarRandom() []byte {
+	mustInit()
+	toret := make([]byte, Ed25519ScalarLength)
+	C.crypto_core_ed25519_scalar_random(g2cbt(toret))
+	return toret
+}
+
+// Ed25519ScalarAdd returns a + b modulo L.
+func Ed25519ScalarAdd(a, b []byte) ([]byte, error) {
+	if err := checkEd25519(a, Ed25519ScalarLength, "Ed25519 scalar"); err != nil {
+		return nil, err
+	}
+	if err := checkEd25519(b, Ed25519ScalarLength, "Ed25519 scalar"); err != nil {
+		return nil, err
+	}
+	toret := make([]byte, Ed25519ScalarLength)
+	C.crypto_core_ed25519_scalar_add(g2cbt(toret), g2cbt(a), g2cbt(b))
+	return toret, nil
+}
+
+// Ed25519ScalarMul returns a * b modulo L.
+func Ed25519ScalarMul(a, b []byte) ([]byte, error) {
+	if err := checkEd25519(a, Ed25519ScalarLength, "Ed25519 scalar"); err != nil {
+		return nil, err
+	}
+	if err := checkEd25519(b, Ed25519ScalarLength, "Ed25519 scalar"); err != nil {
+		return nil, err
+	}
+	toret := make([]byte, Ed25519ScalarLength)
+	C.crypto_core_ed25519_scalar_mul(g2cbt(toret), g2cbt(a), g2cbt(b))
+	return toret, nil
+}
+
+// Ed25519ScalarReduce reduces a 64-byte little-endian number, typically a
+// hash output, modulo L, giving a nearly uniform scalar.
+func Ed25519ScalarReduce(s []byte) ([]byte, error) {
+	if err := checkEd25519(s, Ed25519NonReducedScalarLength, "unreduced Ed25519 scalar"); err != nil {
+		return nil, err
+	}
+	toret := make([]byte, Ed25519ScalarLength)
+	C.crypto_core_ed25519_scalar_reduce(g2cbt(toret), g2cbt(s))
+	return toret, nil
+}
@@ -0,0 +1,60 @@
+package natrium
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEd25519Group(t *testing.T) {
+	a := Ed25519ScalarRandom()
+	b := Ed25519ScalarRandom()
+	sum, err := Ed25519ScalarAdd(a, b)
+	if err != nil {
+		t.FailNow()
+	}
+	aG, _ := Ed25519ScalarMultBase(a)
+	bG, _ := Ed25519ScalarMultBase(b)
+	sumG, _ := Ed25519ScalarMultBase(sum)
+	added, err := Ed25519PointAdd(aG, bG)
+	if err != nil || !bytes.Equal(added, sumG) {
+		t.FailNow()
+	}
+	back, err := Ed25519PointSub(sumG, bG)
+	if err != nil || !bytes.Equal(back, aG) {
+		t.FailNow()
+	}
+	// a*(b*G) == (a*b)*G
+	abG, err := Ed25519ScalarMult(a, bG)
+	prod, _ := Ed25519ScalarMul(a, b)
+	prodG, _ := Ed25519ScalarMultBase(prod)
+	if err != nil || !bytes.Equal(abG, prodG) {
+		t.FailNow()
+	}
+	h := SHA512(RandomBytes(32))
+	r, err := Ed25519ScalarReduce(h)
+	if err != nil || len(r) != Ed25519ScalarLength {
+		t.FailNow()
+	}
+	// an already reduced scalar is left alone
+	again, _ := Ed25519ScalarReduce(append(r, make([]byte, 32)...))
+	if !bytes.Equal(again, r) {
+		t.FailNow()
+	}
+	if _, err := Ed25519ScalarReduce(h[:32]); !errors.Is(err, ErrInvalidKeyLength) {
+		t.FailNow()
+	}
+	if _, err := Ed25519ScalarMult(make([]byte, 32), aG); err != ErrInvalidPoint {
+		t.FailNow()
+	}
+	identity := append([]byte{1}, make([]byte, 31)...)
+	if Ed25519IsValidPoint(identity) || !Ed25519IsValidPoint(aG) {
+		t.FailNow()
+	}
+	if _, err := Ed25519PointAdd(aG, identity); err != ErrInvalidPoint {
+		t.FailNow()
+	}
+	if _, err := Ed25519ScalarAdd(a, b[1:]); !errors.Is(err, ErrInvalidKeyLength) {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,35 @@
+package natrium
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors that callers may want to tell apart. Functions return them either
+// directly or wrapped with more detail, so they should be matched with
+// errors.Is rather than ==.
+var (
+	// ErrSignatureInvalid means a signature did not verify, or was not even
+	// the right length to be a signature.
+	ErrSignatureInvalid = errors.New("signature is invalid")
+	// ErrInvalidKeyLength means a key was not the length the primitive needs.
+	ErrInvalidKeyLength = errors.New("key has the wrong length")
+	// ErrDecryptionFailed means a ciphertext was forged, corrupted, truncated,
+	// or encrypted under another key or nonce.
+	ErrDecryptionFailed = errors.New("decryption failed")
+	// ErrInvalidNonceLength means a nonce was not the length the primitive
+	// needs.
+	ErrInvalidNonceLength = errors.New("nonce has the wrong length")
+	// ErrLowOrderPoint means a Curve25519 public key was one of the few
+	// points that force an all-zero shared secret, whatever the private key.
+	ErrLowOrderPoint = errors.New("public key is a low-order point")
+	// ErrInvalidPoint means bytes given as an Ed25519 or Ristretto255 group
+	// element were not the canonical encoding of a point in the prime-order
+	// group, or an operation produced the identity.
+	ErrInvalidPoint = errors.New("invalid group element")
+)
+
+// keyLengthError wraps ErrInvalidKeyLength with the key's name and lengths.
+func keyLengthError(what string, got, want int) error {
+	return fmt.Errorf("%w: %v is %v bytes instead of %v", ErrInvalidKeyLength, what, got, want)
+}
@@ -0,0 +1,37 @@
+package natrium
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	publ := priv.PublicKey()
+	signature := priv.Sign([]byte("Hello World"))
+	if err := publ.Verify([]byte("Hello Wortd"), signature); !errors.Is(err, ErrSignatureInvalid) {
+		t.FailNow()
+	}
+	if err := publ.Verify([]byte("Hello World"), signature[1:]); !errors.Is(err, ErrSignatureInvalid) {
+		t.FailNow()
+	}
+	if err := publ[1:].Verify([]byte("Hello World"), signature); !errors.Is(err, ErrInvalidKeyLength) {
+		t.FailNow()
+	}
+	if _, err := NewSecretKey(make([]byte, 3)); !errors.Is(err, ErrInvalidKeyLength) {
+		t.FailNow()
+	}
+	key := GenerateSecretKey()
+	nonce := key.NewNonce()
+	ciphertext := key.Seal([]byte("Hello World"), nonce)
+	ciphertext[0] ^= 1
+	if _, err := key.Open(ciphertext, nonce); !errors.Is(err, ErrDecryptionFailed) {
+		t.FailNow()
+	}
+	if _, err := key.Open(ciphertext[:3], nonce); !errors.Is(err, ErrDecryptionFailed) {
+		t.FailNow()
+	}
+	if _, err := NewXChaCha20Stream(make([]byte, 32), make([]byte, 12)); !errors.Is(err, ErrInvalidNonceLength) {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,84 @@
+package natrium
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestAES256GCM(t *testing.T) {
+	raw := make([]byte, AES256GCMKeyLength)
+	RandBytes(raw)
+	key, err := NewAES256GCMKey(raw)
+	if !AES256GCMAvailable() {
+		if err == nil {
+			t.FailNow()
+		}
+		t.Skip("AES-256-GCM not available")
+	}
+	if err != nil {
+		t.FailNow()
+	}
+	ad := []byte("header")
+	ciphertext, err := key.Seal([]byte("Hello World"), ad, AES256GCMNonce(1))
+	if err != nil {
+		t.FailNow()
+	}
+	plaintext, err := key.Open(ciphertext, ad, AES256GCMNonce(1))
+	if err != nil || string(plaintext) != "Hello World" {
+		t.FailNow()
+	}
+	if _, err := key.Open(ciphertext, ad, AES256GCMNonce(2)); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestCounterNonce(t *testing.T) {
+	cn := NewAES256GCMCounterNonce()
+	seen := make(chan string, 400)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				nonce, err := cn.Next()
+				if err != nil || len(nonce) != AES256GCMNonceLength {
+					panic("bad nonce")
+				}
+				seen <- string(nonce)
+			}
+		}()
+	}
+	wg.Wait()
+	close(seen)
+	unique := make(map[string]bool)
+	for n := range seen {
+		unique[n] = true
+	}
+	if len(unique) != 400 {
+		t.FailNow()
+	}
+	small := NewCounterNonce(AES256GCMNonceLength, 2)
+	a, _ := small.Next()
+	b, _ := small.Next()
+	Increment(a)
+	if CTCompare(a, b) != 0 {
+		t.FailNow()
+	}
+	if _, err := small.Next(); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestAES256GCMKeyEncode(t *testing.T) {
+	key, _ := NewAES256GCMKey(RandomBytes(AES256GCMKeyLength))
+	checkEncoding(t, []encodingCase{
+		{key, AES256GCMKeyPEMType, key.Encode, func(b []byte, e Encoding) ([]byte, error) { return DecodeAES256GCMKey(b, e) }},
+	})
+}
+
+func TestAES256GCMKeyGob(t *testing.T) {
+	key, _ := NewAES256GCMKey(RandomBytes(AES256GCMKeyLength))
+	var got AES256GCMKey
+	checkGob(t, key, key, &got, func() []byte { return got })
+}
@@ -1,66 +1,82 @@
 package natrium
 
 // #cgo darwin CFLAGS: -I/usr/local/include
-// #cgo darwin LDFLAGS: /usr/local/lib/libsodium.a
-// #cgo linux windows android LDFLAGS: -Wl,-Bstatic -lsodium -Wl,-Bdynamic
 // #include <stdio.h>
 // #include <sodium.h>
 import "C"
 import (
+	"encoding/binary"
+	"fmt"
 	"hash"
+	"io"
+	"runtime"
+	"sync"
 	"unsafe"
 )
 
-type b2bHasher struct {
+// GenericHashState is an incremental Blake2b hasher. The hash.Hash values
+// returned by NewGenericHash, NewGenericHashKeyed and SecureHasher all have
+// this concrete type, and can be asserted to it to use Clone.
+type GenericHashState struct {
 	state    [384]byte
 	orgstate [384]byte
+	outlen   int
 }
 
-func (bh *b2bHasher) cstate() *C.struct_crypto_generichash_blake2b_state {
+func (bh *GenericHashState) cstate() *C.struct_crypto_generichash_blake2b_state {
 	return (*C.struct_crypto_generichash_blake2b_state)(unsafe.Pointer(&bh.state))
 }
 
-func (bh *b2bHasher) Sum(b []byte) []byte {
-	out := make([]byte, 32)
-	rv := C.crypto_generichash_final(bh.cstate(), (*C.uchar)(&out[0]), 32)
+// Sum appends the hash of everything written so far to b, without changing
+// the state.
+func (bh *GenericHashState) Sum(b []byte) []byte {
+	// finalize a copy, so that the hasher can keep being written to
+	tmp := new(GenericHashState)
+	tmp.state = bh.state
+	out := make([]byte, bh.outlen)
+	rv := C.crypto_generichash_final(tmp.cstate(), g2cbt(out), C.size_t(bh.outlen))
 	if rv != 0 {
 		panic("crypto_generichash_final returned non-zero")
 	}
 	return append(b, out...)
 }
 
-func (bh *b2bHasher) Write(b []byte) (int, error) {
-	rv := C.crypto_generichash_update(bh.cstate(), (*C.uchar)(&b[0]), C.ulonglong(len(b)))
+// Write adds more data to the hash. It never returns an error.
+func (bh *GenericHashState) Write(b []byte) (int, error) {
+	rv := C.crypto_generichash_update(bh.cstate(), g2cbt(b), C.ulonglong(len(b)))
 	if rv != 0 {
 		panic("crypto_generichash_update returned non-zero")
 	}
 	return len(b), nil
 }
 
-func (bh *b2bHasher) Reset() {
+// Reset returns the hasher to its initial, keyed state.
+func (bh *GenericHashState) Reset() {
 	bh.state = bh.orgstate
 }
 
-func (bh *b2bHasher) Size() int {
-	return 32
+// Size returns the output length chosen when the hasher was created.
+func (bh *GenericHashState) Size() int {
+	return bh.outlen
 }
 
-func (bh *b2bHasher) BlockSize() int {
-	return 32
+// BlockSize returns the Blake2b block size.
+func (bh *GenericHashState) BlockSize() int {
+	return 128
 }
 
-// SecureHasher creates a Blake2b stream hasher.
-func SecureHasher(key []byte) hash.Hash {
-	toret := new(b2bHasher)
-	var keyptr *C.uchar
-	var keylen C.size_t
-	if key == nil || len(key) == 0 {
-		keyptr = nil
-	} else {
-		keyptr = (*C.uchar)(&key[0])
-		keylen = C.size_t(len(key))
-	}
-	rv := C.crypto_generichash_init(toret.cstate(), keyptr, keylen, 32)
+// Clone returns an independent copy of the hasher, key and all, so that a
+// common prefix can be hashed once and then continued in several ways.
+func (bh *GenericHashState) Clone() *GenericHashState {
+	toret := *bh
+	return &toret
+}
+
+func newB2bHasher(key []byte, outlen int) *GenericHashState {
+	toret := new(GenericHashState)
+	toret.outlen = outlen
+	rv := C.crypto_generichash_init(toret.cstate(), g2cbt(key), C.size_t(len(key)),
+		C.size_t(outlen))
 	if rv != 0 {
 		panic("crypto_generichash_init returned non-zero!")
 	}
@@ -68,6 +84,11 @@ func SecureHasher(key []byte) hash.Hash {
 	return toret
 }
 
+// SecureHasher creates a Blake2b stream hasher.
+func SecureHasher(key []byte) hash.Hash {
+	return newB2bHasher(key, 32)
+}
+
 // SecureHash uses the Blake2b algorithm to generate a 256-bit (32-byte)
 // hash of a message with an optional key. The key parameter can be nil if
 // normal hashing, instead of authenticated hashing, is wanted.
@@ -94,3 +115,266 @@ func SecureHash(message []byte, key []byte) []byte {
 		keyptr, keylen)
 	return out
 }
+
+// GenericHashBytesMin is the shortest output GenericHash can produce.
+const GenericHashBytesMin int = C.crypto_generichash_BYTES_MIN
+
+// GenericHashBytesMax is the longest output GenericHash can produce.
+const GenericHashBytesMax int = C.crypto_generichash_BYTES_MAX
+
+// GenericHashBytes is the default output length of GenericHash.
+const GenericHashBytes int = C.crypto_generichash_BYTES
+
+// GenericHashKeyBytesMin is the shortest key GenericHashKeyed accepts.
+const GenericHashKeyBytesMin int = C.crypto_generichash_KEYBYTES_MIN
+
+// GenericHashKeyBytesMax is the longest key GenericHashKeyed accepts.
+const GenericHashKeyBytesMax int = C.crypto_generichash_KEYBYTES_MAX
+
+func genericHashLen(outLen int) (int, error) {
+	if outLen == 0 {
+		outLen = GenericHashBytes
+	}
+	if outLen < GenericHashBytesMin || outLen > GenericHashBytesMax {
+		return 0, fmt.Errorf("generic hash output length must be between %v and %v",
+			GenericHashBytesMin, GenericHashBytesMax)
+	}
+	return outLen, nil
+}
+
+func genericHash(message []byte, key []byte, outLen int) []byte {
+	out := make([]byte, outLen)
+	rv := C.crypto_generichash(g2cbt(out), C.size_t(outLen),
+		g2cbt(message), C.ulonglong(len(message)), g2cbt(key), C.size_t(len(key)))
+	if rv != 0 {
+		panic("crypto_generichash returned non-zero")
+	}
+	return out
+}
+
+// GenericHash uses the Blake2b algorithm to hash a message to outLen bytes,
+// which must be between GenericHashBytesMin and GenericHashBytesMax. An outLen
+// of 0 selects GenericHashBytes. Since outLen is almost always a constant, a
+// bad one panics here; the rest of the generic hash functions report it as an
+// error instead.
+func GenericHash(message []byte, outLen int) []byte {
+	outLen, err := genericHashLen(outLen)
+	if err != nil {
+		panic(err.Error())
+	}
+	return genericHash(message, nil, outLen)
+}
+
+// GenericHashParallelChunkSize is the size of the chunks GenericHashParallel
+// hashes independently.
+const GenericHashParallelChunkSize = 1 << 20
+
+// GenericHashParallel hashes data with a two-level BLAKE2b tree, spreading
+// the work over GOMAXPROCS goroutines, which for inputs of many megabytes is
+// much faster than GenericHash on a multicore machine. It is a different
+// function from GenericHash and never gives the same result. outLen is
+// interpreted, and a bad one panics, as for GenericHash.
+//
+// The data is split into chunks of GenericHashParallelChunkSize bytes, the
+// last of which may be shorter; empty data has no chunks. Each chunk is hashed
+// to 32 bytes with unkeyed BLAKE2b. The result is the outLen-byte unkeyed
+// BLAKE2b of the 20 bytes "natrium tree hash v1", then len(data) as an 8-byte
+// little-endian number, then the chunk digests in order.
+func GenericHashParallel(data []byte, outLen int) []byte {
+	outLen, err := genericHashLen(outLen)
+	if err != nil {
+		panic(err.Error())
+	}
+	chunks := (len(data) + GenericHashParallelChunkSize - 1) / GenericHashParallelChunkSize
+	const prefix = "natrium tree hash v1"
+	root := make([]byte, len(prefix)+8+chunks*32)
+	copy(root, prefix)
+	binary.LittleEndian.PutUint64(root[len(prefix):], uint64(len(data)))
+	leaves := root[len(prefix)+8:]
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				end := (i + 1) * GenericHashParallelChunkSize
+				if end > len(data) {
+					end = len(data)
+				}
+				copy(leaves[i*32:], genericHash(data[i*GenericHashParallelChunkSize:end], nil, 32))
+			}
+		}()
+	}
+	for i := 0; i < chunks; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return genericHash(root, nil, outLen)
+}
+
+// GenericHashKeyed is like GenericHash, but keyed, making the result a MAC.
+// The key must be between GenericHashKeyBytesMin and GenericHashKeyBytesMax
+// bytes long.
+func GenericHashKeyed(message, key []byte, outLen int) ([]byte, error) {
+	outLen, err := genericHashLen(outLen)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) < GenericHashKeyBytesMin || len(key) > GenericHashKeyBytesMax {
+		return nil, fmt.Errorf("%w: generic hash key length must be between %v and %v", ErrInvalidKeyLength,
+			GenericHashKeyBytesMin, GenericHashKeyBytesMax)
+	}
+	return genericHash(message, key, outLen), nil
+}
+
+// GenericHashVerify checks, in constant time, that tag is the keyed generic
+// hash of message under key. The output length is taken from the tag.
+func GenericHashVerify(tag, message, key []byte) bool {
+	expected, err := GenericHashKeyed(message, key, len(tag))
+	if err != nil || len(tag) == 0 {
+		return false
+	}
+	return MemCmp(expected, tag)
+}
+
+// NewGenericHash creates an incremental Blake2b hasher producing outLen bytes,
+// with the same output as GenericHash.
+func NewGenericHash(outLen int) (hash.Hash, error) {
+	outLen, err := genericHashLen(outLen)
+	if err != nil {
+		return nil, err
+	}
+	return newB2bHasher(nil, outLen), nil
+}
+
+// NewGenericHashKeyed creates an incremental keyed Blake2b hasher, with the
+// same output as GenericHashKeyed. Reset restores the keyed initial state.
+func NewGenericHashKeyed(key []byte, outLen int) (hash.Hash, error) {
+	outLen, err := genericHashLen(outLen)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) < GenericHashKeyBytesMin || len(key) > GenericHashKeyBytesMax {
+		return nil, fmt.Errorf("%w: generic hash key length must be between %v and %v", ErrInvalidKeyLength,
+			GenericHashKeyBytesMin, GenericHashKeyBytesMax)
+	}
+	return newB2bHasher(key, outLen), nil
+}
+
+// GenericHashReader hashes everything read from r until EOF, keyed with key
+// unless it is nil, without holding the whole input in memory. Bad lengths are
+// reported before anything is read; an error from r is returned unchanged, so
+// the two can be told apart.
+func GenericHashReader(r io.Reader, key []byte, outLen int) ([]byte, error) {
+	var h hash.Hash
+	var err error
+	if key == nil {
+		h, err = NewGenericHash(outLen)
+	} else {
+		h, err = NewGenericHashKeyed(key, outLen)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// HashTee is an io.Writer that passes everything written to it through to
+// another writer while hashing it with BLAKE2b. It is returned by
+// NewGenericHashTee.
+type HashTee struct {
+	w io.Writer
+	h hash.Hash
+}
+
+// NewGenericHashTee returns a HashTee writing to w, keyed with key unless it is
+// nil, whose Sum gives an outLen-byte hash. Only bytes that w accepts are
+// hashed, so after a short write the hash still matches what reached w.
+func NewGenericHashTee(w io.Writer, key []byte, outLen int) (*HashTee, error) {
+	var h hash.Hash
+	var err error
+	if key == nil {
+		h, err = NewGenericHash(outLen)
+	} else {
+		h, err = NewGenericHashKeyed(key, outLen)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &HashTee{w: w, h: h}, nil
+}
+
+// Write writes p to the underlying writer and hashes what it accepted.
+func (ht *HashTee) Write(p []byte) (int, error) {
+	n, err := ht.w.Write(p)
+	ht.h.Write(p[:n])
+	return n, err
+}
+
+// Sum returns the hash of everything written so far. Writing may continue
+// afterwards.
+func (ht *HashTee) Sum() []byte {
+	return ht.h.Sum(nil)
+}
+
+// GenericHashSaltLength is the maximum length of the salt passed to
+// GenericHashSaltPersonal.
+const GenericHashSaltLength int = C.crypto_generichash_blake2b_SALTBYTES
+
+// GenericHashPersonalLength is the maximum length of the personalization
+// string passed to GenericHashSaltPersonal.
+const GenericHashPersonalLength int = C.crypto_generichash_blake2b_PERSONALBYTES
+
+// GenericHashSaltPersonal is like GenericHashKeyed, but also takes Blake2b's
+// salt and personalization parameters, so that the same key gives unrelated
+// hashes in different contexts. The key may be empty. Salt and personal may
+// be up to GenericHashSaltLength and GenericHashPersonalLength bytes long
+// respectively, and are zero-padded if shorter.
+func GenericHashSaltPersonal(message, key, salt, personal []byte, outLen int) ([]byte, error) {
+	outLen, err := genericHashLen(outLen)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) > GenericHashKeyBytesMax {
+		return nil, fmt.Errorf("%w: generic hash key must be at most %v bytes long", ErrInvalidKeyLength,
+			GenericHashKeyBytesMax)
+	}
+	if len(salt) > GenericHashSaltLength {
+		return nil, fmt.Errorf("generic hash salt must be at most %v bytes long",
+			GenericHashSaltLength)
+	}
+	if len(personal) > GenericHashPersonalLength {
+		return nil, fmt.Errorf("generic hash personalization must be at most %v bytes long",
+			GenericHashPersonalLength)
+	}
+	paddedSalt := make([]byte, GenericHashSaltLength)
+	copy(paddedSalt, salt)
+	paddedPersonal := make([]byte, GenericHashPersonalLength)
+	copy(paddedPersonal, personal)
+	out := make([]byte, outLen)
+	rv := C.crypto_generichash_blake2b_salt_personal(g2cbt(out), C.size_t(outLen),
+		g2cbt(message), C.ulonglong(len(message)), g2cbt(key), C.size_t(len(key)),
+		g2cbt(paddedSalt), g2cbt(paddedPersonal))
+	if rv != 0 {
+		panic("crypto_generichash_blake2b_salt_personal returned non-zero")
+	}
+	return out, nil
+}
+
+// NewMAC creates a streaming keyed Blake2b MAC producing tagLen-byte tags from
+// Sum. It can stand in for hmac.New wherever a hash.Hash is expected; Reset
+// restores the keyed initial state. Check tags with MACVerify.
+func NewMAC(key []byte, tagLen int) (hash.Hash, error) {
+	return NewGenericHashKeyed(key, tagLen)
+}
+
+// MACVerify reports, in constant time, whether the tag of everything written
+// to h so far is expectedTag. h is left as it was.
+func MACVerify(h hash.Hash, expectedTag []byte) bool {
+	return MemCmp(h.Sum(nil), expectedTag)
+}
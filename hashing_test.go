@@ -2,6 +2,8 @@ package natrium
 
 import (
 	"bytes"
+	"errors"
+	"io"
 	"math/rand"
 	"testing"
 )
@@ -23,3 +25,263 @@ func TestHashing(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestGenericHash(t *testing.T) {
+	message := []byte("Hello World")
+	if CTCompare(GenericHash(message, 0), SecureHash(message, nil)) != 0 {
+		t.FailNow()
+	}
+	if len(GenericHash(message, GenericHashBytesMin)) != GenericHashBytesMin ||
+		len(GenericHash(nil, GenericHashBytesMax)) != GenericHashBytesMax {
+		t.FailNow()
+	}
+	// Blake2b-256 of the empty string
+	if HexEncode(GenericHash(nil, 32)) !=
+		"0e5751c026e543b2e8ab2eb06099daa1d1e5df47778f7787faab45cdf12fe3a8" {
+		t.FailNow()
+	}
+}
+
+func TestGenericHashKeyed(t *testing.T) {
+	key := make([]byte, 64)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	// first keyed vector from the BLAKE2 reference KAT
+	mac, err := GenericHashKeyed(nil, key, 64)
+	if err != nil || HexEncode(mac) != "10ebb67700b1868efb4417987acf4690ae9d972fb7a590c2f02871799aaa4786"+
+		"b5e996e8f0f4eb981fc214b005f42d2ff4233499391653df7aefcbc13fc51568" {
+		t.FailNow()
+	}
+	if !GenericHashVerify(mac, nil, key) || GenericHashVerify(mac, []byte("x"), key) {
+		t.FailNow()
+	}
+	if _, err := GenericHashKeyed(nil, key[:5], 32); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestNewGenericHash(t *testing.T) {
+	message := make([]byte, 10000)
+	RandBytes(message)
+	key := make([]byte, 32)
+	RandBytes(key)
+	hasher, err := NewGenericHashKeyed(key, 48)
+	if err != nil || hasher.Size() != 48 {
+		t.FailNow()
+	}
+	for i := 0; i < len(message); i += 7 {
+		end := i + 7
+		if end > len(message) {
+			end = len(message)
+		}
+		hasher.Write(message[i:end])
+	}
+	expected, _ := GenericHashKeyed(message, key, 48)
+	if CTCompare(hasher.Sum(nil), expected) != 0 || CTCompare(hasher.Sum(nil), expected) != 0 {
+		t.FailNow()
+	}
+	hasher.Reset()
+	hasher.Write(message)
+	if CTCompare(hasher.Sum(nil), expected) != 0 {
+		t.FailNow()
+	}
+	if _, err := NewGenericHash(8); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestGenericHashSaltPersonal(t *testing.T) {
+	key := RandomBytes(32)
+	message := []byte("Hello World")
+	a, err := GenericHashSaltPersonal(message, key, nil, []byte("context-a"), 32)
+	if err != nil {
+		t.FailNow()
+	}
+	b, _ := GenericHashSaltPersonal(message, key, nil, []byte("context-b"), 32)
+	if MemCmp(a, b) {
+		t.FailNow()
+	}
+	// all-zero salt and personalization are plain keyed hashing
+	plain, _ := GenericHashSaltPersonal(message, key, nil, nil, 32)
+	keyed, _ := GenericHashKeyed(message, key, 32)
+	if !MemCmp(plain, keyed) {
+		t.FailNow()
+	}
+	if _, err := GenericHashSaltPersonal(message, key, make([]byte, 17), nil, 32); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestGenericHashLengths(t *testing.T) {
+	key := RandomBytes(GenericHashKeyBytesMin)
+	message := []byte("Hello World")
+	for outLen := GenericHashBytesMin; outLen <= GenericHashBytesMax; outLen += 8 {
+		if len(GenericHash(message, outLen)) != outLen {
+			t.FailNow()
+		}
+		keyed, err := GenericHashKeyed(message, key, outLen)
+		if err != nil || len(keyed) != outLen {
+			t.FailNow()
+		}
+		salted, err := GenericHashSaltPersonal(message, key, nil, []byte("len"), outLen)
+		if err != nil || len(salted) != outLen {
+			t.FailNow()
+		}
+		h, err := NewGenericHashKeyed(key, outLen)
+		if err != nil || h.Size() != outLen {
+			t.FailNow()
+		}
+		h.Write(message)
+		if !MemCmp(h.Sum(nil), keyed) {
+			t.FailNow()
+		}
+	}
+	for _, outLen := range []int{-1, GenericHashBytesMin - 1, GenericHashBytesMax + 1} {
+		if _, err := GenericHashKeyed(message, key, outLen); err == nil {
+			t.FailNow()
+		}
+		if _, err := GenericHashSaltPersonal(message, nil, nil, nil, outLen); err == nil {
+			t.FailNow()
+		}
+		if _, err := NewGenericHash(outLen); err == nil {
+			t.FailNow()
+		}
+	}
+}
+
+func TestMAC(t *testing.T) {
+	key := RandomBytes(32)
+	mac, err := NewMAC(key, 16)
+	if err != nil {
+		t.FailNow()
+	}
+	mac.Write([]byte("Hello "))
+	mac.Write([]byte("World"))
+	tag := mac.Sum(nil)
+	expected, _ := GenericHashKeyed([]byte("Hello World"), key, 16)
+	if !MACVerify(mac, expected) || !MemCmp(tag, expected) {
+		t.FailNow()
+	}
+	mac.Reset()
+	mac.Write([]byte("Hello Wortd"))
+	if MACVerify(mac, expected) {
+		t.FailNow()
+	}
+	if _, err := NewMAC(key[:4], 16); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestGenericHashReader(t *testing.T) {
+	data := RandomBytes(100000)
+	key := RandomBytes(GenericHashKeyBytesMin)
+	sum, err := GenericHashReader(bytes.NewReader(data), nil, 32)
+	if err != nil || !bytes.Equal(sum, GenericHash(data, 32)) {
+		t.FailNow()
+	}
+	sum, err = GenericHashReader(bytes.NewReader(data), key, 64)
+	keyed, _ := GenericHashKeyed(data, key, 64)
+	if err != nil || !bytes.Equal(sum, keyed) {
+		t.FailNow()
+	}
+	if _, err := GenericHashReader(failingReader{}, key, 32); err != io.ErrClosedPipe {
+		t.FailNow()
+	}
+	if _, err := GenericHashReader(failingReader{}, key[1:], 32); !errors.Is(err, ErrInvalidKeyLength) {
+		t.FailNow()
+	}
+	if _, err := GenericHashReader(failingReader{}, nil, 8); err == nil || err == io.ErrClosedPipe {
+		t.FailNow()
+	}
+}
+
+func TestGenericHashTee(t *testing.T) {
+	data := RandomBytes(100000)
+	var out bytes.Buffer
+	tee, err := NewGenericHashTee(&out, nil, 32)
+	if err != nil {
+		t.FailNow()
+	}
+	if _, err := io.Copy(tee, bytes.NewReader(data)); err != nil {
+		t.FailNow()
+	}
+	if !bytes.Equal(out.Bytes(), data) || !bytes.Equal(tee.Sum(), GenericHash(data, 32)) {
+		t.FailNow()
+	}
+	key := RandomBytes(32)
+	tee, _ = NewGenericHashTee(new(bytes.Buffer), key, 64)
+	tee.Write(data[:10])
+	tee.Write(data[10:])
+	keyed, _ := GenericHashKeyed(data, key, 64)
+	if !bytes.Equal(tee.Sum(), keyed) {
+		t.FailNow()
+	}
+	if _, err := NewGenericHashTee(&out, key[:1], 32); !errors.Is(err, ErrInvalidKeyLength) {
+		t.FailNow()
+	}
+}
+
+func TestGenericHashParallel(t *testing.T) {
+	data := make([]byte, 5*GenericHashParallelChunkSize/2)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	vectors := []struct {
+		data   []byte
+		outLen int
+		want   string
+	}{
+		{data, 32, "079dc4437b8dd4df6f21a295d1acf3ee88d4e6047445c6ef79ceedabda1b741d"},
+		{nil, 0, "5af490804f6e950376f00555e6422110b422cd84c1ade628af796fe7aea0332a"},
+		{data[:GenericHashParallelChunkSize], 64, "b686a7252bf63c91170f7d275502563448ffc2c4" +
+			"70d0720f503f63e55a55a079ef78f76c77066b5d900877507fbca54a752c08c1c0bf3851f8d1f9bbd1c0e33b"},
+	}
+	for _, v := range vectors {
+		if BinToHex(GenericHashParallel(v.data, v.outLen)) != v.want {
+			t.FailNow()
+		}
+	}
+	if bytes.Equal(GenericHashParallel(data, 32), GenericHash(data, 32)) {
+		t.FailNow()
+	}
+}
+
+func BenchmarkGenericHash_Serial(b *testing.B) {
+	data := make([]byte, 64<<20)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		GenericHash(data, 32)
+	}
+}
+
+func BenchmarkGenericHash_Parallel(b *testing.B) {
+	data := make([]byte, 64<<20)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		GenericHashParallel(data, 32)
+	}
+}
+
+func TestGenericHashClone(t *testing.T) {
+	prefix := RandomBytes(1000)
+	key := RandomBytes(32)
+	h, _ := NewGenericHashKeyed(key, 32)
+	h.Write(prefix)
+	a := h.(*GenericHashState)
+	b := a.Clone()
+	a.Write([]byte("A"))
+	b.Write([]byte("B"))
+	wantA, _ := GenericHashKeyed(append(append([]byte(nil), prefix...), 'A'), key, 32)
+	wantB, _ := GenericHashKeyed(append(append([]byte(nil), prefix...), 'B'), key, 32)
+	if !bytes.Equal(a.Sum(nil), wantA) || !bytes.Equal(b.Sum(nil), wantB) {
+		t.FailNow()
+	}
+	// the clone keeps the key across Reset
+	b.Reset()
+	b.Write([]byte("B"))
+	wantB, _ = GenericHashKeyed([]byte("B"), key, 32)
+	if !bytes.Equal(b.Sum(nil), wantB) {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,87 @@
+package natrium
+
+import (
+	"fmt"
+	"hash"
+)
+
+// Hash functions for HKDFExtract, HKDFExpand and HKDF, named by their output
+// size in bits.
+const (
+	HKDFSHA256 = 256
+	HKDFSHA512 = 512
+)
+
+// hkdfHMAC returns the HMAC constructor and output length for sha.
+func hkdfHMAC(sha int) (func(key []byte) hash.Hash, int, error) {
+	switch sha {
+	case HKDFSHA256:
+		return NewHMACSHA256, HMACSHA256Length, nil
+	case HKDFSHA512:
+		return NewHMACSHA512, HMACSHA512Length, nil
+	}
+	return nil, 0, fmt.Errorf("HKDF hash must be HKDFSHA256 or HKDFSHA512, not %v", sha)
+}
+
+// HKDFExtract is the extract step of HKDF, RFC 5869, with HMAC-SHA-256 or
+// HMAC-SHA-512 as sha selects. It condenses input keying material ikm, such
+// as a Diffie-Hellman output, and an optional salt into a pseudorandom key as
+// long as the hash, for HKDFExpand. A nil salt stands for a string of zero
+// bytes, as in the RFC.
+//
+// The libsodium versions natrium supports predate crypto_kdf_hkdf, so HKDF is
+// built here from libsodium's HMAC; the output is the same as any other
+// RFC 5869 implementation's. Unlike Expand, which uses BLAKE2b, it is meant
+// for protocols such as Noise that specify HKDF exactly.
+func HKDFExtract(salt, ikm []byte, sha int) ([]byte, error) {
+	newHMAC, _, err := hkdfHMAC(sha)
+	if err != nil {
+		return nil, err
+	}
+	h := newHMAC(salt)
+	h.Write(ikm)
+	return h.Sum(nil), nil
+}
+
+// HKDFExpand is the expand step of HKDF, stretching the pseudorandom key prk
+// into outLen bytes bound to info. prk must be at least as long as the hash,
+// as HKDFExtract's output is, and outLen between 1 and 255 times the hash
+// length.
+func HKDFExpand(prk, info []byte, outLen int, sha int) ([]byte, error) {
+	newHMAC, size, err := hkdfHMAC(sha)
+	if err != nil {
+		return nil, err
+	}
+	if len(prk) < size {
+		return nil, fmt.Errorf("%w: HKDF pseudorandom key is %v bytes, shorter than %v",
+			ErrInvalidKeyLength, len(prk), size)
+	}
+	if outLen < 1 || outLen > 255*size {
+		return nil, fmt.Errorf("HKDF output length must be between 1 and %v", 255*size)
+	}
+	h := newHMAC(prk)
+	toret := make([]byte, 0, outLen+size)
+	var block []byte
+	for i := 1; len(toret) < outLen; i++ {
+		h.Reset()
+		h.Write(block)
+		h.Write(info)
+		h.Write([]byte{byte(i)})
+		block = h.Sum(block[:0])
+		toret = append(toret, block...)
+	}
+	wipe(block)
+	wipe(toret[outLen:cap(toret)])
+	return toret[:outLen], nil
+}
+
+// HKDF runs HKDFExtract and then HKDFExpand in one call, wiping the
+// intermediate pseudorandom key.
+func HKDF(salt, ikm, info []byte, outLen int, sha int) ([]byte, error) {
+	prk, err := HKDFExtract(salt, ikm, sha)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(prk)
+	return HKDFExpand(prk, info, outLen, sha)
+}
@@ -0,0 +1,76 @@
+package natrium
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func byteRange(lo, hi int) []byte {
+	toret := make([]byte, 0, hi-lo)
+	for i := lo; i < hi; i++ {
+		toret = append(toret, byte(i))
+	}
+	return toret
+}
+
+func TestHKDFVectors(t *testing.T) {
+	vectors := []struct {
+		sha             int
+		salt, ikm, info []byte
+		prk, okm        string
+	}{
+		// RFC 5869, test cases 1 to 3
+		{HKDFSHA256, byteRange(0, 13), bytes.Repeat([]byte{0x0b}, 22), byteRange(0xf0, 0xfa),
+			"077709362c2e32df0ddc3f0dc47bba6390b6c73bb50f9c3122ec844ad7c2b3e5",
+			"3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865"},
+		{HKDFSHA256, byteRange(0x60, 0xb0), byteRange(0, 0x50), byteRange(0xb0, 0x100),
+			"06a6b88c5853361a06104c9ceb35b45cef760014904671014a193f40c15fc244",
+			"b11e398dc80327a1c8e7f78c596a49344f012eda2d4efad8a050cc4c19afa97c" +
+				"59045a99cac7827271cb41c65e590e09da3275600c2f09b8367793a9aca3db71" +
+				"cc30c58179ec3e87c14c01d5c1f3434f1d87"},
+		{HKDFSHA256, nil, bytes.Repeat([]byte{0x0b}, 22), nil,
+			"19ef24a32c717b167f33a91d6f648bdf96596776afdb6377ac434c1c293ccb04",
+			"8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8"},
+		// the inputs of test case 1 with SHA-512, from another implementation
+		{HKDFSHA512, byteRange(0, 13), bytes.Repeat([]byte{0x0b}, 22), byteRange(0xf0, 0xfa),
+			"665799823737ded04a88e47e54a5890bb2c3d247c7a4254a8e61350723590a26" +
+				"c36238127d8661b88cf80ef802d57e2f7cebcf1e00e083848be19929c61b4237",
+			"832390086cda71fb47625bb5ceb168e4c8e26a1a16ed34d9fc7fe92c1481579338da362cb8d9f925d7cb"},
+	}
+	for _, v := range vectors {
+		prk, err := HKDFExtract(v.salt, v.ikm, v.sha)
+		if err != nil || BinToHex(prk) != v.prk {
+			t.FailNow()
+		}
+		okm, err := HKDFExpand(prk, v.info, len(v.okm)/2, v.sha)
+		if err != nil || BinToHex(okm) != v.okm {
+			t.FailNow()
+		}
+		okm, err = HKDF(v.salt, v.ikm, v.info, len(v.okm)/2, v.sha)
+		if err != nil || BinToHex(okm) != v.okm {
+			t.FailNow()
+		}
+	}
+}
+
+func TestHKDFLimits(t *testing.T) {
+	prk, _ := HKDFExtract(nil, []byte("ikm"), HKDFSHA256)
+	if out, err := HKDFExpand(prk, nil, 255*32, HKDFSHA256); err != nil || len(out) != 255*32 {
+		t.FailNow()
+	}
+	for _, outLen := range []int{0, -1, 255*32 + 1} {
+		if _, err := HKDFExpand(prk, nil, outLen, HKDFSHA256); err == nil {
+			t.FailNow()
+		}
+	}
+	if _, err := HKDFExpand(prk, nil, 32, HKDFSHA512); !errors.Is(err, ErrInvalidKeyLength) {
+		t.FailNow()
+	}
+	if _, err := HKDFExtract(nil, []byte("ikm"), 384); err == nil {
+		t.FailNow()
+	}
+	if _, err := HKDF(nil, []byte("ikm"), nil, 32, 1); err == nil {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,80 @@
+package natrium
+
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #include <stdio.h>
+// #include <sodium.h>
+import "C"
+import (
+	"fmt"
+	"io"
+)
+
+// hybridSealedKeyLength is the size of the sealed secretstream key that starts
+// a HybridSeal stream.
+const hybridSealedKeyLength = BoxSealOverhead + SecretStreamKeyLength
+
+// HybridSeal anonymously encrypts everything read from r to the holder of the
+// private key for to, writing the result onto w. Only a fresh random
+// SecretStreamKey goes through public-key encryption; the payload itself is
+// streamed through secretstream, so it can be of any size and is never held
+// in memory at once. The stream is laid out as follows:
+//
+//	80 bytes  crypto_box_seal of the 32-byte secretstream key
+//	24 bytes  secretstream header
+//	frames    as written by SecretStreamKey.NewWriter, ending with a frame
+//	          tagged SecretStreamTagFinal
+//
+// An error is returned if to is malformed or a low-order point, or if reading
+// r or writing w fails, in which case w holds partial output.
+func HybridSeal(w io.Writer, to BoxPublic, r io.Reader) error {
+	if len(to) != BoxPublicLength {
+		return keyLengthError("box public key", len(to), BoxPublicLength)
+	}
+	key := GenerateSecretStreamKey()
+	defer key.Destroy()
+	sealed := make([]byte, hybridSealedKeyLength)
+	rv := C.crypto_box_seal(g2cbt(sealed), g2cbt(key), C.ulonglong(len(key)), g2cbt(to))
+	if rv != 0 {
+		return ErrLowOrderPoint
+	}
+	sw, header, err := key.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(sealed); err != nil {
+		return err
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := io.Copy(sw, r); err != nil {
+		return err
+	}
+	return sw.Close()
+}
+
+// HybridOpen decrypts a stream produced by HybridSeal from r onto w. Like the
+// reader from SecretStreamKey.NewReader, it reports truncation as
+// ErrSecretStreamTruncated and tampering as ErrDecryptionFailed. Plaintext is
+// written as each frame verifies, so w must be discarded if HybridOpen fails.
+func HybridOpen(w io.Writer, priv BoxPrivate, r io.Reader) error {
+	start := make([]byte, hybridSealedKeyLength+SecretStreamHeaderLength)
+	if _, err := io.ReadFull(r, start); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("%w: hybrid stream too short", ErrDecryptionFailed)
+		}
+		return err
+	}
+	raw, err := priv.SealOpen(start[:hybridSealedKeyLength])
+	if err != nil {
+		return err
+	}
+	key := SecretStreamKey(raw)
+	defer key.Destroy()
+	sr, err := key.NewReader(r, start[hybridSealedKeyLength:])
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, sr)
+	return err
+}
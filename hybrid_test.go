@@ -0,0 +1,56 @@
+package natrium
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestHybrid(t *testing.T) {
+	priv := BoxGenerateKey()
+	for _, size := range []int{0, 5, 3*SecretStreamChunkSize + 7} {
+		message := RandomBytes(size)
+		var ct bytes.Buffer
+		if HybridSeal(&ct, priv.PublicKey(), bytes.NewReader(message)) != nil {
+			t.FailNow()
+		}
+		var pt bytes.Buffer
+		if HybridOpen(&pt, priv, bytes.NewReader(ct.Bytes())) != nil || !bytes.Equal(pt.Bytes(), message) {
+			t.FailNow()
+		}
+		if HybridOpen(&pt, BoxGenerateKey(), bytes.NewReader(ct.Bytes())) != ErrDecryptionFailed {
+			t.FailNow()
+		}
+		// the stream starts with the sealed key and the secretstream header
+		raw, err := priv.SealOpen(ct.Bytes()[:hybridSealedKeyLength])
+		if err != nil || len(raw) != SecretStreamKeyLength {
+			t.FailNow()
+		}
+	}
+}
+
+func TestHybridTamper(t *testing.T) {
+	priv := BoxGenerateKey()
+	var ct bytes.Buffer
+	if HybridSeal(&ct, priv.PublicKey(), bytes.NewReader([]byte("Hello World"))) != nil {
+		t.FailNow()
+	}
+	good := ct.Bytes()
+	var pt bytes.Buffer
+	if err := HybridOpen(&pt, priv, bytes.NewReader(good[:50])); !errors.Is(err, ErrDecryptionFailed) {
+		t.FailNow()
+	}
+	if err := HybridOpen(&pt, priv, bytes.NewReader(good[:len(good)-1])); err != ErrSecretStreamTruncated {
+		t.FailNow()
+	}
+	for _, i := range []int{0, hybridSealedKeyLength, len(good) - 1} {
+		tampered := append([]byte(nil), good...)
+		tampered[i] ^= 1
+		if err := HybridOpen(&pt, priv, bytes.NewReader(tampered)); !errors.Is(err, ErrDecryptionFailed) {
+			t.FailNow()
+		}
+	}
+	if HybridSeal(&ct, make(BoxPublic, BoxPublicLength), bytes.NewReader(nil)) != ErrLowOrderPoint {
+		t.FailNow()
+	}
+}
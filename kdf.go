@@ -0,0 +1,243 @@
+package natrium
+
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #include <stdio.h>
+// #include <sodium.h>
+import "C"
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// MasterKey represents a key from which many independent subkeys can be
+// derived with crypto_kdf. It is a slice, so plain assignment shares it;
+// see Clone.
+type MasterKey []byte
+
+// String returns a redacted placeholder so the key is not printed by accident.
+func (k MasterKey) String() string {
+	return redacted("mstkey", k)
+}
+
+// Format implements fmt.Formatter, printing String for every verb.
+func (k MasterKey) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, k.String())
+}
+
+// MasterKeyLength is the length of a MasterKey.
+const MasterKeyLength int = C.crypto_kdf_KEYBYTES
+
+// SubkeyBytesMin is the shortest subkey Subkey can derive.
+const SubkeyBytesMin int = C.crypto_kdf_BYTES_MIN
+
+// SubkeyBytesMax is the longest subkey Subkey can derive.
+const SubkeyBytesMax int = C.crypto_kdf_BYTES_MAX
+
+// NewMasterKey returns a length-checked copy of b as a MasterKey.
+func NewMasterKey(b []byte) (MasterKey, error) {
+	return copyKey(b, MasterKeyLength, "master key")
+}
+
+// MasterKeyFromHex parses a master key written in hex.
+func MasterKeyFromHex(s string) (MasterKey, error) {
+	return keyFromHex(s, "", MasterKeyLength, "master key")
+}
+
+// MustMasterKeyFromHex is like MasterKeyFromHex, but panics on error.
+func MustMasterKeyFromHex(s string) MasterKey {
+	return mustKey(MasterKeyFromHex(s))
+}
+
+// Equal reports, in constant time, whether two master keys are the same.
+func (k MasterKey) Equal(other MasterKey) bool {
+	return MemCmp(k, other)
+}
+
+// Clone returns an independent copy of the master key.
+func (k MasterKey) Clone() MasterKey {
+	return append(MasterKey(nil), k...)
+}
+
+// GenerateMasterKey generates a random MasterKey.
+func GenerateMasterKey() MasterKey {
+	mustInit()
+	toret := make([]byte, MasterKeyLength)
+	RandBytes(toret)
+	return toret
+}
+
+// Destroy wipes the master key from memory. Subkeys already derived from it
+// are unaffected and must be wiped separately.
+func (k MasterKey) Destroy() {
+	wipe(k)
+}
+
+// Context turns a short label into a context for Subkey by padding it with
+// zero bytes to 8 bytes, so that callers can write Context("session"). Labels
+// are meant to be constants in the program, so one longer than 8 bytes is a
+// bug, and makes Context panic rather than be silently truncated into a
+// collision with another label. Because of the padding, a label must not
+// itself end in a zero byte.
+func Context(label string) [8]byte {
+	var toret [8]byte
+	if len(label) > len(toret) {
+		panic(fmt.Sprintf("KDF context label %q is longer than %v bytes", label, len(toret)))
+	}
+	copy(toret[:], label)
+	return toret
+}
+
+// Subkey derives the subkey with the given id and context, which can be made
+// from a string with Context. The subkey must be between SubkeyBytesMin and
+// SubkeyBytesMax bytes long. Different ids or contexts give unrelated
+// subkeys, and knowing a subkey reveals nothing about the master key.
+func (k MasterKey) Subkey(id uint64, context [8]byte, outLen int) ([]byte, error) {
+	if len(k) != MasterKeyLength {
+		return nil, keyLengthError("master key", len(k), MasterKeyLength)
+	}
+	if outLen < SubkeyBytesMin || outLen > SubkeyBytesMax {
+		return nil, fmt.Errorf("subkey length must be between %v and %v",
+			SubkeyBytesMin, SubkeyBytesMax)
+	}
+	toret := make([]byte, outLen)
+	rv := C.crypto_kdf_derive_from_key(g2cbt(toret), C.size_t(outLen), C.uint64_t(id),
+		(*C.char)(unsafe.Pointer(&context[0])), g2cbt(k))
+	if rv != 0 {
+		return nil, errors.New("crypto_kdf_derive_from_key returned non-zero")
+	}
+	return toret, nil
+}
+
+// GobEncode implements the gob.GobEncoder interface.
+func (k MasterKey) GobEncode() ([]byte, error) {
+	return encodeKeyGob(gobMasterKey, k)
+}
+
+// GobDecode implements the gob.GobDecoder interface, rejecting anything that
+// is not a master key of the right length.
+func (k *MasterKey) GobDecode(data []byte) error {
+	raw, err := decodeKeyGob(data, gobMasterKey, MasterKeyLength, "master key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, returning a
+// copy of the raw key.
+func (k MasterKey) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), k...), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. The
+// data is length-checked and copied, never aliased.
+func (k *MasterKey) UnmarshalBinary(data []byte) error {
+	raw, err := copyKey(data, MasterKeyLength, "master key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, encoding the
+// key as lowercase hex.
+func (k MasterKey) MarshalText() ([]byte, error) {
+	return []byte(BinToHex(k)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, reversing
+// MarshalText.
+func (k *MasterKey) UnmarshalText(text []byte) error {
+	raw, err := unmarshalKeyText(text, MasterKeyLength, "master key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MasterKeyPEMType is the PEM block type used by Encode with EncodingPEM.
+const MasterKeyPEMType = "NATRIUM MASTER KEY"
+
+// Encode serializes the key in the given encoding, as raw bytes, hex, PEM or
+// base64. Every encoding holds the secret in the clear.
+func (k MasterKey) Encode(enc Encoding) ([]byte, error) {
+	return encodeKey(k, enc, MasterKeyPEMType, MasterKeyLength, "master key")
+}
+
+// DecodeMasterKey parses a key written by Encode with the same encoding. Data
+// of the wrong length, or a PEM block of another type, gives an error.
+func DecodeMasterKey(data []byte, enc Encoding) (MasterKey, error) {
+	return decodeKey(data, enc, MasterKeyPEMType, MasterKeyLength, "master key")
+}
+
+// ExpandMax is the longest output Expand can produce.
+const ExpandMax = 255 * GenericHashBytesMax
+
+// Expand stretches a secret, such as the output of ScalarMult or ECDHSecret,
+// into outLen bytes of key material bound to the label info, playing the role
+// of the expand step of HKDF. Different info values give independent outputs,
+// so one secret can yield separate encryption and MAC keys. The secret must be
+// between GenericHashKeyBytesMin and GenericHashKeyBytesMax bytes long, and
+// outLen between 1 and ExpandMax.
+//
+// The output is the concatenation, truncated to outLen bytes, of the blocks
+// T(1), T(2), ..., where T(i) is the 64-byte BLAKE2b of info followed by i as
+// an 8-byte little-endian number, keyed with secret. As with HKDF, a shorter
+// output is a prefix of a longer one with the same info, so the length should
+// not be relied on to separate keys.
+func Expand(secret, info []byte, outLen int) ([]byte, error) {
+	if len(secret) < GenericHashKeyBytesMin || len(secret) > GenericHashKeyBytesMax {
+		return nil, fmt.Errorf("%w: Expand secret length must be between %v and %v",
+			ErrInvalidKeyLength, GenericHashKeyBytesMin, GenericHashKeyBytesMax)
+	}
+	if outLen < 1 || outLen > ExpandMax {
+		return nil, fmt.Errorf("Expand output length must be between 1 and %v", ExpandMax)
+	}
+	toret := make([]byte, 0, outLen+GenericHashBytesMax)
+	block := make([]byte, len(info)+8)
+	copy(block, info)
+	for i := uint64(1); len(toret) < outLen; i++ {
+		binary.LittleEndian.PutUint64(block[len(info):], i)
+		toret = append(toret, genericHash(block, secret, GenericHashBytesMax)...)
+	}
+	wipe(toret[outLen:cap(toret)])
+	return toret[:outLen], nil
+}
+
+// SplitKey cuts key material, such as the output of Expand, into consecutive
+// parts of the given sizes, in order, so that
+//
+//	parts, err := SplitKey(material, 32, 32, 24)
+//
+// gives an encryption key, a MAC key and a nonce. The sizes must be positive
+// and add up to exactly len(material), so that a miscounted offset is an
+// error rather than a silently overlapping or truncated key. Each part is a
+// copy, so wiping it, or material, affects nothing else. The sizes are a
+// list rather than a map from names, because the order of the parts is what
+// fixes their offsets, and Go maps have none.
+func SplitKey(material []byte, sizes ...int) ([][]byte, error) {
+	total := 0
+	for _, size := range sizes {
+		if size <= 0 {
+			return nil, fmt.Errorf("key part size %v is not positive", size)
+		}
+		total += size
+		if total > len(material) {
+			break
+		}
+	}
+	if total != len(material) {
+		return nil, fmt.Errorf("key parts of sizes %v do not add up to %v bytes", sizes, len(material))
+	}
+	toret := make([][]byte, len(sizes))
+	for i, size := range sizes {
+		toret[i] = append([]byte(nil), material[:size]...)
+		material = material[size:]
+	}
+	return toret, nil
+}
@@ -0,0 +1,109 @@
+package natrium
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSubkey(t *testing.T) {
+	master := GenerateMasterKey()
+	ctx := [8]byte{'t', 'e', 's', 't'}
+	a, err := master.Subkey(1, ctx, 32)
+	if err != nil || len(a) != 32 {
+		t.FailNow()
+	}
+	b, _ := master.Subkey(2, ctx, 32)
+	c, _ := master.Subkey(1, [8]byte{'o', 't', 'h', 'e', 'r'}, 32)
+	again, _ := master.Subkey(1, ctx, 32)
+	if CTCompare(a, b) == 0 || CTCompare(a, c) == 0 || CTCompare(a, again) != 0 {
+		t.FailNow()
+	}
+	if _, err := master.Subkey(1, ctx, 8); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestExpand(t *testing.T) {
+	secret := make([]byte, 32)
+	for i := range secret {
+		secret[i] = byte(i)
+	}
+	// computed independently with Python's hashlib.blake2b
+	want := "4efd550a4b79689b8fc235a6ff59d3ed75b6244c246e34046b5e6c5bfea41e16" +
+		"ebf3046366a10a07f8649dd1ca99d358ae7153b112280f1a925e681fc0b39274" +
+		"4709ebc6f559cf0e3ede9846831478656d569e640856f4435b07b0a13b393bb3" +
+		"c5de48bb"
+	out, err := Expand(secret, []byte("natrium test"), 100)
+	if err != nil || BinToHex(out) != want {
+		t.FailNow()
+	}
+	enc, _ := Expand(secret, []byte("encryption"), 32)
+	mac, _ := Expand(secret, []byte("mac"), 32)
+	if MemCmp(enc, mac) {
+		t.FailNow()
+	}
+	if _, err := Expand(secret[:8], nil, 32); err == nil {
+		t.FailNow()
+	}
+	for _, n := range []int{0, ExpandMax + 1} {
+		if _, err := Expand(secret, nil, n); err == nil {
+			t.FailNow()
+		}
+	}
+	if out, err := Expand(secret, nil, ExpandMax); err != nil || len(out) != ExpandMax {
+		t.FailNow()
+	}
+}
+
+func TestContext(t *testing.T) {
+	if Context("test") != [8]byte{'t', 'e', 's', 't'} || Context("") != [8]byte{} {
+		t.FailNow()
+	}
+	master := GenerateMasterKey()
+	a, _ := master.Subkey(1, Context("session"), 32)
+	b, _ := master.Subkey(1, Context("storage"), 32)
+	if MemCmp(a, b) {
+		t.FailNow()
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.FailNow()
+			}
+		}()
+		Context("too long!")
+	}()
+}
+
+func TestSplitKey(t *testing.T) {
+	material := RandomBytes(88)
+	parts, err := SplitKey(material, 32, 32, 24)
+	if err != nil || len(parts) != 3 {
+		t.FailNow()
+	}
+	if !bytes.Equal(bytes.Join(parts, nil), material) || len(parts[2]) != 24 {
+		t.FailNow()
+	}
+	wipe(parts[0])
+	if isZero(material[:32]) {
+		t.FailNow()
+	}
+	for _, sizes := range [][]int{nil, {32, 32}, {32, 32, 25}, {88, 0}, {100, -12}} {
+		if _, err := SplitKey(material, sizes...); err == nil {
+			t.FailNow()
+		}
+	}
+}
+
+func TestMasterKeyEncode(t *testing.T) {
+	key := GenerateMasterKey()
+	checkEncoding(t, []encodingCase{
+		{key, MasterKeyPEMType, key.Encode, func(b []byte, e Encoding) ([]byte, error) { return DecodeMasterKey(b, e) }},
+	})
+}
+
+func TestMasterKeyGob(t *testing.T) {
+	key := GenerateMasterKey()
+	var got MasterKey
+	checkGob(t, key, key, &got, func() []byte { return got })
+}
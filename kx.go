@@ -0,0 +1,310 @@
+package natrium
+
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #include <stdio.h>
+// #include <sodium.h>
+import "C"
+import (
+	"errors"
+	"fmt"
+)
+
+// KxPublic represents a public key for session key exchange.
+type KxPublic []byte
+
+// KxPrivate represents a private key for session key exchange. Assigning it
+// does not copy the key; use Clone for that.
+type KxPrivate []byte
+
+// String returns the key in hex, prefixed with its kind.
+func (k KxPublic) String() string {
+	return fmt.Sprintf("kxpub:%x", []byte(k))
+}
+
+// String returns a redacted placeholder so the key is not printed by accident.
+func (k KxPrivate) String() string {
+	return redacted("kxprv", k)
+}
+
+// Format implements fmt.Formatter, printing String for every verb.
+func (k KxPrivate) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, k.String())
+}
+
+// HexString returns the key as "kxprv:" followed by hex, which
+// KxPrivateFromHex parses back.
+func (k KxPrivate) HexString() string {
+	return fmt.Sprintf("kxprv:%x", []byte(k))
+}
+
+// KxPublicLength is the length of a key exchange public key.
+const KxPublicLength int = C.crypto_kx_PUBLICKEYBYTES
+
+// KxPrivateLength is the length of a key exchange private key.
+const KxPrivateLength int = C.crypto_kx_SECRETKEYBYTES
+
+// KxSessionKeyLength is the length of each session key. Session keys can be
+// used directly as SecretKey or AEAD keys.
+const KxSessionKeyLength int = C.crypto_kx_SESSIONKEYBYTES
+
+// NewKxPublic returns a length-checked copy of b as a key exchange public key.
+func NewKxPublic(b []byte) (KxPublic, error) {
+	return copyKey(b, KxPublicLength, "key exchange public key")
+}
+
+// KxPublicFromHex parses a key exchange public key written in hex, optionally
+// prefixed with "kxpub:".
+func KxPublicFromHex(s string) (KxPublic, error) {
+	return keyFromHex(s, "kxpub:", KxPublicLength, "key exchange public key")
+}
+
+// MustKxPublicFromHex is like KxPublicFromHex, but panics on error.
+func MustKxPublicFromHex(s string) KxPublic {
+	return mustKey(KxPublicFromHex(s))
+}
+
+// NewKxPrivate returns a length-checked copy of b as a key exchange private
+// key.
+func NewKxPrivate(b []byte) (KxPrivate, error) {
+	return copyKey(b, KxPrivateLength, "key exchange private key")
+}
+
+// KxPrivateFromHex parses a key exchange private key written in hex,
+// optionally prefixed with "kxprv:".
+func KxPrivateFromHex(s string) (KxPrivate, error) {
+	return keyFromHex(s, "kxprv:", KxPrivateLength, "key exchange private key")
+}
+
+// MustKxPrivateFromHex is like KxPrivateFromHex, but panics on error.
+func MustKxPrivateFromHex(s string) KxPrivate {
+	return mustKey(KxPrivateFromHex(s))
+}
+
+// Equal reports whether two key exchange public keys are the same.
+func (k KxPublic) Equal(other KxPublic) bool {
+	return MemCmp(k, other)
+}
+
+// Clone returns a copy of the key with its own backing array.
+func (k KxPublic) Clone() KxPublic {
+	return append(KxPublic(nil), k...)
+}
+
+// Equal reports whether two key exchange private keys are the same, in
+// constant time.
+func (k KxPrivate) Equal(other KxPrivate) bool {
+	return MemCmp(k, other)
+}
+
+// Clone returns an independent copy of the private key.
+func (k KxPrivate) Clone() KxPrivate {
+	return append(KxPrivate(nil), k...)
+}
+
+// KxGenerateKey generates a key exchange private key.
+func KxGenerateKey() KxPrivate {
+	mustInit()
+	// crypto_kx_keypair also just draws random bytes, but would bypass
+	// SetRandSource
+	priv := make([]byte, KxPrivateLength)
+	RandBytes(priv)
+	return priv
+}
+
+// PublicKey derives the public key corresponding to the key exchange private key.
+func (k KxPrivate) PublicKey() KxPublic {
+	toret := make([]byte, KxPublicLength)
+	rv := C.crypto_scalarmult_base(g2cbt(toret), g2cbt(k))
+	if rv != 0 {
+		panic("crypto_scalarmult_base returned non-zero")
+	}
+	return toret
+}
+
+// Destroy wipes the private key from memory, like EdDSAPrivate.Destroy.
+func (k KxPrivate) Destroy() {
+	wipe(k)
+}
+
+// ClientSessionKeys computes the session keys for the client side of a key
+// exchange with the given server. The client's rx key equals the server's tx
+// key and vice versa.
+func (k KxPrivate) ClientSessionKeys(serverpub KxPublic) (rx, tx []byte, err error) {
+	if len(k) != KxPrivateLength || len(serverpub) != KxPublicLength {
+		return nil, nil, fmt.Errorf("%w: key exchange key", ErrInvalidKeyLength)
+	}
+	rx = make([]byte, KxSessionKeyLength)
+	tx = make([]byte, KxSessionKeyLength)
+	rv := C.crypto_kx_client_session_keys(g2cbt(rx), g2cbt(tx),
+		g2cbt(k.PublicKey()), g2cbt(k), g2cbt(serverpub))
+	if rv != 0 {
+		return nil, nil, errors.New("server public key rejected")
+	}
+	return
+}
+
+// ServerSessionKeys computes the session keys for the server side of a key
+// exchange with the given client.
+func (k KxPrivate) ServerSessionKeys(clientpub KxPublic) (rx, tx []byte, err error) {
+	if len(k) != KxPrivateLength || len(clientpub) != KxPublicLength {
+		return nil, nil, fmt.Errorf("%w: key exchange key", ErrInvalidKeyLength)
+	}
+	rx = make([]byte, KxSessionKeyLength)
+	tx = make([]byte, KxSessionKeyLength)
+	rv := C.crypto_kx_server_session_keys(g2cbt(rx), g2cbt(tx),
+		g2cbt(k.PublicKey()), g2cbt(k), g2cbt(clientpub))
+	if rv != 0 {
+		return nil, nil, errors.New("client public key rejected")
+	}
+	return
+}
+
+// EphemeralClientKeys performs the client side of a key exchange with a fresh
+// key pair that is destroyed straight away, for sending to a server without
+// identifying the sender. The ephemeral public key must be sent along so the
+// server can call ServerSessionKeysFor.
+func EphemeralClientKeys(serverpub KxPublic) (ephemeralPub KxPublic, rx, tx []byte, err error) {
+	ephemeral := KxGenerateKey()
+	defer ephemeral.Destroy()
+	rx, tx, err = ephemeral.ClientSessionKeys(serverpub)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return ephemeral.PublicKey(), rx, tx, nil
+}
+
+// ServerSessionKeysFor is the server side of EphemeralClientKeys. It is the
+// same as serverPriv.ServerSessionKeys(clientEphemeralPub).
+func ServerSessionKeysFor(serverPriv KxPrivate, clientEphemeralPub KxPublic) (rx, tx []byte, err error) {
+	return serverPriv.ServerSessionKeys(clientEphemeralPub)
+}
+
+// GobEncode implements the gob.GobEncoder interface.
+func (k KxPublic) GobEncode() ([]byte, error) {
+	return encodeKeyGob(gobKxPublic, k)
+}
+
+// GobDecode implements the gob.GobDecoder interface, rejecting anything that
+// is not a key exchange public key of the right length.
+func (k *KxPublic) GobDecode(data []byte) error {
+	raw, err := decodeKeyGob(data, gobKxPublic, KxPublicLength, "key exchange public key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// GobEncode implements the gob.GobEncoder interface.
+func (k KxPrivate) GobEncode() ([]byte, error) {
+	return encodeKeyGob(gobKxPrivate, k)
+}
+
+// GobDecode implements the gob.GobDecoder interface, rejecting anything that
+// is not a key exchange private key of the right length.
+func (k *KxPrivate) GobDecode(data []byte) error {
+	raw, err := decodeKeyGob(data, gobKxPrivate, KxPrivateLength, "key exchange private key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, returning a
+// copy of the raw key.
+func (k KxPublic) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), k...), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. The
+// data is length-checked and copied, never aliased.
+func (k *KxPublic) UnmarshalBinary(data []byte) error {
+	raw, err := copyKey(data, KxPublicLength, "key exchange public key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, returning a
+// copy of the raw key.
+func (k KxPrivate) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), k...), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. The
+// data is length-checked and copied, never aliased.
+func (k *KxPrivate) UnmarshalBinary(data []byte) error {
+	raw, err := copyKey(data, KxPrivateLength, "key exchange private key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, encoding the
+// key as lowercase hex.
+func (k KxPublic) MarshalText() ([]byte, error) {
+	return []byte(BinToHex(k)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, reversing
+// MarshalText.
+func (k *KxPublic) UnmarshalText(text []byte) error {
+	raw, err := unmarshalKeyText(text, KxPublicLength, "key exchange public key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, encoding the
+// key as lowercase hex.
+func (k KxPrivate) MarshalText() ([]byte, error) {
+	return []byte(BinToHex(k)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, reversing
+// MarshalText.
+func (k *KxPrivate) UnmarshalText(text []byte) error {
+	raw, err := unmarshalKeyText(text, KxPrivateLength, "key exchange private key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// PEM block types used by Encode with EncodingPEM.
+const (
+	KxPublicPEMType  = "NATRIUM KX PUBLIC KEY"
+	KxPrivatePEMType = "NATRIUM KX PRIVATE KEY"
+)
+
+// Encode serializes the public key in the given encoding, as raw bytes, hex,
+// PEM or base64. PEM blocks have type KxPublicPEMType.
+func (k KxPublic) Encode(enc Encoding) ([]byte, error) {
+	return encodeKey(k, enc, KxPublicPEMType, KxPublicLength, "key exchange public key")
+}
+
+// DecodeKxPublic parses a public key written by Encode with the same encoding.
+// Data of the wrong length, or a PEM block of another type, gives an error.
+func DecodeKxPublic(data []byte, enc Encoding) (KxPublic, error) {
+	return decodeKey(data, enc, KxPublicPEMType, KxPublicLength, "key exchange public key")
+}
+
+// Encode serializes the private key in the given encoding, like
+// KxPublic.Encode. Every encoding holds the secret in the clear.
+func (k KxPrivate) Encode(enc Encoding) ([]byte, error) {
+	return encodeKey(k, enc, KxPrivatePEMType, KxPrivateLength, "key exchange private key")
+}
+
+// DecodeKxPrivate parses a private key written by Encode with the same
+// encoding.
+func DecodeKxPrivate(data []byte, enc Encoding) (KxPrivate, error) {
+	return decodeKey(data, enc, KxPrivatePEMType, KxPrivateLength, "key exchange private key")
+}
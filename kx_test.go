@@ -0,0 +1,55 @@
+package natrium
+
+import "testing"
+
+func TestKx(t *testing.T) {
+	client := KxGenerateKey()
+	server := KxGenerateKey()
+	crx, ctx, err := client.ClientSessionKeys(server.PublicKey())
+	if err != nil {
+		t.FailNow()
+	}
+	srx, stx, err := server.ServerSessionKeys(client.PublicKey())
+	if err != nil {
+		t.FailNow()
+	}
+	if CTCompare(crx, stx) != 0 || CTCompare(ctx, srx) != 0 || CTCompare(crx, ctx) == 0 {
+		t.FailNow()
+	}
+	if _, _, err := client.ClientSessionKeys(make([]byte, KxPublicLength)); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestEphemeralKx(t *testing.T) {
+	server := KxGenerateKey()
+	ephemeral, crx, ctx, err := EphemeralClientKeys(server.PublicKey())
+	if err != nil {
+		t.FailNow()
+	}
+	srx, stx, err := ServerSessionKeysFor(server, ephemeral)
+	if err != nil || !MemCmp(crx, stx) || !MemCmp(ctx, srx) {
+		t.FailNow()
+	}
+	if _, _, _, err := EphemeralClientKeys(server.PublicKey()[:5]); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestKxEncode(t *testing.T) {
+	priv := KxGenerateKey()
+	publ := priv.PublicKey()
+	checkEncoding(t, []encodingCase{
+		{publ, KxPublicPEMType, publ.Encode, func(b []byte, e Encoding) ([]byte, error) { return DecodeKxPublic(b, e) }},
+		{priv, KxPrivatePEMType, priv.Encode, func(b []byte, e Encoding) ([]byte, error) { return DecodeKxPrivate(b, e) }},
+	})
+}
+
+func TestKxGob(t *testing.T) {
+	priv := KxGenerateKey()
+	publ := priv.PublicKey()
+	var gotPubl KxPublic
+	checkGob(t, publ, publ, &gotPubl, func() []byte { return gotPubl })
+	var gotPriv KxPrivate
+	checkGob(t, priv, priv, &gotPriv, func() []byte { return gotPriv })
+}
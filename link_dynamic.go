@@ -0,0 +1,12 @@
+//go:build natrium_dynamic
+// +build natrium_dynamic
+
+package natrium
+
+// With the natrium_dynamic build tag, libsodium is linked as a shared library,
+// for macOS setups and distributions that do not ship libsodium.a. Only the
+// link flags differ from the default in link_static.go; the code is the same.
+
+// #cgo darwin LDFLAGS: -L/usr/local/lib -lsodium
+// #cgo linux windows android LDFLAGS: -lsodium
+import "C"
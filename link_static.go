@@ -0,0 +1,13 @@
+//go:build !natrium_dynamic
+// +build !natrium_dynamic
+
+package natrium
+
+// By default libsodium is linked statically, so that programs using natrium
+// do not depend on the version of libsodium installed where they run. Build
+// with the natrium_dynamic tag, handled in link_dynamic.go, where only a
+// shared libsodium is available.
+
+// #cgo darwin LDFLAGS: /usr/local/lib/libsodium.a
+// #cgo linux windows android LDFLAGS: -Wl,-Bstatic -lsodium -Wl,-Bdynamic
+import "C"
@@ -1,27 +1,64 @@
 package natrium
 
 import (
-	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"unsafe"
 )
 
 // #cgo darwin CFLAGS: -I/usr/local/include
-// #cgo darwin LDFLAGS: /usr/local/lib/libsodium.a
-// #cgo linux windows android LDFLAGS: -Wl,-Bstatic -lsodium -Wl,-Bdynamic
 // #include <stdio.h>
 // #include <sodium.h>
 import "C"
 
-// TODO use the sodium encode and decode
-
 // HexEncode encodes a byte array to a hexadecimal string.
 func HexEncode(bts []byte) string {
-	return hex.EncodeToString(bts)
+	return BinToHex(bts)
 }
 
 // HexDecode decodes a hexadecimal string to a byte array.
 func HexDecode(str string) ([]byte, error) {
-	return hex.DecodeString(str)
+	return HexToBin(str)
+}
+
+// BinToHex encodes a byte array to a lowercase hexadecimal string, in time
+// independent of the data, which makes it suitable for secret material.
+func BinToHex(bin []byte) string {
+	out := make([]byte, len(bin)*2+1)
+	C.sodium_bin2hex(g2cst(out), C.size_t(len(out)), g2cbt(bin), C.size_t(len(bin)))
+	return string(out[:len(bin)*2])
+}
+
+// HexToBin decodes a hexadecimal string to a byte array in constant time,
+// returning an error if it contains anything but an even number of hex digits.
+func HexToBin(hex string) ([]byte, error) {
+	return HexToBinIgnore(hex, "")
+}
+
+// HexToBinIgnore is like HexToBin, but skips any of the characters in ignore
+// found between pairs of hex digits, so that for example "de:ad:be:ef" can be
+// decoded with an ignore set of ":".
+func HexToBinIgnore(hex string, ignore string) ([]byte, error) {
+	hexb := []byte(hex)
+	out := make([]byte, len(hexb)/2)
+	var ign *C.char
+	if ignore != "" {
+		ignb := append([]byte(ignore), 0)
+		ign = g2cst(ignb)
+	}
+	start := g2cst(hexb)
+	var outlen C.size_t
+	var end *C.char
+	rv := C.sodium_hex2bin(g2cbt(out), C.size_t(len(out)), start, C.size_t(len(hexb)),
+		ign, &outlen, &end)
+	if rv != 0 || consumed(start, end) != len(hexb) {
+		return nil, errors.New("invalid hex string")
+	}
+	return out[:outlen], nil
 }
 
 // CTCompare returns 0 if the two byte strings are identical, -1 if a is less than b (little-endian), and 1 if a is larger than b. It runs in constant time given a particular length of a and b.
@@ -32,6 +69,118 @@ func CTCompare(a []byte, b []byte) int {
 	return int(C.sodium_compare(g2cbt(a), g2cbt(b), C.size_t(len(a))))
 }
 
+// MemCmp reports whether a and b have the same length and contents. For
+// slices of equal length, it runs in time independent of their contents, so
+// it is the right way to compare MACs, tokens and other secrets. Unlike
+// CTCompare, it only reports equality and cannot be used to sort.
+func MemCmp(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	return C.sodium_memcmp(unsafe.Pointer(g2cbt(a)), unsafe.Pointer(g2cbt(b)), C.size_t(len(a))) == 0
+}
+
+// ConstantTimeSelect returns a copy of a if condition is 1 and of b if it is
+// 0, in time that depends only on the length of the inputs, for branch-free
+// code built on the scalar and point operations. It works like
+// crypto/subtle.ConstantTimeCopy; libsodium has no such primitive, so the
+// bytes are blended with a mask in Go. a and b must have the same length, and
+// condition must be 0 or 1, or ConstantTimeSelect panics.
+func ConstantTimeSelect(condition int, a, b []byte) []byte {
+	if len(a) != len(b) {
+		panic("unequal lengths passed to ConstantTimeSelect")
+	}
+	if condition != 0 && condition != 1 {
+		panic("ConstantTimeSelect condition must be 0 or 1")
+	}
+	mask := byte(-condition)
+	toret := make([]byte, len(a))
+	for i := range toret {
+		toret[i] = b[i] ^ (mask & (a[i] ^ b[i]))
+	}
+	return toret
+}
+
+// MemCmpVariable reports whether candidate equals secret, for comparing a
+// fixed secret against attacker-supplied data of any length. MemCmp returns
+// early on a length mismatch, so probing it with candidates of different
+// lengths reveals len(secret); MemCmpVariable does not.
+//
+// Both inputs are hashed to 32 bytes with BLAKE2b, keyed with 32 random bytes
+// drawn afresh for every call, and the two digests are compared with MemCmp.
+// The running time thus depends only on the two lengths, never on the
+// contents or on where they differ: the time spent on secret is the same for
+// every candidate, and the time spent on candidate depends on nothing but
+// what the caller already knows. Because the key is random and secret,
+// digests cannot be precomputed, and different inputs collide only if
+// BLAKE2b is broken.
+func MemCmpVariable(secret, candidate []byte) bool {
+	key := make([]byte, 32)
+	RandBytes(key)
+	defer wipe(key)
+	a := genericHash(secret, key, 32)
+	b := genericHash(candidate, key, 32)
+	defer wipe(a)
+	return MemCmp(a, b)
+}
+
+// Increment treats nonce as a little-endian number and adds one to it in
+// constant time, wrapping around to zero on overflow. It is meant for
+// advancing counter nonces.
+func Increment(nonce []byte) {
+	C.sodium_increment(g2cbt(nonce), C.size_t(len(nonce)))
+}
+
+// Nonce is a nonce of whatever length a primitive needs. Its methods never
+// modify it in place, so a Nonce can be handed around without worrying about
+// someone else advancing it.
+type Nonce []byte
+
+// RandomNonce returns a random nonce of the given length, such as
+// SecretBoxNonceLength or AEADNonceLength.
+func RandomNonce(length int) Nonce {
+	return RandomBytes(length)
+}
+
+// Next returns the nonce that follows n, as if by Increment. The receiver is
+// left untouched.
+func (n Nonce) Next() Nonce {
+	toret := append(Nonce(nil), n...)
+	Increment(toret)
+	return toret
+}
+
+// Bytes returns a copy of the nonce, ready to pass to Seal or Open.
+func (n Nonce) Bytes() []byte {
+	return append([]byte(nil), n...)
+}
+
+// Add treats a and b as little-endian numbers of the same length and sets a to
+// their sum in constant time, wrapping around on overflow.
+func Add(a, b []byte) {
+	if len(a) != len(b) {
+		panic("unequal lengths passed to Add")
+	}
+	C.sodium_add(g2cbt(a), g2cbt(b), C.size_t(len(a)))
+}
+
+// Compare treats a and b as little-endian numbers of the same length and
+// returns -1, 0 or 1 in constant time, like CTCompare.
+func Compare(a, b []byte) int {
+	return CTCompare(a, b)
+}
+
+// wipe zeroes b in a way the compiler cannot optimize away.
+func wipe(b []byte) {
+	C.sodium_memzero(unsafe.Pointer(g2cbt(b)), C.size_t(len(b)))
+}
+
+// isZero reports, in constant time, whether b is all zeros, as it is after
+// being wiped.
+func isZero(b []byte) bool {
+	return C.sodium_is_zero(g2cbt(b), C.size_t(len(b))) == 1
+}
+
 func g2cbt(f []byte) *C.uchar {
 	if len(f) > 0 {
 		return (*C.uchar)(&f[0])
@@ -46,6 +195,290 @@ func g2cst(f []byte) *C.char {
 	return nil
 }
 
+var (
+	initOnce sync.Once
+	initErr  error
+)
+
+// Init initializes libsodium, returning an error if it cannot be. It is
+// called automatically when the package is loaded, which panics on failure,
+// but calling it again is harmless and gives explicit confirmation that the
+// library is usable. Only the first call does any work; it is safe to call
+// from several goroutines at once.
+func Init() error {
+	initOnce.Do(func() {
+		// the key and signature lengths are compile-time constants, so
+		// there is nothing else left to check here
+		if C.sodium_init() < 0 {
+			initErr = errors.New("libsodium could not be initialized")
+		}
+	})
+	return initErr
+}
+
+// mustInit panics if the package could not be initialized, so that keys are
+// never generated by an unusable library.
+func mustInit() {
+	if err := Init(); err != nil {
+		panic("natrium: " + err.Error())
+	}
+}
+
 func init() {
-	C.sodium_init()
+	mustInit()
+}
+
+func unmarshalKeyJSON(data []byte, length int, what string) ([]byte, error) {
+	var raw []byte
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != length {
+		return nil, keyLengthError(what, len(raw), length)
+	}
+	return raw, nil
+}
+
+// copyKey returns a private copy of b after checking that it is length bytes
+// long, for the New* key constructors.
+func copyKey(b []byte, length int, what string) ([]byte, error) {
+	if len(b) != length {
+		return nil, keyLengthError(what, len(b), length)
+	}
+	return append([]byte(nil), b...), nil
+}
+
+// parseKeyPEM decodes the first PEM block in data, checking its type and the
+// length of the key it holds.
+func parseKeyPEM(data []byte, blockType string, length int, what string) ([]byte, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found while looking for %v", what)
+	}
+	if block.Type != blockType {
+		return nil, fmt.Errorf("PEM block has type %q, expected %q", block.Type, blockType)
+	}
+	return copyKey(block.Bytes, length, what)
+}
+
+// Encoding selects one of the serializations that the Encode methods and
+// Decode functions of keys support.
+type Encoding int
+
+const (
+	// EncodingRaw is the key bytes themselves.
+	EncodingRaw Encoding = iota
+	// EncodingHex is lowercase hex without a prefix, as written by
+	// MarshalText.
+	EncodingHex
+	// EncodingPEM is a PEM block of the type specific to the kind of key, as
+	// written by MarshalPEM.
+	EncodingPEM
+	// EncodingBase64 is standard base64 with padding.
+	EncodingBase64
+)
+
+// String returns the name of the encoding, for error messages and logs.
+func (e Encoding) String() string {
+	switch e {
+	case EncodingRaw:
+		return "raw"
+	case EncodingHex:
+		return "hex"
+	case EncodingPEM:
+		return "PEM"
+	case EncodingBase64:
+		return "base64"
+	}
+	return fmt.Sprintf("Encoding(%d)", int(e))
+}
+
+// encodeKey serializes k, which must be length bytes long, for the Encode
+// methods. PEM blocks get the type pemType.
+func encodeKey(k []byte, enc Encoding, pemType string, length int, what string) ([]byte, error) {
+	if len(k) != length {
+		return nil, keyLengthError(what, len(k), length)
+	}
+	switch enc {
+	case EncodingRaw:
+		return append([]byte(nil), k...), nil
+	case EncodingHex:
+		return []byte(BinToHex(k)), nil
+	case EncodingPEM:
+		return pem.EncodeToMemory(&pem.Block{Type: pemType, Bytes: k}), nil
+	case EncodingBase64:
+		return []byte(BinToBase64(k, Base64Original)), nil
+	}
+	return nil, fmt.Errorf("unknown key encoding %v", enc)
+}
+
+// decodeKey reverses encodeKey, checking the PEM type and the length of the
+// key in one place for every encoding.
+func decodeKey(data []byte, enc Encoding, pemType string, length int, what string) ([]byte, error) {
+	switch enc {
+	case EncodingRaw:
+		return copyKey(data, length, what)
+	case EncodingHex:
+		return unmarshalKeyText(data, length, what)
+	case EncodingPEM:
+		return parseKeyPEM(data, pemType, length, what)
+	case EncodingBase64:
+		raw, err := Base64ToBin(string(data), Base64Original)
+		if err != nil {
+			return nil, fmt.Errorf("%v is not valid base64", what)
+		}
+		defer wipe(raw)
+		return copyKey(raw, length, what)
+	}
+	return nil, fmt.Errorf("unknown key encoding %v", enc)
+}
+
+// unmarshalKeyText decodes a key written as hex by a MarshalText method.
+func unmarshalKeyText(text []byte, length int, what string) ([]byte, error) {
+	raw, err := HexToBin(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("%v is not a valid hex string", what)
+	}
+	return copyKey(raw, length, what)
+}
+
+// keyFromHex decodes a hex key for the *FromHex constructors, after removing
+// prefix if s starts with it.
+func keyFromHex(s, prefix string, length int, what string) ([]byte, error) {
+	return unmarshalKeyText([]byte(strings.TrimPrefix(s, prefix)), length, what)
+}
+
+// redacted is what String returns for a secret key: its kind and length, but
+// none of its bytes.
+func redacted(prefix string, k []byte) string {
+	return fmt.Sprintf("%v:<redacted,len=%v>", prefix, len(k))
+}
+
+// mustKey panics if err is not nil, for the Must*FromHex constructors.
+func mustKey(k []byte, err error) []byte {
+	if err != nil {
+		panic("natrium: " + err.Error())
+	}
+	return k
+}
+
+// Tags identifying the kind of key in a gob encoding, so that a key of one
+// kind is never decoded as another.
+const (
+	gobEdDSAPublic byte = iota + 1
+	gobEdDSAPrivate
+	gobBoxPublic
+	gobBoxPrivate
+	gobECDHPublic
+	gobECDHPrivate
+	gobKxPublic
+	gobKxPrivate
+	gobAEADKey
+	gobAES256GCMKey
+	gobMasterKey
+	gobSecretKey
+	gobSecretStreamKey
+	gobXChaChaBoxPublic
+	gobXChaChaBoxPrivate
+)
+
+func encodeKeyGob(tag byte, k []byte) ([]byte, error) {
+	return append([]byte{tag}, k...), nil
+}
+
+func decodeKeyGob(data []byte, tag byte, length int, what string) ([]byte, error) {
+	if len(data) == 0 || data[0] != tag {
+		return nil, fmt.Errorf("gob data is not an encoded %v", what)
+	}
+	return copyKey(data[1:], length, what)
+}
+
+// Base64Variant selects one of the base64 variants libsodium implements. The
+// only valid values are the four constants below.
+type Base64Variant int
+
+// Variants of base64 accepted by BinToBase64 and Base64ToBin.
+const (
+	Base64Original          Base64Variant = C.sodium_base64_VARIANT_ORIGINAL
+	Base64OriginalNoPadding Base64Variant = C.sodium_base64_VARIANT_ORIGINAL_NO_PADDING
+	Base64URLSafe           Base64Variant = C.sodium_base64_VARIANT_URLSAFE
+	Base64URLSafeNoPadding  Base64Variant = C.sodium_base64_VARIANT_URLSAFE_NO_PADDING
+)
+
+// valid reports whether v is one of the four variants; libsodium aborts the
+// process when given anything else.
+func (v Base64Variant) valid() bool {
+	switch v {
+	case Base64Original, Base64OriginalNoPadding, Base64URLSafe, Base64URLSafeNoPadding:
+		return true
+	}
+	return false
+}
+
+// BinToBase64 encodes a byte array to a base64 string of the given variant,
+// in time independent of the data. It panics if variant is not one of the
+// Base64Variant constants.
+func BinToBase64(bin []byte, variant Base64Variant) string {
+	if !variant.valid() {
+		panic(fmt.Sprintf("invalid base64 variant %v", int(variant)))
+	}
+	out := make([]byte, C.sodium_base64_encoded_len(C.size_t(len(bin)), C.int(variant)))
+	C.sodium_bin2base64(g2cst(out), C.size_t(len(out)), g2cbt(bin), C.size_t(len(bin)),
+		C.int(variant))
+	return cstring(out)
+}
+
+// Base64ToBin decodes a base64 string of the given variant. Any character
+// outside the variant's alphabet, a wrong padding, non-zero leftover bits, or
+// trailing garbage causes an error, and the error is the same whatever went
+// wrong. A variant that is not one of the Base64Variant constants gives an
+// error of its own.
+func Base64ToBin(s string, variant Base64Variant) ([]byte, error) {
+	if !variant.valid() {
+		return nil, fmt.Errorf("invalid base64 variant %v", int(variant))
+	}
+	b64 := []byte(s)
+	out := make([]byte, len(b64)/4*3+3)
+	start := g2cst(b64)
+	var outlen C.size_t
+	var end *C.char
+	rv := C.sodium_base642bin(g2cbt(out), C.size_t(len(out)), start, C.size_t(len(b64)),
+		nil, &outlen, &end, C.int(variant))
+	if rv != 0 || consumed(start, end) != len(b64) {
+		return nil, errors.New("invalid base64 string")
+	}
+	return out[:outlen], nil
+}
+
+// BinToBase64URL encodes a byte array to unpadded URL-safe base64, the usual
+// form for tokens in URLs and HTTP headers.
+func BinToBase64URL(bin []byte) string {
+	return BinToBase64(bin, Base64URLSafeNoPadding)
+}
+
+// Base64URLToBin decodes a string produced by BinToBase64URL. Padding and the
+// '+' and '/' characters of standard base64 are rejected.
+func Base64URLToBin(s string) ([]byte, error) {
+	return Base64ToBin(s, Base64URLSafeNoPadding)
+}
+
+// fingerprint hashes a tag and a public key and formats the result with a
+// colon between every byte.
+func fingerprint(tag string, k []byte) string {
+	sum := GenericHash(append([]byte(tag), k...), GenericHashBytesMin)
+	hex := BinToHex(sum)
+	var sb strings.Builder
+	for i := 0; i < len(hex); i += 2 {
+		if i > 0 {
+			sb.WriteByte(':')
+		}
+		sb.WriteString(hex[i : i+2])
+	}
+	return sb.String()
+}
+
+// consumed returns how far a C parser advanced from start to end.
+func consumed(start, end *C.char) int {
+	return int(uintptr(unsafe.Pointer(end)) - uintptr(unsafe.Pointer(start)))
 }
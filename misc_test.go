@@ -0,0 +1,346 @@
+package natrium
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestHex(t *testing.T) {
+	bin := []byte{0xde, 0xad, 0xbe, 0xef}
+	if BinToHex(bin) != "deadbeef" || BinToHex(nil) != "" {
+		t.FailNow()
+	}
+	decoded, err := HexToBin("DEADbeef")
+	if err != nil || CTCompare(decoded, bin) != 0 {
+		t.FailNow()
+	}
+	decoded, err = HexToBinIgnore("de:ad:be:ef", ":")
+	if err != nil || CTCompare(decoded, bin) != 0 {
+		t.FailNow()
+	}
+	for _, bad := range []string{"dea", "deadbeeg", "de:ad"} {
+		if _, err := HexToBin(bad); err == nil {
+			t.FailNow()
+		}
+	}
+	if decoded, err := HexToBin(""); err != nil || len(decoded) != 0 {
+		t.FailNow()
+	}
+}
+
+func TestBase64URL(t *testing.T) {
+	bin := []byte{0xfb, 0xff, 0x01, 0x02}
+	if BinToBase64URL(bin) != "-_8BAg" {
+		t.FailNow()
+	}
+	got, err := Base64URLToBin("-_8BAg")
+	if err != nil || BinToHex(got) != "fbff0102" {
+		t.FailNow()
+	}
+	for _, bad := range []string{"+/8BAg", "-_8BAg==", "-_8BA g"} {
+		if _, err := Base64URLToBin(bad); err == nil {
+			t.FailNow()
+		}
+	}
+}
+
+func TestBase64(t *testing.T) {
+	bin := []byte{0xfb, 0xff, 0x01}
+	if BinToBase64(bin, Base64Original) != "+/8B" || BinToBase64(bin, Base64URLSafe) != "-_8B" {
+		t.FailNow()
+	}
+	if BinToBase64([]byte("a"), Base64Original) != "YQ==" ||
+		BinToBase64([]byte("a"), Base64OriginalNoPadding) != "YQ" {
+		t.FailNow()
+	}
+	decoded, err := Base64ToBin("-_8B", Base64URLSafeNoPadding)
+	if err != nil || CTCompare(decoded, bin) != 0 {
+		t.FailNow()
+	}
+	if _, err := Base64ToBin("+/8B", Base64URLSafe); err == nil {
+		t.FailNow()
+	}
+	if _, err := Base64ToBin("YQ", Base64Original); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestBase64Strict(t *testing.T) {
+	bad := map[string]Base64Variant{
+		"YW*j":      Base64Original,
+		"YW\x00j":   Base64Original,
+		"YWJj\n":    Base64Original,
+		"YQ=":       Base64Original,
+		"YQ===":     Base64Original,
+		"YQ==YQ==":  Base64Original,
+		"YR==":      Base64Original,
+		"YWJjZA==x": Base64Original,
+		"YQ==":      Base64OriginalNoPadding,
+		"YWJj-":     Base64URLSafeNoPadding,
+		"YWJjZ":     Base64URLSafeNoPadding,
+	}
+	for s, variant := range bad {
+		if _, err := Base64ToBin(s, variant); err == nil {
+			t.FailNow()
+		}
+	}
+	long := BinToBase64(RandomBytes(1000), Base64Original)
+	if decoded, err := Base64ToBin(long, Base64Original); err != nil || len(decoded) != 1000 {
+		t.FailNow()
+	}
+	if _, err := Base64ToBin(long+"AAAA=", Base64Original); err == nil {
+		t.FailNow()
+	}
+	if decoded, err := Base64ToBin("", Base64Original); err != nil || len(decoded) != 0 {
+		t.FailNow()
+	}
+	// libsodium aborts on other variants, so they must never reach it
+	for _, v := range []Base64Variant{0, 2, 8, -1} {
+		if _, err := Base64ToBin("YQ==", v); err == nil {
+			t.FailNow()
+		}
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.FailNow()
+				}
+			}()
+			BinToBase64([]byte("a"), v)
+		}()
+	}
+}
+
+func TestMemCmp(t *testing.T) {
+	if !MemCmp([]byte("abc"), []byte("abc")) || !MemCmp(nil, []byte{}) {
+		t.FailNow()
+	}
+	if MemCmp([]byte("abc"), []byte("abd")) || MemCmp([]byte("abc"), []byte("ab")) {
+		t.FailNow()
+	}
+}
+
+func TestConstantTimeSelect(t *testing.T) {
+	a, b := []byte{1, 2, 3, 0xff}, []byte{0xf0, 0, 3, 4}
+	if !MemCmp(ConstantTimeSelect(1, a, b), a) || !MemCmp(ConstantTimeSelect(0, a, b), b) {
+		t.FailNow()
+	}
+	got := ConstantTimeSelect(1, a, b)
+	got[0] = 9
+	if a[0] != 1 || len(ConstantTimeSelect(0, nil, nil)) != 0 {
+		t.FailNow()
+	}
+	for _, f := range []func(){
+		func() { ConstantTimeSelect(1, a, b[:3]) },
+		func() { ConstantTimeSelect(2, a, b) },
+		func() { ConstantTimeSelect(-1, a, b) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.FailNow()
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestMemCmpVariable(t *testing.T) {
+	secret := []byte("correct horse battery staple")
+	if !MemCmpVariable(secret, []byte("correct horse battery staple")) || !MemCmpVariable(nil, []byte{}) {
+		t.FailNow()
+	}
+	for _, candidate := range [][]byte{nil, secret[:1], secret[:len(secret)-1],
+		append(append([]byte(nil), secret...), 0), []byte("correct horse battery stapla")} {
+		if MemCmpVariable(secret, candidate) {
+			t.FailNow()
+		}
+	}
+}
+
+func TestIncrement(t *testing.T) {
+	nonce := []byte{0xff, 0x00, 0x00}
+	Increment(nonce)
+	if BinToHex(nonce) != "000100" {
+		t.FailNow()
+	}
+	nonce = []byte{0xff, 0xff, 0xff}
+	Increment(nonce)
+	if BinToHex(nonce) != "000000" {
+		t.FailNow()
+	}
+}
+
+func TestNonceNext(t *testing.T) {
+	nonce := RandomNonce(SecretBoxNonceLength)
+	orig := nonce.Bytes()
+	prev := nonce
+	for i := 0; i < 300; i++ {
+		next := prev.Next()
+		if Compare(next, prev) != 1 {
+			t.FailNow()
+		}
+		prev = next
+	}
+	if CTCompare(nonce, orig) != 0 {
+		t.FailNow()
+	}
+}
+
+func TestAddCompare(t *testing.T) {
+	a := []byte{0xff, 0x01}
+	Add(a, []byte{0x02, 0x00})
+	if BinToHex(a) != "0102" {
+		t.FailNow()
+	}
+	Add(a, []byte{0xff, 0xfe})
+	if BinToHex(a) != "0001" {
+		t.FailNow()
+	}
+	if Compare([]byte{0x02, 0x01}, []byte{0x01, 0x02}) != -1 ||
+		Compare([]byte{0x01, 0x02}, []byte{0x02, 0x01}) != 1 ||
+		Compare(a, a) != 0 {
+		t.FailNow()
+	}
+}
+
+func TestKeyText(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	box := BoxGenerateKey()
+	kx := KxGenerateKey()
+	ecdh := ECDHGenerateKey()
+	keys := []encoding.TextMarshaler{priv, priv.PublicKey(), box, box.PublicKey(),
+		kx, kx.PublicKey(), ecdh, ecdh.PublicKey(), GenerateSecretKey(),
+		GenerateAEADKey(), GenerateSecretStreamKey(), GenerateMasterKey()}
+	decoded := []encoding.TextUnmarshaler{new(EdDSAPrivate), new(EdDSAPublic),
+		new(BoxPrivate), new(BoxPublic), new(KxPrivate), new(KxPublic),
+		new(ECDHPrivate), new(ECDHPublic), new(SecretKey), new(AEADKey),
+		new(SecretStreamKey), new(MasterKey)}
+	for i, key := range keys {
+		text, err := key.MarshalText()
+		if err != nil {
+			t.FailNow()
+		}
+		if decoded[i].UnmarshalText(text) != nil {
+			t.FailNow()
+		}
+		again, _ := decoded[i].(encoding.TextMarshaler).MarshalText()
+		if string(again) != string(text) {
+			t.FailNow()
+		}
+		if decoded[i].UnmarshalText(text[1:]) == nil {
+			t.FailNow()
+		}
+		if decoded[i].UnmarshalText(append([]byte("zz"), text[2:]...)) == nil {
+			t.FailNow()
+		}
+	}
+}
+
+func TestInit(t *testing.T) {
+	if Init() != nil || Init() != nil {
+		t.FailNow()
+	}
+	done := make(chan error)
+	for i := 0; i < 4; i++ {
+		go func() { done <- Init() }()
+	}
+	for i := 0; i < 4; i++ {
+		if <-done != nil {
+			t.FailNow()
+		}
+	}
+}
+
+func TestSecretKeysRedacted(t *testing.T) {
+	gcm, _ := NewAES256GCMKey(RandomBytes(AES256GCMKeyLength))
+	box := BoxGenerateKey()
+	secrets := []interface {
+		fmt.Stringer
+		fmt.Formatter
+	}{
+		EdDSAGenerateKey(), box, KxGenerateKey(), ECDHGenerateKey(),
+		Precompute(box, BoxGenerateKey().PublicKey()), GenerateAEADKey(), gcm,
+		GenerateSecretKey(), GenerateMasterKey(), GenerateSecretStreamKey(),
+		XChaChaBoxPrivate(box), XChaChaBoxPrecompute(XChaChaBoxPrivate(box), XChaChaBoxGenerateKey().PublicKey()),
+	}
+	for _, k := range secrets {
+		for _, verb := range []string{"%v", "%x", "%d", "%#v"} {
+			if fmt.Sprintf(verb, k) != k.String() {
+				t.FailNow()
+			}
+		}
+	}
+}
+
+// encodingCase is a key with its Encode method and Decode function, for
+// checkEncoding.
+type encodingCase struct {
+	key     []byte
+	pemType string
+	encode  func(Encoding) ([]byte, error)
+	decode  func([]byte, Encoding) ([]byte, error)
+}
+
+// checkEncoding round-trips every key through every Encoding, and checks that
+// malformed data, and PEM blocks of any other case's type, are rejected.
+func checkEncoding(t *testing.T, cases []encodingCase) {
+	t.Helper()
+	for i, k := range cases {
+		for _, enc := range []Encoding{EncodingRaw, EncodingHex, EncodingPEM, EncodingBase64} {
+			data, err := k.encode(enc)
+			if err != nil {
+				t.FailNow()
+			}
+			got, err := k.decode(data, enc)
+			if err != nil || !bytes.Equal(got, k.key) {
+				t.FailNow()
+			}
+			if enc != EncodingRaw && bytes.Equal(data, k.key) {
+				t.FailNow()
+			}
+		}
+		data, _ := k.encode(EncodingPEM)
+		if !bytes.Contains(data, []byte(k.pemType)) {
+			t.FailNow()
+		}
+		for j, other := range cases {
+			if _, err := other.decode(data, EncodingPEM); j != i && err == nil {
+				t.FailNow()
+			}
+		}
+		if _, err := k.encode(Encoding(99)); err == nil {
+			t.FailNow()
+		}
+		if _, err := k.decode(k.key[1:], EncodingRaw); !errors.Is(err, ErrInvalidKeyLength) {
+			t.FailNow()
+		}
+		if _, err := k.decode([]byte(BinToBase64(k.key[1:], Base64Original)), EncodingBase64); !errors.Is(err, ErrInvalidKeyLength) {
+			t.FailNow()
+		}
+		if _, err := k.decode([]byte("not base64!"), EncodingBase64); err == nil {
+			t.FailNow()
+		}
+	}
+}
+
+// checkGob sends key through a gob stream into out, which decoded must then
+// return, and checks that out refuses a key of the same length with another
+// tag.
+func checkGob(t *testing.T, key interface{}, raw []byte, out gob.GobDecoder, decoded func() []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	if gob.NewEncoder(&buf).Encode(key) != nil || gob.NewDecoder(&buf).Decode(out) != nil {
+		t.FailNow()
+	}
+	if !bytes.Equal(decoded(), raw) {
+		t.FailNow()
+	}
+	if out.GobDecode(append([]byte{0xff}, raw...)) == nil || out.GobDecode(nil) == nil {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,84 @@
+package natrium
+
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #include <stdio.h>
+// #include <sodium.h>
+import "C"
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// multiSlotLength is the size of one recipient's sealed copy of the payload
+// key in a SealMulti blob.
+const multiSlotLength = BoxSealOverhead + SecretBoxKeyLength
+
+// SealMultiMaxRecipients is the largest number of recipients SealMulti
+// accepts.
+const SealMultiMaxRecipients = 0xffff
+
+// SealMulti anonymously encrypts one message to several recipients at once.
+// The message is encrypted only once, under a fresh random SecretKey, and that
+// key is then sealed to every recipient with BoxPublic.Seal. The blob is laid
+// out as follows, with no other framing:
+//
+//	2 bytes    number of recipients n, big-endian
+//	n*80 bytes for each recipient, crypto_box_seal of the 32-byte key
+//	24 bytes   secretbox nonce
+//	rest       crypto_secretbox_easy of the message under the key and nonce
+//
+// Slots carry no recipient identifiers, so the blob does not reveal who it is
+// for, beyond how many recipients there are; the price is that SealMultiOpen
+// must try each slot in turn. An error is returned if there are no recipients
+// or too many, or if a public key is malformed or a low-order point.
+func SealMulti(message []byte, recipients []BoxPublic) ([]byte, error) {
+	if len(recipients) == 0 || len(recipients) > SealMultiMaxRecipients {
+		return nil, fmt.Errorf("SealMulti needs between 1 and %v recipients", SealMultiMaxRecipients)
+	}
+	key := GenerateSecretKey()
+	defer key.Destroy()
+	toret := make([]byte, 2, 2+len(recipients)*multiSlotLength+SecretBoxNonceLength+
+		len(message)+SecretBoxMACLength)
+	binary.BigEndian.PutUint16(toret, uint16(len(recipients)))
+	for _, pk := range recipients {
+		if len(pk) != BoxPublicLength {
+			return nil, keyLengthError("box public key", len(pk), BoxPublicLength)
+		}
+		slot := make([]byte, multiSlotLength)
+		rv := C.crypto_box_seal(g2cbt(slot), g2cbt(key), C.ulonglong(len(key)), g2cbt(pk))
+		if rv != 0 {
+			return nil, ErrLowOrderPoint
+		}
+		toret = append(toret, slot...)
+	}
+	nonce := key.NewNonce()
+	toret = append(toret, nonce...)
+	return append(toret, key.Seal(message, nonce)...), nil
+}
+
+// SealMultiOpen decrypts a blob produced by SealMulti, provided priv belongs
+// to one of its recipients.
+func SealMultiOpen(blob []byte, priv BoxPrivate) ([]byte, error) {
+	if len(blob) < 2 {
+		return nil, fmt.Errorf("%w: multi-recipient box too short", ErrDecryptionFailed)
+	}
+	n := int(binary.BigEndian.Uint16(blob))
+	body := blob[2:]
+	if n == 0 || len(body) < n*multiSlotLength+SecretBoxNonceLength+SecretBoxMACLength {
+		return nil, fmt.Errorf("%w: multi-recipient box too short", ErrDecryptionFailed)
+	}
+	slots, body := body[:n*multiSlotLength], body[n*multiSlotLength:]
+	for i := 0; i < n; i++ {
+		key, err := priv.SealOpen(slots[i*multiSlotLength : (i+1)*multiSlotLength])
+		if err != nil {
+			continue
+		}
+		pt, err := SecretKey(key).Open(body[SecretBoxNonceLength:], body[:SecretBoxNonceLength])
+		wipe(key)
+		return pt, err
+	}
+	return nil, errNotARecipient
+}
+
+var errNotARecipient = errors.New("not a recipient of this multi-recipient box")
@@ -0,0 +1,48 @@
+package natrium
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestSealMulti(t *testing.T) {
+	privs := []BoxPrivate{BoxGenerateKey(), BoxGenerateKey(), BoxGenerateKey()}
+	var pubs []BoxPublic
+	for _, priv := range privs {
+		pubs = append(pubs, priv.PublicKey())
+	}
+	msg := []byte("to the group")
+	blob, err := SealMulti(msg, pubs)
+	if err != nil || len(blob) != 2+3*(BoxSealOverhead+32)+24+len(msg)+16 {
+		t.FailNow()
+	}
+	if binary.BigEndian.Uint16(blob) != 3 {
+		t.FailNow()
+	}
+	for _, priv := range privs {
+		pt, err := SealMultiOpen(blob, priv)
+		if err != nil || !bytes.Equal(pt, msg) {
+			t.FailNow()
+		}
+	}
+	if _, err := SealMultiOpen(blob, BoxGenerateKey()); err == nil {
+		t.FailNow()
+	}
+	blob[len(blob)-1] ^= 1
+	if _, err := SealMultiOpen(blob, privs[1]); err != ErrDecryptionFailed {
+		t.FailNow()
+	}
+	if _, err := SealMultiOpen(blob[:50], privs[1]); err == nil {
+		t.FailNow()
+	}
+	if _, err := SealMulti(msg, nil); err == nil {
+		t.FailNow()
+	}
+	if _, err := SealMulti(msg, []BoxPublic{make([]byte, 31)}); err == nil {
+		t.FailNow()
+	}
+	if _, err := SealMulti(msg, []BoxPublic{make([]byte, 32)}); err != ErrLowOrderPoint {
+		t.FailNow()
+	}
+}
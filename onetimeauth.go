@@ -0,0 +1,83 @@
+package natrium
+
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #include <stdio.h>
+// #include <sodium.h>
+import "C"
+import "unsafe"
+
+// OneTimeAuthKeyLength is the length of the key passed to OneTimeAuth.
+const OneTimeAuthKeyLength int = C.crypto_onetimeauth_KEYBYTES
+
+// OneTimeAuthTagLength is the length of the tag returned by OneTimeAuth.
+const OneTimeAuthTagLength int = C.crypto_onetimeauth_BYTES
+
+// OneTimeAuth computes a Poly1305 tag of a message.
+//
+// Each key must be used to authenticate EXACTLY ONE message: an attacker who
+// sees two tags made with the same key can forge tags for other messages.
+// Keys are typically derived freshly per message, say from a stream cipher.
+func OneTimeAuth(message, key []byte) []byte {
+	if len(key) != OneTimeAuthKeyLength {
+		panic("one-time auth key has the wrong length")
+	}
+	out := make([]byte, OneTimeAuthTagLength)
+	rv := C.crypto_onetimeauth(g2cbt(out), g2cbt(message), C.ulonglong(len(message)), g2cbt(key))
+	if rv != 0 {
+		panic("crypto_onetimeauth returned non-zero")
+	}
+	return out
+}
+
+// OneTimeAuthVerify checks, in constant time, that tag is the OneTimeAuth tag
+// of message under key.
+func OneTimeAuthVerify(tag, message, key []byte) bool {
+	if len(tag) != OneTimeAuthTagLength || len(key) != OneTimeAuthKeyLength {
+		return false
+	}
+	return C.crypto_onetimeauth_verify(g2cbt(tag), g2cbt(message), C.ulonglong(len(message)), g2cbt(key)) == 0
+}
+
+// OneTimeAuthState incrementally computes a OneTimeAuth tag. The same
+// single-use rule applies to its key. Use it through the pointer that
+// NewOneTimeAuthState returns; a copy of the struct shares the state of the
+// original rather than forking it.
+type OneTimeAuthState struct {
+	// libsodium needs the state aligned more strictly than Go guarantees, so
+	// it lives at an aligned offset inside an oversized buffer, allocated
+	// separately so that it stays aligned however the struct is copied
+	state *C.crypto_onetimeauth_state
+}
+
+// NewOneTimeAuthState starts computing a OneTimeAuth tag under key.
+func NewOneTimeAuthState(key []byte) *OneTimeAuthState {
+	if len(key) != OneTimeAuthKeyLength {
+		panic("one-time auth key has the wrong length")
+	}
+	buf := make([]byte, C.sizeof_crypto_onetimeauth_state+63)
+	off := int(-uintptr(unsafe.Pointer(&buf[0])) & 63)
+	toret := &OneTimeAuthState{(*C.crypto_onetimeauth_state)(unsafe.Pointer(&buf[off]))}
+	rv := C.crypto_onetimeauth_init(toret.state, g2cbt(key))
+	if rv != 0 {
+		panic("crypto_onetimeauth_init returned non-zero")
+	}
+	return toret
+}
+
+// Update adds more data to the message being authenticated.
+func (s *OneTimeAuthState) Update(b []byte) {
+	rv := C.crypto_onetimeauth_update(s.state, g2cbt(b), C.ulonglong(len(b)))
+	if rv != 0 {
+		panic("crypto_onetimeauth_update returned non-zero")
+	}
+}
+
+// Final returns the tag of everything passed to Update.
+func (s *OneTimeAuthState) Final() []byte {
+	out := make([]byte, OneTimeAuthTagLength)
+	rv := C.crypto_onetimeauth_final(s.state, g2cbt(out))
+	if rv != 0 {
+		panic("crypto_onetimeauth_final returned non-zero")
+	}
+	return out
+}
@@ -0,0 +1,26 @@
+package natrium
+
+import "testing"
+
+func TestOneTimeAuth(t *testing.T) {
+	key := RandomBytes(OneTimeAuthKeyLength)
+	message := RandomBytes(1000)
+	tag := OneTimeAuth(message, key)
+	if !OneTimeAuthVerify(tag, message, key) || OneTimeAuthVerify(tag, message[1:], key) {
+		t.FailNow()
+	}
+	state := NewOneTimeAuthState(key)
+	state.Update(message[:333])
+	state.Update(message[333:])
+	if CTCompare(state.Final(), tag) != 0 {
+		t.FailNow()
+	}
+	// a copy shares the state, wherever it sits in memory
+	state = NewOneTimeAuthState(key)
+	state.Update(message[:333])
+	moved := *state
+	moved.Update(message[333:])
+	if CTCompare(state.Final(), tag) != 0 {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,41 @@
+package natrium
+
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #include <stdio.h>
+// #include <sodium.h>
+import "C"
+import "errors"
+
+// Pad returns a copy of buf padded to a multiple of blockSize, using the
+// ISO/IEC 7816-4 padding of sodium_pad. At least one byte is always added, so
+// Unpad can strip the padding even if buf itself ends in zeros. Padding before
+// encryption hides the exact length of a message, leaking only how many blocks
+// it spans.
+func Pad(buf []byte, blockSize int) ([]byte, error) {
+	if blockSize <= 0 {
+		return nil, errors.New("padding block size must be positive")
+	}
+	out := make([]byte, len(buf)+blockSize-len(buf)%blockSize)
+	copy(out, buf)
+	var padded C.size_t
+	rv := C.sodium_pad(&padded, g2cbt(out), C.size_t(len(buf)), C.size_t(blockSize),
+		C.size_t(len(out)))
+	if rv != 0 {
+		return nil, errors.New("sodium_pad failed")
+	}
+	return out[:padded], nil
+}
+
+// Unpad removes the padding added by Pad with the same blockSize, returning a
+// subslice of buf. Malformed padding is reported as an error.
+func Unpad(buf []byte, blockSize int) ([]byte, error) {
+	if blockSize <= 0 {
+		return nil, errors.New("padding block size must be positive")
+	}
+	var unpadded C.size_t
+	rv := C.sodium_unpad(&unpadded, g2cbt(buf), C.size_t(len(buf)), C.size_t(blockSize))
+	if rv != 0 {
+		return nil, errors.New("invalid padding")
+	}
+	return buf[:unpadded], nil
+}
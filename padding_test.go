@@ -0,0 +1,28 @@
+package natrium
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPad(t *testing.T) {
+	for _, msg := range [][]byte{nil, []byte("hello"), make([]byte, 16), {1, 0, 0}} {
+		padded, err := Pad(msg, 16)
+		if err != nil || len(padded)%16 != 0 || len(padded) <= len(msg) {
+			t.FailNow()
+		}
+		unpadded, err := Unpad(padded, 16)
+		if err != nil || !bytes.Equal(unpadded, msg) {
+			t.FailNow()
+		}
+	}
+	if _, err := Pad([]byte("x"), 0); err == nil {
+		t.FailNow()
+	}
+	if _, err := Unpad(make([]byte, 16), 16); err == nil {
+		t.FailNow()
+	}
+	if _, err := Unpad([]byte("hello"), 0); err == nil {
+		t.FailNow()
+	}
+}
@@ -1,12 +1,18 @@
 package natrium
 
 // #cgo darwin CFLAGS: -I/usr/local/include
-// #cgo darwin LDFLAGS: /usr/local/lib/libsodium.a
-// #cgo linux windows android LDFLAGS: -Wl,-Bstatic -lsodium -Wl,-Bdynamic
 // #include <stdio.h>
 // #include <sodium.h>
 import "C"
-import "unsafe"
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"unsafe"
+)
+
+// RandomSeedLength is the length of the seed passed to RandomBytesDeterministic.
+const RandomSeedLength int = C.randombytes_SEEDBYTES
 
 // RandUint32 returns a random uint32.
 func RandUint32() uint32 {
@@ -18,7 +24,74 @@ func RandUint32LT(lim uint32) uint32 {
 	return uint32(C.randombytes_uniform(C.uint32_t(lim)))
 }
 
+// randSource holds a randFill installed by SetRandSource.
+var randSource atomic.Value
+
+type randFill struct {
+	fill func([]byte)
+}
+
+// SetRandSource replaces libsodium's CSPRNG with fill, FOR TESTS ONLY, so
+// that code generating keys and nonces can be made reproducible; passing nil
+// restores the real generator. A program that calls it outside of tests gives
+// up all security, since every key it generates becomes predictable.
+//
+// The source is used by RandBytes, RandomBytes, Rand and through them by the
+// key generation functions and NewNonce methods. Randomness that libsodium
+// draws internally, such as the ephemeral keys of sealed boxes, secretstream
+// headers, password hash salts, random scalars and RandUint32, is not
+// affected.
+func SetRandSource(fill func([]byte)) {
+	randSource.Store(randFill{fill})
+}
+
 // RandBytes fills the given byte slice with random values.
 func RandBytes(b []byte) {
+	if src, _ := randSource.Load().(randFill); src.fill != nil {
+		src.fill(b)
+		return
+	}
 	C.randombytes_buf(unsafe.Pointer(g2cbt(b)), C.size_t(len(b)))
 }
+
+// RandomBytes returns n cryptographically secure random bytes. It is safe to
+// call concurrently.
+func RandomBytes(n int) []byte {
+	toret := make([]byte, n)
+	RandBytes(toret)
+	return toret
+}
+
+// RandomUniform returns a random integer in [0, upperBound), without the
+// modulo bias of reducing a random uint32. An upperBound of 0 returns 0.
+func RandomUniform(upperBound uint32) uint32 {
+	if upperBound == 0 {
+		return 0
+	}
+	return uint32(C.randombytes_uniform(C.uint32_t(upperBound)))
+}
+
+// RandomBytesDeterministic returns n bytes that are indistinguishable from
+// random, but always the same for a given RandomSeedLength-byte seed. This is
+// useful for reproducible tests, and must not be used to generate secrets
+// unless the seed itself is secret and random.
+func RandomBytesDeterministic(n int, seed []byte) ([]byte, error) {
+	if len(seed) != RandomSeedLength {
+		return nil, errors.New("random seed has the wrong length")
+	}
+	toret := make([]byte, n)
+	C.randombytes_buf_deterministic(unsafe.Pointer(g2cbt(toret)), C.size_t(n), g2cbt(seed))
+	return toret, nil
+}
+
+type randReader struct{}
+
+func (randReader) Read(p []byte) (int, error) {
+	RandBytes(p)
+	return len(p), nil
+}
+
+// Rand is an io.Reader backed by libsodium's CSPRNG, a drop-in replacement
+// for crypto/rand.Reader. Reads always fill the whole buffer and never fail,
+// and it is safe for concurrent use.
+var Rand io.Reader = randReader{}
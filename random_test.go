@@ -0,0 +1,94 @@
+package natrium
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"testing"
+)
+
+func TestRandomBytes(t *testing.T) {
+	if len(RandomBytes(0)) != 0 || len(RandomBytes(100)) != 100 {
+		t.FailNow()
+	}
+	if CTCompare(RandomBytes(32), RandomBytes(32)) == 0 {
+		t.FailNow()
+	}
+}
+
+func TestRandomUniform(t *testing.T) {
+	if RandomUniform(0) != 0 || RandomUniform(1) != 0 {
+		t.FailNow()
+	}
+	buckets := make([]int, 10)
+	for i := 0; i < 100000; i++ {
+		buckets[RandomUniform(10)]++
+	}
+	for _, count := range buckets {
+		// expected 10000 each, with a standard deviation of about 95
+		if count < 9500 || count > 10500 {
+			t.FailNow()
+		}
+	}
+}
+
+func TestRandomBytesDeterministic(t *testing.T) {
+	seed := RandomBytes(RandomSeedLength)
+	a, err := RandomBytesDeterministic(100, seed)
+	if err != nil {
+		t.FailNow()
+	}
+	b, _ := RandomBytesDeterministic(100, seed)
+	c, _ := RandomBytesDeterministic(100, RandomBytes(RandomSeedLength))
+	if CTCompare(a, b) != 0 || CTCompare(a, c) == 0 {
+		t.FailNow()
+	}
+	if _, err := RandomBytesDeterministic(100, seed[1:]); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestRandReader(t *testing.T) {
+	for _, size := range []int{0, 1, 31, 4096, 100000} {
+		buf := make([]byte, size)
+		n, err := Rand.Read(buf)
+		if n != size || err != nil {
+			t.FailNow()
+		}
+		if size >= 31 && isZero(buf) {
+			t.FailNow()
+		}
+	}
+	if _, _, err := ed25519.GenerateKey(Rand); err != nil {
+		t.FailNow()
+	}
+}
+
+func TestSetRandSource(t *testing.T) {
+	counter := func() func([]byte) {
+		var n byte
+		return func(b []byte) {
+			for i := range b {
+				b[i] = n
+				n++
+			}
+		}
+	}
+	SetRandSource(counter())
+	t.Cleanup(func() { SetRandSource(nil) })
+	a, nonce := EdDSAGenerateKey(), GenerateSecretKey().NewNonce()
+	SetRandSource(counter())
+	b, again := EdDSAGenerateKey(), GenerateSecretKey().NewNonce()
+	if !a.Equal(b) || !bytes.Equal(nonce, again) {
+		t.FailNow()
+	}
+	// the seed is the first 32 bytes drawn
+	for i, v := range a.Seed() {
+		if v != byte(i) {
+			t.FailNow()
+		}
+	}
+	SetRandSource(nil)
+	if a.Equal(EdDSAGenerateKey()) {
+		t.FailNow()
+	}
+}
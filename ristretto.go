@@ -0,0 +1,196 @@
+package natrium
+
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #include <stdio.h>
+// #include <sodium.h>
+import "C"
+import "errors"
+
+// Ristretto255 is a prime-order group built on Curve25519. Unlike the Ed25519
+// group, it has no cofactor, and every valid encoding is a unique element, so
+// protocols such as OPRFs, VRFs and Schnorr-style proofs can be written
+// without worrying about small-order points. Points and scalars are plain
+// byte slices, like those of the Ed25519* functions.
+
+// RistrettoPointLength is the length of an encoded Ristretto255 element.
+const RistrettoPointLength int = C.crypto_core_ristretto255_BYTES
+
+// RistrettoHashLength is the length of the input to RistrettoFromHash.
+const RistrettoHashLength int = C.crypto_core_ristretto255_HASHBYTES
+
+// RistrettoScalarLength is the length of a scalar modulo the group order.
+const RistrettoScalarLength int = C.crypto_core_ristretto255_SCALARBYTES
+
+// RistrettoNonReducedScalarLength is the length of the input to
+// RistrettoScalarReduce.
+const RistrettoNonReducedScalarLength int = C.crypto_core_ristretto255_NONREDUCEDSCALARBYTES
+
+var errZeroScalar = errors.New("zero scalar has no inverse")
+
+func checkRistrettoScalar(s []byte) error {
+	if len(s) != RistrettoScalarLength {
+		return keyLengthError("Ristretto255 scalar", len(s), RistrettoScalarLength)
+	}
+	return nil
+}
+
+// RistrettoIsValidPoint reports whether p is the canonical encoding of a
+// Ristretto255 element.
+func RistrettoIsValidPoint(p []byte) bool {
+	return len(p) == RistrettoPointLength && C.crypto_core_ristretto255_is_valid_point(g2cbt(p)) == 1
+}
+
+// RistrettoRandomPoint returns a uniformly random element, whose discrete
+// logarithm nobody knows.
+func RistrettoRandomPoint() []byte {
+	mustInit()
+	toret := make([]byte, RistrettoPointLength)
+	C.crypto_core_ristretto255_random(g2cbt(toret))
+	return toret
+}
+
+// RistrettoFromHash maps a 64-byte string, typically the output of SHA512 or
+// GenericHash, to an element, so that messages can be hashed into the group.
+func RistrettoFromHash(hash []byte) ([]byte, error) {
+	if len(hash) != RistrettoHashLength {
+		return nil, keyLengthError("Ristretto255 hash", len(hash), RistrettoHashLength)
+	}
+	toret := make([]byte, RistrettoPointLength)
+	if C.crypto_core_ristretto255_from_hash(g2cbt(toret), g2cbt(hash)) != 0 {
+		return nil, ErrInvalidPoint
+	}
+	return toret, nil
+}
+
+// RistrettoAdd returns the sum of two valid elements.
+func RistrettoAdd(a, b []byte) ([]byte, error) {
+	if !RistrettoIsValidPoint(a) || !RistrettoIsValidPoint(b) {
+		return nil, ErrInvalidPoint
+	}
+	toret := make([]byte, RistrettoPointLength)
+	if C.crypto_core_ristretto255_add(g2cbt(toret), g2cbt(a), g2cbt(b)) != 0 {
+		return nil, ErrInvalidPoint
+	}
+	return toret, nil
+}
+
+// RistrettoSub returns the difference a - b of two valid elements.
+func RistrettoSub(a, b []byte) ([]byte, error) {
+	if !RistrettoIsValidPoint(a) || !RistrettoIsValidPoint(b) {
+		return nil, ErrInvalidPoint
+	}
+	toret := make([]byte, RistrettoPointLength)
+	if C.crypto_core_ristretto255_sub(g2cbt(toret), g2cbt(a), g2cbt(b)) != 0 {
+		return nil, ErrInvalidPoint
+	}
+	return toret, nil
+}
+
+// RistrettoScalarMult multiplies a valid element by a scalar. ErrInvalidPoint
+// is returned if the product is the identity, as for a zero scalar.
+func RistrettoScalarMult(scalar, point []byte) ([]byte, error) {
+	if err := checkRistrettoScalar(scalar); err != nil {
+		return nil, err
+	}
+	if !RistrettoIsValidPoint(point) {
+		return nil, ErrInvalidPoint
+	}
+	toret := make([]byte, RistrettoPointLength)
+	if C.crypto_scalarmult_ristretto255(g2cbt(toret), g2cbt(scalar), g2cbt(point)) != 0 {
+		return nil, ErrInvalidPoint
+	}
+	return toret, nil
+}
+
+// RistrettoScalarMultBase multiplies the group generator by a scalar.
+func RistrettoScalarMultBase(scalar []byte) ([]byte, error) {
+	if err := checkRistrettoScalar(scalar); err != nil {
+		return nil, err
+	}
+	toret := make([]byte, RistrettoPointLength)
+	if C.crypto_scalarmult_ristretto255_base(g2cbt(toret), g2cbt(scalar)) != 0 {
+		return nil, ErrInvalidPoint
+	}
+	return toret, nil
+}
+
+// RistrettoScalarRandom returns a random non-zero scalar.
+func RistrettoScalarRandom() []byte {
+	mustInit()
+	toret := make([]byte, RistrettoScalarLength)
+	C.crypto_core_ristretto255_scalar_random(g2cbt(toret))
+	return toret
+}
+
+// RistrettoScalarAdd returns a + b modulo the group order.
+func RistrettoScalarAdd(a, b []byte) ([]byte, error) {
+	if err := checkRistrettoScalar(a); err != nil {
+		return nil, err
+	}
+	if err := checkRistrettoScalar(b); err != nil {
+		return nil, err
+	}
+	toret := make([]byte, RistrettoScalarLength)
+	C.crypto_core_ristretto255_scalar_add(g2cbt(toret), g2cbt(a), g2cbt(b))
+	return toret, nil
+}
+
+// RistrettoScalarSub returns a - b modulo the group order.
+func RistrettoScalarSub(a, b []byte) ([]byte, error) {
+	if err := checkRistrettoScalar(a); err != nil {
+		return nil, err
+	}
+	if err := checkRistrettoScalar(b); err != nil {
+		return nil, err
+	}
+	toret := make([]byte, RistrettoScalarLength)
+	C.crypto_core_ristretto255_scalar_sub(g2cbt(toret), g2cbt(a), g2cbt(b))
+	return toret, nil
+}
+
+// RistrettoScalarMul returns a * b modulo the group order.
+func RistrettoScalarMul(a, b []byte) ([]byte, error) {
+	if err := checkRistrettoScalar(a); err != nil {
+		return nil, err
+	}
+	if err := checkRistrettoScalar(b); err != nil {
+		return nil, err
+	}
+	toret := make([]byte, RistrettoScalarLength)
+	C.crypto_core_ristretto255_scalar_mul(g2cbt(toret), g2cbt(a), g2cbt(b))
+	return toret, nil
+}
+
+// RistrettoScalarNegate returns -s modulo the group order.
+func RistrettoScalarNegate(s []byte) ([]byte, error) {
+	if err := checkRistrettoScalar(s); err != nil {
+		return nil, err
+	}
+	toret := make([]byte, RistrettoScalarLength)
+	C.crypto_core_ristretto255_scalar_negate(g2cbt(toret), g2cbt(s))
+	return toret, nil
+}
+
+// RistrettoScalarInvert returns the multiplicative inverse of s, failing for
+// a zero scalar, which has none.
+func RistrettoScalarInvert(s []byte) ([]byte, error) {
+	if err := checkRistrettoScalar(s); err != nil {
+		return nil, err
+	}
+	toret := make([]byte, RistrettoScalarLength)
+	if C.crypto_core_ristretto255_scalar_invert(g2cbt(toret), g2cbt(s)) != 0 {
+		return nil, errZeroScalar
+	}
+	return toret, nil
+}
+
+// RistrettoScalarReduce reduces a 64-byte little-endian number modulo the
+// group order, giving a nearly uniform scalar from a hash output.
+func RistrettoScalarReduce(s []byte) ([]byte, error) {
+	if len(s) != RistrettoNonReducedScalarLength {
+		return nil, keyLengthError("unreduced Ristretto255 scalar", len(s), RistrettoNonReducedScalarLength)
+	}
+	toret := make([]byte, RistrettoScalarLength)
+	C.crypto_core_ristretto255_scalar_reduce(g2cbt(toret), g2cbt(s))
+	return toret, nil
+}
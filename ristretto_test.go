@@ -0,0 +1,67 @@
+package natrium
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRistretto(t *testing.T) {
+	a := RistrettoScalarRandom()
+	b := RistrettoScalarRandom()
+	aG, _ := RistrettoScalarMultBase(a)
+	bG, _ := RistrettoScalarMultBase(b)
+	sum, _ := RistrettoScalarAdd(a, b)
+	sumG, _ := RistrettoScalarMultBase(sum)
+	if added, err := RistrettoAdd(aG, bG); err != nil || !bytes.Equal(added, sumG) {
+		t.FailNow()
+	}
+	diff, _ := RistrettoScalarSub(sum, b)
+	if !bytes.Equal(diff, a) {
+		t.FailNow()
+	}
+	if back, err := RistrettoSub(sumG, bG); err != nil || !bytes.Equal(back, aG) {
+		t.FailNow()
+	}
+	// blinding and unblinding a hashed element, as in an OPRF
+	p, err := RistrettoFromHash(SHA512([]byte("input")))
+	if err != nil || !RistrettoIsValidPoint(p) {
+		t.FailNow()
+	}
+	blinded, _ := RistrettoScalarMult(a, p)
+	inv, err := RistrettoScalarInvert(a)
+	if err != nil {
+		t.FailNow()
+	}
+	if unblinded, _ := RistrettoScalarMult(inv, blinded); !bytes.Equal(unblinded, p) {
+		t.FailNow()
+	}
+	neg, _ := RistrettoScalarNegate(a)
+	if zero, _ := RistrettoScalarAdd(a, neg); !isZero(zero) {
+		t.FailNow()
+	}
+	if _, err := RistrettoScalarInvert(make([]byte, 32)); err == nil {
+		t.FailNow()
+	}
+	prod, _ := RistrettoScalarMul(a, b)
+	abG, _ := RistrettoScalarMult(a, bG)
+	if prodG, _ := RistrettoScalarMultBase(prod); !bytes.Equal(prodG, abG) {
+		t.FailNow()
+	}
+	r, err := RistrettoScalarReduce(SHA512([]byte("x")))
+	if err != nil || len(r) != RistrettoScalarLength {
+		t.FailNow()
+	}
+	if !RistrettoIsValidPoint(RistrettoRandomPoint()) || RistrettoIsValidPoint(bytes.Repeat([]byte{0xff}, 32)) {
+		t.FailNow()
+	}
+	if _, err := RistrettoAdd(aG, bytes.Repeat([]byte{0xff}, 32)); err != ErrInvalidPoint {
+		t.FailNow()
+	}
+	if _, err := RistrettoScalarMult(a[1:], p); !errors.Is(err, ErrInvalidKeyLength) {
+		t.FailNow()
+	}
+	if _, err := RistrettoFromHash(make([]byte, 32)); !errors.Is(err, ErrInvalidKeyLength) {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,76 @@
+package natrium
+
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #include <stdio.h>
+// #include <sodium.h>
+import "C"
+import (
+	"errors"
+	"fmt"
+)
+
+// The scrypt functions exist to verify and migrate hashes made by older
+// systems. New password hashes should always use HashPassword, which is
+// Argon2id.
+
+// ScryptSaltLength is the length of the salt passed to DeriveKeyScrypt.
+const ScryptSaltLength int = C.crypto_pwhash_scryptsalsa208sha256_SALTBYTES
+
+// ScryptBytesMin is the shortest key DeriveKeyScrypt can derive.
+const ScryptBytesMin int = C.crypto_pwhash_scryptsalsa208sha256_BYTES_MIN
+
+// ScryptBytesMax is the longest key DeriveKeyScrypt can derive, the
+// 32*(2^32-1) bytes scrypt allows, or the largest int if that is smaller.
+const ScryptBytesMax int = min(0x1fffffffe0, maxInt)
+
+// Presets for the opsLimit and memLimit parameters of the scrypt functions.
+const (
+	ScryptOpsInteractive uint64 = C.crypto_pwhash_scryptsalsa208sha256_OPSLIMIT_INTERACTIVE
+	ScryptMemInteractive uint64 = C.crypto_pwhash_scryptsalsa208sha256_MEMLIMIT_INTERACTIVE
+	ScryptOpsSensitive   uint64 = C.crypto_pwhash_scryptsalsa208sha256_OPSLIMIT_SENSITIVE
+	ScryptMemSensitive   uint64 = C.crypto_pwhash_scryptsalsa208sha256_MEMLIMIT_SENSITIVE
+)
+
+// DeriveKeyScrypt is the scrypt counterpart of DeriveKeyFromPassword, taking
+// a ScryptSaltLength-byte salt. outLen must be between ScryptBytesMin and
+// ScryptBytesMax.
+func DeriveKeyScrypt(password, salt []byte, outLen int, opsLimit, memLimit uint64) ([]byte, error) {
+	if len(salt) != ScryptSaltLength {
+		return nil, errors.New("wrong salt length for scrypt")
+	}
+	if outLen < ScryptBytesMin || outLen > ScryptBytesMax {
+		return nil, fmt.Errorf("scrypt output length must be between %v and %v",
+			ScryptBytesMin, ScryptBytesMax)
+	}
+	toret := make([]byte, outLen)
+	rv := C.crypto_pwhash_scryptsalsa208sha256(g2cbt(toret), C.ulonglong(outLen),
+		g2cst(password), C.ulonglong(len(password)), g2cbt(salt),
+		C.ulonglong(opsLimit), C.size_t(memLimit))
+	if rv != 0 {
+		return nil, errors.New("scrypt failed (invalid parameters or out of memory)")
+	}
+	return toret, nil
+}
+
+// HashPasswordScrypt is like HashPassword, but produces a scrypt hash string
+// in the "$7$" format.
+func HashPasswordScrypt(password []byte, opsLimit, memLimit uint64) (string, error) {
+	out := make([]byte, C.crypto_pwhash_scryptsalsa208sha256_STRBYTES)
+	rv := C.crypto_pwhash_scryptsalsa208sha256_str(g2cst(out), g2cst(password),
+		C.ulonglong(len(password)), C.ulonglong(opsLimit), C.size_t(memLimit))
+	if rv != 0 {
+		return "", errors.New("scrypt failed (invalid parameters or out of memory)")
+	}
+	return cstring(out), nil
+}
+
+// VerifyPasswordScrypt reports whether the password matches a scrypt hash
+// string. Once it does, the password should be rehashed with HashPassword.
+func VerifyPasswordScrypt(hash string, password []byte) bool {
+	if len(hash) >= C.crypto_pwhash_scryptsalsa208sha256_STRBYTES {
+		return false
+	}
+	haha := append([]byte(hash), 0)
+	return C.crypto_pwhash_scryptsalsa208sha256_str_verify(g2cst(haha), g2cst(password),
+		C.ulonglong(len(password))) == 0
+}
@@ -0,0 +1,30 @@
+package natrium
+
+import "testing"
+
+func TestScrypt(t *testing.T) {
+	hash, err := HashPasswordScrypt([]byte("hunter2"), ScryptOpsInteractive, ScryptMemInteractive)
+	if err != nil || hash[:3] != "$7$" {
+		t.FailNow()
+	}
+	if !VerifyPasswordScrypt(hash, []byte("hunter2")) || VerifyPasswordScrypt(hash, []byte("hunter3")) {
+		t.FailNow()
+	}
+	if VerifyPassword(hash, []byte("hunter2")) {
+		t.FailNow()
+	}
+	key, err := DeriveKeyScrypt([]byte("hunter2"), make([]byte, ScryptSaltLength), 32,
+		ScryptOpsInteractive, ScryptMemInteractive)
+	if err != nil || len(key) != 32 {
+		t.FailNow()
+	}
+	if _, err := DeriveKeyScrypt(nil, make([]byte, 16), 32, ScryptOpsInteractive, ScryptMemInteractive); err == nil {
+		t.FailNow()
+	}
+	for _, n := range []int{-1, 0, ScryptBytesMin - 1} {
+		if _, err := DeriveKeyScrypt(nil, make([]byte, ScryptSaltLength), n,
+			ScryptOpsInteractive, ScryptMemInteractive); err == nil {
+			t.FailNow()
+		}
+	}
+}
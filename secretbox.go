@@ -0,0 +1,307 @@
+package natrium
+
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #include <stdio.h>
+// #include <sodium.h>
+import "C"
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// SecretKey represents a key for symmetric authenticated encryption using
+// XSalsa20 and Poly1305. Assignment aliases the key, so Destroy on one copy
+// wipes them all; Clone makes an independent one.
+type SecretKey []byte
+
+// String returns a redacted placeholder so the key is not printed by accident.
+func (k SecretKey) String() string {
+	return redacted("seckey", k)
+}
+
+// Format implements fmt.Formatter, printing String for every verb.
+func (k SecretKey) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, k.String())
+}
+
+// SecretBoxKeyLength is the length of a SecretKey.
+const SecretBoxKeyLength int = C.crypto_secretbox_KEYBYTES
+
+// SecretBoxNonceLength is the length of the nonce passed to Seal and Open.
+const SecretBoxNonceLength int = C.crypto_secretbox_NONCEBYTES
+
+// SecretBoxMACLength is the number of bytes Seal adds to a message.
+const SecretBoxMACLength int = C.crypto_secretbox_MACBYTES
+
+// NewSecretKey returns a length-checked copy of b as a SecretKey.
+func NewSecretKey(b []byte) (SecretKey, error) {
+	return copyKey(b, SecretBoxKeyLength, "secretbox key")
+}
+
+// SecretKeyFromHex parses a secretbox key written in hex. String never prints
+// the key, so there is no prefix to strip.
+func SecretKeyFromHex(s string) (SecretKey, error) {
+	return keyFromHex(s, "", SecretBoxKeyLength, "secretbox key")
+}
+
+// MustSecretKeyFromHex is like SecretKeyFromHex, but panics on error.
+func MustSecretKeyFromHex(s string) SecretKey {
+	return mustKey(SecretKeyFromHex(s))
+}
+
+// Equal reports, in constant time, whether two secretbox keys are the same.
+func (k SecretKey) Equal(other SecretKey) bool {
+	return MemCmp(k, other)
+}
+
+// Clone returns a copy of the key that is unaffected by later changes to k.
+func (k SecretKey) Clone() SecretKey {
+	return append(SecretKey(nil), k...)
+}
+
+// GenerateSecretKey generates a random SecretKey.
+func GenerateSecretKey() SecretKey {
+	mustInit()
+	toret := make([]byte, SecretBoxKeyLength)
+	RandBytes(toret)
+	return toret
+}
+
+// NewNonce returns a random nonce of SecretBoxNonceLength bytes. XSalsa20
+// nonces are 24 bytes long, like those of AEADKey, so they need no counter.
+func (k SecretKey) NewNonce() []byte {
+	return RandomBytes(SecretBoxNonceLength)
+}
+
+// Destroy wipes the key from memory. The key must not be used afterwards:
+// nothing detects the use of a destroyed key, which is then simply the
+// all-zero key.
+func (k SecretKey) Destroy() {
+	wipe(k)
+}
+
+func (k SecretKey) checkKey() {
+	if len(k) != SecretBoxKeyLength {
+		panic("secret key has the wrong length")
+	}
+}
+
+func (k SecretKey) check(nonce []byte) {
+	k.checkKey()
+	if len(nonce) != SecretBoxNonceLength {
+		panic("secretbox nonce has the wrong length")
+	}
+}
+
+// Seal encrypts and authenticates a message with crypto_secretbox_easy, so the
+// result is the MAC followed by the encrypted message. The nonce must be
+// SecretBoxNonceLength bytes long, and must never be reused with the same key.
+func (k SecretKey) Seal(message, nonce []byte) []byte {
+	k.check(nonce)
+	out := make([]byte, len(message)+SecretBoxMACLength)
+	rv := C.crypto_secretbox_easy(g2cbt(out), g2cbt(message), C.ulonglong(len(message)),
+		g2cbt(nonce), g2cbt(k))
+	if rv != 0 {
+		panic("crypto_secretbox_easy returned non-zero")
+	}
+	return out
+}
+
+// CiphertextLen returns the length of the output of Seal for an n-byte
+// message.
+func (k SecretKey) CiphertextLen(n int) int {
+	return n + SecretBoxMACLength
+}
+
+// Open decrypts and verifies a ciphertext produced by Seal.
+func (k SecretKey) Open(ciphertext, nonce []byte) ([]byte, error) {
+	k.check(nonce)
+	if len(ciphertext) < SecretBoxMACLength {
+		return nil, fmt.Errorf("%w: ciphertext too short", ErrDecryptionFailed)
+	}
+	out := make([]byte, len(ciphertext)-SecretBoxMACLength)
+	rv := C.crypto_secretbox_open_easy(g2cbt(out), g2cbt(ciphertext), C.ulonglong(len(ciphertext)),
+		g2cbt(nonce), g2cbt(k))
+	if rv != 0 {
+		return nil, ErrDecryptionFailed
+	}
+	return out, nil
+}
+
+// SecretBoxAutoOverhead is the number of bytes EncryptAuto adds to a message.
+const SecretBoxAutoOverhead = SecretBoxNonceLength + SecretBoxMACLength
+
+// EncryptAuto is like Seal, but draws a random nonce itself and prepends it to
+// the output, leaving nothing for the caller to manage. The layout is:
+//
+//	24 bytes  nonce
+//	16 bytes  Poly1305 MAC
+//	rest      XSalsa20 ciphertext, as long as the message
+//
+// which is the nonce followed by the output of crypto_secretbox_easy.
+func (k SecretKey) EncryptAuto(message []byte) []byte {
+	nonce := k.NewNonce()
+	return append(nonce, k.Seal(message, nonce)...)
+}
+
+// AutoCiphertextLen is like CiphertextLen, but for EncryptAuto, whose output
+// also carries the nonce.
+func (k SecretKey) AutoCiphertextLen(n int) int {
+	return n + SecretBoxAutoOverhead
+}
+
+// DecryptAuto decrypts and verifies a ciphertext produced by EncryptAuto.
+func (k SecretKey) DecryptAuto(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < SecretBoxAutoOverhead {
+		return nil, fmt.Errorf("%w: ciphertext too short", ErrDecryptionFailed)
+	}
+	return k.Open(ciphertext[SecretBoxNonceLength:], ciphertext[:SecretBoxNonceLength])
+}
+
+// deterministicNonce derives the nonce of SealDeterministic: the 24-byte
+// BLAKE2b of context's length as an 8-byte little-endian number, context and
+// message, keyed with a hash of k so that the encryption key itself is never
+// used as a BLAKE2b key.
+func (k SecretKey) deterministicNonce(message, context []byte) []byte {
+	k.checkKey()
+	nonceKey := genericHash([]byte("natrium secretbox deterministic nonce"), k, 32)
+	defer wipe(nonceKey)
+	input := make([]byte, 8, 8+len(context)+len(message))
+	binary.LittleEndian.PutUint64(input, uint64(len(context)))
+	input = append(append(input, context...), message...)
+	defer wipe(input)
+	return genericHash(input, nonceKey, SecretBoxNonceLength)
+}
+
+// SealDeterministic is like EncryptAuto, with the same output layout, but for
+// systems that can keep neither a counter nor a source of fresh randomness
+// per message: the nonce is derived from the message and context instead of
+// drawn at random. Different messages or contexts get different nonces, so
+// nonces never repeat for different plaintexts.
+//
+// WARNING: this is deterministic encryption, in the manner of SIV. Sealing
+// the same message with the same context and key always gives the same
+// ciphertext, so anyone who sees two ciphertexts learns whether they hold the
+// same plaintext. That is the price of needing no state; where revealing
+// repeats matters, use EncryptAuto. Putting a message ID or timestamp in the
+// context gives unique ciphertexts for messages with unique contexts.
+func (k SecretKey) SealDeterministic(message, context []byte) []byte {
+	nonce := k.deterministicNonce(message, context)
+	return append(nonce, k.Seal(message, nonce)...)
+}
+
+// OpenDeterministic decrypts and verifies a ciphertext produced by
+// SealDeterministic with the same context. It also recomputes the nonce from
+// the plaintext and context and compares it with the one received, which is
+// what binds the context to the ciphertext; any mismatch gives
+// ErrDecryptionFailed.
+func (k SecretKey) OpenDeterministic(ciphertext, context []byte) ([]byte, error) {
+	if len(ciphertext) < SecretBoxAutoOverhead {
+		return nil, fmt.Errorf("%w: ciphertext too short", ErrDecryptionFailed)
+	}
+	nonce := ciphertext[:SecretBoxNonceLength]
+	out, err := k.Open(ciphertext[SecretBoxNonceLength:], nonce)
+	if err != nil {
+		return nil, err
+	}
+	if !MemCmp(k.deterministicNonce(out, context), nonce) {
+		wipe(out)
+		return nil, fmt.Errorf("%w: nonce does not match message and context", ErrDecryptionFailed)
+	}
+	return out, nil
+}
+
+// SealDetached is like Seal, but returns the MAC separately, for formats with
+// a fixed MAC field. The ciphertext is as long as the message.
+func (k SecretKey) SealDetached(message, nonce []byte) (ciphertext, mac []byte) {
+	k.check(nonce)
+	ciphertext = make([]byte, len(message))
+	mac = make([]byte, SecretBoxMACLength)
+	rv := C.crypto_secretbox_detached(g2cbt(ciphertext), g2cbt(mac), g2cbt(message),
+		C.ulonglong(len(message)), g2cbt(nonce), g2cbt(k))
+	if rv != 0 {
+		panic("crypto_secretbox_detached returned non-zero")
+	}
+	return ciphertext, mac
+}
+
+// OpenDetached decrypts and verifies a ciphertext and MAC produced by
+// SealDetached.
+func (k SecretKey) OpenDetached(ciphertext, mac, nonce []byte) ([]byte, error) {
+	k.check(nonce)
+	if len(mac) != SecretBoxMACLength {
+		return nil, fmt.Errorf("%w: secretbox MAC has the wrong length", ErrDecryptionFailed)
+	}
+	out := make([]byte, len(ciphertext))
+	rv := C.crypto_secretbox_open_detached(g2cbt(out), g2cbt(ciphertext), g2cbt(mac),
+		C.ulonglong(len(ciphertext)), g2cbt(nonce), g2cbt(k))
+	if rv != 0 {
+		return nil, ErrDecryptionFailed
+	}
+	return out, nil
+}
+
+// GobEncode implements the gob.GobEncoder interface.
+func (k SecretKey) GobEncode() ([]byte, error) {
+	return encodeKeyGob(gobSecretKey, k)
+}
+
+// GobDecode implements the gob.GobDecoder interface, rejecting anything that
+// is not a secretbox key of the right length.
+func (k *SecretKey) GobDecode(data []byte) error {
+	raw, err := decodeKeyGob(data, gobSecretKey, SecretBoxKeyLength, "secretbox key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, returning a
+// copy of the raw key.
+func (k SecretKey) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), k...), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. The
+// data is length-checked and copied, never aliased.
+func (k *SecretKey) UnmarshalBinary(data []byte) error {
+	raw, err := copyKey(data, SecretBoxKeyLength, "secretbox key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, encoding the
+// key as lowercase hex.
+func (k SecretKey) MarshalText() ([]byte, error) {
+	return []byte(BinToHex(k)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, reversing
+// MarshalText.
+func (k *SecretKey) UnmarshalText(text []byte) error {
+	raw, err := unmarshalKeyText(text, SecretBoxKeyLength, "secretbox key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// SecretKeyPEMType is the PEM block type used by Encode with EncodingPEM.
+const SecretKeyPEMType = "NATRIUM SECRETBOX KEY"
+
+// Encode serializes the key in the given encoding, as raw bytes, hex, PEM or
+// base64. Every encoding holds the secret in the clear.
+func (k SecretKey) Encode(enc Encoding) ([]byte, error) {
+	return encodeKey(k, enc, SecretKeyPEMType, SecretBoxKeyLength, "secretbox key")
+}
+
+// DecodeSecretKey parses a key written by Encode with the same encoding. Data
+// of the wrong length, or a PEM block of another type, gives an error.
+func DecodeSecretKey(data []byte, enc Encoding) (SecretKey, error) {
+	return decodeKey(data, enc, SecretKeyPEMType, SecretBoxKeyLength, "secretbox key")
+}
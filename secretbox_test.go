@@ -0,0 +1,201 @@
+package natrium
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSecretBox(t *testing.T) {
+	key := GenerateSecretKey()
+	nonce := make([]byte, SecretBoxNonceLength)
+	RandBytes(nonce)
+	message := []byte("Hello World")
+	ciphertext := key.Seal(message, nonce)
+	if len(ciphertext) != len(message)+SecretBoxMACLength {
+		t.FailNow()
+	}
+	plaintext, err := key.Open(ciphertext, nonce)
+	if err != nil || string(plaintext) != string(message) {
+		t.FailNow()
+	}
+	ciphertext[5] ^= 1
+	if _, err := key.Open(ciphertext, nonce); err == nil {
+		t.FailNow()
+	}
+	if _, err := key.Open(ciphertext[:3], nonce); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestSecretKeyString(t *testing.T) {
+	key := GenerateSecretKey()
+	if strings.Contains(fmt.Sprint(key), HexEncode(key)) {
+		t.FailNow()
+	}
+}
+
+func TestSecretKeyDestroy(t *testing.T) {
+	key := GenerateSecretKey()
+	alias := key
+	key.Destroy()
+	if !isZero(alias) {
+		t.FailNow()
+	}
+	// an all-zero key, such as one from a test vector, is a key like any other
+	zero := SecretKey(make([]byte, SecretBoxKeyLength))
+	nonce := zero.NewNonce()
+	if pt, err := zero.Open(zero.Seal([]byte("Hello World"), nonce), nonce); err != nil || string(pt) != "Hello World" {
+		t.FailNow()
+	}
+}
+
+func TestSecretKeyEqual(t *testing.T) {
+	key := GenerateSecretKey()
+	if !key.Equal(SecretKey(append([]byte(nil), key...))) {
+		t.FailNow()
+	}
+	if key.Equal(GenerateSecretKey()) || key.Equal(key[:16]) {
+		t.FailNow()
+	}
+}
+
+func TestSecretBoxDetached(t *testing.T) {
+	key := GenerateSecretKey()
+	nonce := RandomBytes(SecretBoxNonceLength)
+	ciphertext, mac := key.SealDetached([]byte("Hello World"), nonce)
+	if len(ciphertext) != 11 || len(mac) != SecretBoxMACLength {
+		t.FailNow()
+	}
+	plain, err := key.OpenDetached(ciphertext, mac, nonce)
+	if err != nil || string(plain) != "Hello World" {
+		t.FailNow()
+	}
+	ciphertext[3] ^= 1
+	if _, err := key.OpenDetached(ciphertext, mac, nonce); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestSecretBoxNewNonce(t *testing.T) {
+	key := GenerateSecretKey()
+	nonce := key.NewNonce()
+	if len(nonce) != SecretBoxNonceLength || MemCmp(nonce, key.NewNonce()) {
+		t.FailNow()
+	}
+	plain, err := key.Open(key.Seal([]byte("Hello"), nonce), nonce)
+	if err != nil || string(plain) != "Hello" {
+		t.FailNow()
+	}
+}
+
+func TestSecretKeyClone(t *testing.T) {
+	key := GenerateSecretKey()
+	clone := key.Clone()
+	nonce := key.NewNonce()
+	ct := key.Seal([]byte("hello"), nonce)
+	key.Destroy()
+	if pt, err := clone.Open(ct, nonce); err != nil || string(pt) != "hello" {
+		t.FailNow()
+	}
+}
+
+func TestSecretKeyFromHex(t *testing.T) {
+	key := GenerateSecretKey()
+	parsed, err := SecretKeyFromHex(BinToHex(key))
+	if err != nil || !parsed.Equal(key) {
+		t.FailNow()
+	}
+	if _, err := SecretKeyFromHex(key.String()); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestSecretBoxAuto(t *testing.T) {
+	key := GenerateSecretKey()
+	ct := key.EncryptAuto([]byte("hello"))
+	if len(ct) != 5+SecretBoxAutoOverhead {
+		t.FailNow()
+	}
+	if pt, err := key.DecryptAuto(ct); err != nil || string(pt) != "hello" {
+		t.FailNow()
+	}
+	if pt, err := key.Open(ct[SecretBoxNonceLength:], ct[:SecretBoxNonceLength]); err != nil || string(pt) != "hello" {
+		t.FailNow()
+	}
+	ct[SecretBoxNonceLength] ^= 1
+	if _, err := key.DecryptAuto(ct); err != ErrDecryptionFailed {
+		t.FailNow()
+	}
+	if _, err := key.DecryptAuto(ct[:SecretBoxAutoOverhead-1]); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestSecretBoxCiphertextLen(t *testing.T) {
+	key := GenerateSecretKey()
+	for _, n := range []int{0, 1, 1000} {
+		if len(key.Seal(make([]byte, n), key.NewNonce())) != key.CiphertextLen(n) ||
+			len(key.EncryptAuto(make([]byte, n))) != key.AutoCiphertextLen(n) {
+			t.FailNow()
+		}
+	}
+}
+
+func TestSecretBoxDeterministic(t *testing.T) {
+	key := GenerateSecretKey()
+	message, context := []byte("Hello World"), []byte("request 1")
+	ct := key.SealDeterministic(message, context)
+	if len(ct) != key.AutoCiphertextLen(len(message)) {
+		t.FailNow()
+	}
+	if !bytes.Equal(ct, key.SealDeterministic(message, context)) {
+		t.FailNow()
+	}
+	for _, other := range [][]byte{
+		key.SealDeterministic([]byte("Hello Worle"), context),
+		key.SealDeterministic(message, []byte("request 2")),
+		key.SealDeterministic(append([]byte("1"), message...), []byte("request ")),
+		GenerateSecretKey().SealDeterministic(message, context),
+	} {
+		if bytes.Equal(other[:SecretBoxNonceLength], ct[:SecretBoxNonceLength]) {
+			t.FailNow()
+		}
+	}
+	plain, err := key.OpenDeterministic(ct, context)
+	if err != nil || !bytes.Equal(plain, message) {
+		t.FailNow()
+	}
+	// it is an EncryptAuto ciphertext, but the context is bound through the nonce
+	if plain, err := key.DecryptAuto(ct); err != nil || !bytes.Equal(plain, message) {
+		t.FailNow()
+	}
+	if _, err := key.OpenDeterministic(ct, []byte("request 2")); !errors.Is(err, ErrDecryptionFailed) {
+		t.FailNow()
+	}
+	if _, err := key.OpenDeterministic(key.EncryptAuto(message), context); !errors.Is(err, ErrDecryptionFailed) {
+		t.FailNow()
+	}
+	ct[len(ct)-1] ^= 1
+	if _, err := key.OpenDeterministic(ct, context); !errors.Is(err, ErrDecryptionFailed) {
+		t.FailNow()
+	}
+	if _, err := key.OpenDeterministic(ct[:10], context); !errors.Is(err, ErrDecryptionFailed) {
+		t.FailNow()
+	}
+}
+
+func TestSecretKeyEncode(t *testing.T) {
+	key := GenerateSecretKey()
+	checkEncoding(t, []encodingCase{
+		{key, SecretKeyPEMType, key.Encode, func(b []byte, e Encoding) ([]byte, error) { return DecodeSecretKey(b, e) }},
+	})
+}
+
+func TestSecretKeyGob(t *testing.T) {
+	key := GenerateSecretKey()
+	var got SecretKey
+	checkGob(t, key, key, &got, func() []byte { return got })
+}
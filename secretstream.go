@@ -0,0 +1,472 @@
+package natrium
+
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #include <stdio.h>
+// #include <sodium.h>
+import "C"
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// SecretStreamKey represents a key for encrypting a stream of messages with
+// crypto_secretstream_xchacha20poly1305. Each message is authenticated, and
+// the stream as a whole is protected against reordering and, by using
+// SecretStreamTagFinal, truncation.
+type SecretStreamKey []byte
+
+// String returns a redacted placeholder so the key is not printed by accident.
+func (k SecretStreamKey) String() string {
+	return redacted("sskey", k)
+}
+
+// Format implements fmt.Formatter, printing String for every verb.
+func (k SecretStreamKey) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, k.String())
+}
+
+// SecretStreamKeyLength is the length of a SecretStreamKey.
+const SecretStreamKeyLength int = C.crypto_secretstream_xchacha20poly1305_KEYBYTES
+
+// SecretStreamHeaderLength is the length of the header that starts a stream.
+const SecretStreamHeaderLength int = C.crypto_secretstream_xchacha20poly1305_HEADERBYTES
+
+// SecretStreamOverhead is the number of bytes Push adds to each message.
+const SecretStreamOverhead int = C.crypto_secretstream_xchacha20poly1305_ABYTES
+
+// Tags that can be attached to a message in a stream.
+const (
+	// SecretStreamTagMessage marks an ordinary message.
+	SecretStreamTagMessage = byte(C.crypto_secretstream_xchacha20poly1305_TAG_MESSAGE)
+	// SecretStreamTagPush marks the end of a set of messages, without ending
+	// the stream.
+	SecretStreamTagPush = byte(C.crypto_secretstream_xchacha20poly1305_TAG_PUSH)
+	// SecretStreamTagRekey marks a message after which the key is rotated.
+	SecretStreamTagRekey = byte(C.crypto_secretstream_xchacha20poly1305_TAG_REKEY)
+	// SecretStreamTagFinal marks the last message of the stream.
+	SecretStreamTagFinal = byte(C.crypto_secretstream_xchacha20poly1305_TAG_FINAL)
+)
+
+// NewSecretStreamKey returns a length-checked copy of b as a SecretStreamKey.
+func NewSecretStreamKey(b []byte) (SecretStreamKey, error) {
+	return copyKey(b, SecretStreamKeyLength, "secretstream key")
+}
+
+// SecretStreamKeyFromHex parses a secretstream key written in hex.
+func SecretStreamKeyFromHex(s string) (SecretStreamKey, error) {
+	return keyFromHex(s, "", SecretStreamKeyLength, "secretstream key")
+}
+
+// MustSecretStreamKeyFromHex is like SecretStreamKeyFromHex, but panics on error.
+func MustSecretStreamKeyFromHex(s string) SecretStreamKey {
+	return mustKey(SecretStreamKeyFromHex(s))
+}
+
+// Equal reports, in constant time, whether two secretstream keys are the
+// same.
+func (k SecretStreamKey) Equal(other SecretStreamKey) bool {
+	return MemCmp(k, other)
+}
+
+// Clone returns a copy of the key with its own backing array, so that
+// destroying k leaves the copy usable.
+func (k SecretStreamKey) Clone() SecretStreamKey {
+	return append(SecretStreamKey(nil), k...)
+}
+
+// GenerateSecretStreamKey generates a random SecretStreamKey.
+func GenerateSecretStreamKey() SecretStreamKey {
+	mustInit()
+	toret := make([]byte, SecretStreamKeyLength)
+	RandBytes(toret)
+	return toret
+}
+
+// Encryptor encrypts a stream of messages. It is not safe for concurrent use.
+type Encryptor struct {
+	state C.crypto_secretstream_xchacha20poly1305_state
+}
+
+// Decryptor decrypts a stream of messages produced by an Encryptor. It is not
+// safe for concurrent use.
+type Decryptor struct {
+	state C.crypto_secretstream_xchacha20poly1305_state
+	done  bool
+}
+
+// Destroy wipes the key from memory. Streams already started keep working,
+// since their state holds a separate copy of the key.
+func (k SecretStreamKey) Destroy() {
+	wipe(k)
+}
+
+// NewEncryptor starts a new stream, returning the Encryptor along with the
+// header that must be sent to the other side before any message.
+func (k SecretStreamKey) NewEncryptor() (*Encryptor, []byte) {
+	if len(k) != SecretStreamKeyLength {
+		panic("secretstream key has the wrong length")
+	}
+	toret := new(Encryptor)
+	header := make([]byte, SecretStreamHeaderLength)
+	rv := C.crypto_secretstream_xchacha20poly1305_init_push(&toret.state,
+		g2cbt(header), g2cbt(k))
+	if rv != 0 {
+		panic("crypto_secretstream_xchacha20poly1305_init_push returned non-zero")
+	}
+	return toret, header
+}
+
+// Push encrypts the next message of the stream with the given tag,
+// additionally authenticating but not encrypting the associated data ad.
+func (e *Encryptor) Push(chunk, ad []byte, tag byte) []byte {
+	out := make([]byte, len(chunk)+SecretStreamOverhead)
+	rv := C.crypto_secretstream_xchacha20poly1305_push(&e.state, g2cbt(out), nil,
+		g2cbt(chunk), C.ulonglong(len(chunk)), g2cbt(ad), C.ulonglong(len(ad)),
+		C.uchar(tag))
+	if rv != 0 {
+		panic("crypto_secretstream_xchacha20poly1305_push returned non-zero")
+	}
+	return out
+}
+
+// NewDecryptor starts decrypting the stream that begins with the given header.
+func (k SecretStreamKey) NewDecryptor(header []byte) *Decryptor {
+	if len(k) != SecretStreamKeyLength {
+		panic("secretstream key has the wrong length")
+	}
+	if len(header) != SecretStreamHeaderLength {
+		panic("secretstream header has the wrong length")
+	}
+	toret := new(Decryptor)
+	rv := C.crypto_secretstream_xchacha20poly1305_init_pull(&toret.state,
+		g2cbt(header), g2cbt(k))
+	if rv != 0 {
+		panic("crypto_secretstream_xchacha20poly1305_init_pull returned non-zero")
+	}
+	return toret
+}
+
+// NewDecryptorChecked is like NewDecryptor, but returns an error instead of
+// panicking if the header is not SecretStreamHeaderLength bytes long, so that
+// a header received from a peer can be passed in without checking it first.
+func (k SecretStreamKey) NewDecryptorChecked(header []byte) (*Decryptor, error) {
+	if len(header) != SecretStreamHeaderLength {
+		return nil, fmt.Errorf("secretstream header is %v bytes instead of %v",
+			len(header), SecretStreamHeaderLength)
+	}
+	return k.NewDecryptor(header), nil
+}
+
+// Pull decrypts and verifies the next message of the stream, returning it
+// along with its tag. The associated data ad must be exactly what was given to
+// Push. A message that was tampered with, reordered, paired with the wrong ad,
+// or pulled after the one tagged SecretStreamTagFinal gives an error.
+func (d *Decryptor) Pull(chunk, ad []byte) (plaintext []byte, tag byte, err error) {
+	if d.done {
+		return nil, 0, errors.New("secretstream already finished")
+	}
+	if len(chunk) < SecretStreamOverhead {
+		return nil, 0, fmt.Errorf("%w: secretstream message too short", ErrDecryptionFailed)
+	}
+	plaintext = make([]byte, len(chunk)-SecretStreamOverhead)
+	var ctag C.uchar
+	rv := C.crypto_secretstream_xchacha20poly1305_pull(&d.state, g2cbt(plaintext), nil,
+		&ctag, g2cbt(chunk), C.ulonglong(len(chunk)), g2cbt(ad), C.ulonglong(len(ad)))
+	if rv != 0 {
+		return nil, 0, ErrDecryptionFailed
+	}
+	tag = byte(ctag)
+	if tag == SecretStreamTagFinal {
+		d.done = true
+	}
+	return plaintext, tag, nil
+}
+
+// Rekey rotates the key of the stream, so that compromising the current state
+// does not reveal earlier messages. Unlike a message tagged
+// SecretStreamTagRekey, which both sides act on automatically, an explicit
+// Rekey is invisible on the wire: the Decryptor must call its own Rekey at
+// exactly the same point in the stream, or every later message will fail to
+// decrypt.
+func (e *Encryptor) Rekey() {
+	C.crypto_secretstream_xchacha20poly1305_rekey(&e.state)
+}
+
+// Rekey rotates the key of the stream, mirroring a call to Encryptor.Rekey
+// made after the message most recently pulled. Messages tagged
+// SecretStreamTagRekey are already followed by a rekey, so Rekey must not be
+// called for them as well.
+func (d *Decryptor) Rekey() {
+	C.crypto_secretstream_xchacha20poly1305_rekey(&d.state)
+}
+
+// SecretStreamChunkSize is the default amount of plaintext NewWriter buffers
+// before encrypting it as one frame.
+const SecretStreamChunkSize = 16 * 1024
+
+// secretStreamMaxChunk bounds frame sizes, so that a reader never allocates
+// an unreasonable amount of memory for a frame it cannot authenticate yet.
+const secretStreamMaxChunk = 1 << 24
+
+var errWriterClosed = errors.New("secretstream writer already closed")
+
+// On the wire, every frame is a 4-byte big-endian length followed by that
+// many bytes of ciphertext. The length is also the frame's associated data,
+// so it cannot be altered without the frame failing to authenticate.
+type ssWriter struct {
+	enc    *Encryptor
+	w      io.Writer
+	buf    []byte
+	size   int
+	err    error
+	closed bool
+}
+
+// NewWriter returns an io.WriteCloser that encrypts everything written to it
+// onto underlying, in frames of SecretStreamChunkSize bytes of plaintext. The
+// returned header must reach the reader first; NewWriter does not write it.
+// Close must be called to end the stream, otherwise the reader will consider
+// it truncated. Closing the writer does not close underlying.
+func (k SecretStreamKey) NewWriter(underlying io.Writer) (io.WriteCloser, []byte, error) {
+	return k.NewWriterSize(underlying, SecretStreamChunkSize)
+}
+
+// NewWriterSize is like NewWriter, but buffers up to size bytes of plaintext
+// per frame.
+func (k SecretStreamKey) NewWriterSize(underlying io.Writer, size int) (io.WriteCloser, []byte, error) {
+	if size <= 0 || size > secretStreamMaxChunk {
+		return nil, nil, fmt.Errorf("secretstream chunk size must be between 1 and %v",
+			secretStreamMaxChunk)
+	}
+	enc, header := k.NewEncryptor()
+	toret := &ssWriter{
+		enc:  enc,
+		w:    underlying,
+		buf:  make([]byte, 0, size),
+		size: size,
+	}
+	return toret, header, nil
+}
+
+func (sw *ssWriter) frame(tag byte) error {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(sw.buf)+SecretStreamOverhead))
+	ct := sw.enc.Push(sw.buf, length[:], tag)
+	sw.buf = sw.buf[:0]
+	if _, err := sw.w.Write(length[:]); err != nil {
+		return err
+	}
+	_, err := sw.w.Write(ct)
+	return err
+}
+
+func (sw *ssWriter) Write(p []byte) (int, error) {
+	if sw.closed {
+		return 0, errWriterClosed
+	}
+	if sw.err != nil {
+		return 0, sw.err
+	}
+	n := 0
+	for len(p) > 0 {
+		take := sw.size - len(sw.buf)
+		if take > len(p) {
+			take = len(p)
+		}
+		sw.buf = append(sw.buf, p[:take]...)
+		p = p[take:]
+		n += take
+		if len(sw.buf) == sw.size {
+			if sw.err = sw.frame(SecretStreamTagMessage); sw.err != nil {
+				return n, sw.err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close encrypts whatever is still buffered as the final frame of the stream.
+func (sw *ssWriter) Close() error {
+	if sw.closed {
+		return errWriterClosed
+	}
+	sw.closed = true
+	if sw.err != nil {
+		return sw.err
+	}
+	return sw.frame(SecretStreamTagFinal)
+}
+
+// ErrSecretStreamTruncated is returned by the reader from NewReader when the
+// underlying stream ends before the final frame.
+var ErrSecretStreamTruncated = errors.New("secretstream truncated")
+
+type ssReader struct {
+	dec  *Decryptor
+	r    io.Reader
+	buf  []byte
+	err  error
+	done bool
+	// the frame being read, kept across timeouts: its length prefix, and
+	// once that is complete, its body; have counts the bytes read into
+	// whichever of the two is still being filled
+	length [4]byte
+	frame  []byte
+	have   int
+}
+
+// NewReader returns an io.Reader that decrypts a stream written by NewWriter
+// from underlying, given the header NewWriter returned. It gives io.EOF only
+// once the final frame has been read; if underlying ends earlier, it gives
+// ErrSecretStreamTruncated instead. A frame that was tampered with or
+// reordered gives an error as soon as it is read.
+//
+// A timeout from underlying, that is an error with a Timeout method that
+// reports true, such as a passed deadline on a net.Conn, is returned without
+// losing what was read of the current frame, so Read can simply be called
+// again. Any other error is final.
+func (k SecretStreamKey) NewReader(underlying io.Reader, header []byte) (io.Reader, error) {
+	dec, err := k.NewDecryptorChecked(header)
+	if err != nil {
+		return nil, err
+	}
+	return &ssReader{dec: dec, r: underlying}, nil
+}
+
+// fill reads into b[*have:] until b is full, advancing *have as it goes, so
+// that a call interrupted by an error can later be resumed.
+func (sr *ssReader) fill(b []byte, have *int) error {
+	for *have < len(b) {
+		n, err := sr.r.Read(b[*have:])
+		*have += n
+		if err != nil && *have < len(b) {
+			if err == io.EOF {
+				return ErrSecretStreamTruncated
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (sr *ssReader) next() error {
+	if sr.frame == nil {
+		if err := sr.fill(sr.length[:], &sr.have); err != nil {
+			return err
+		}
+		framelen := binary.BigEndian.Uint32(sr.length[:])
+		if framelen < uint32(SecretStreamOverhead) ||
+			framelen > uint32(secretStreamMaxChunk+SecretStreamOverhead) {
+			return fmt.Errorf("%w: secretstream frame has an invalid length", ErrDecryptionFailed)
+		}
+		sr.frame = make([]byte, framelen)
+		sr.have = 0
+	}
+	if err := sr.fill(sr.frame, &sr.have); err != nil {
+		return err
+	}
+	frame := sr.frame
+	sr.frame, sr.have = nil, 0
+	plain, tag, err := sr.dec.Pull(frame, sr.length[:])
+	if err != nil {
+		return err
+	}
+	sr.buf = plain
+	sr.done = tag == SecretStreamTagFinal
+	return nil
+}
+
+// isTimeout reports whether err is a timeout that can be retried, as
+// net.Error defines it.
+func isTimeout(err error) bool {
+	var te interface{ Timeout() bool }
+	return errors.As(err, &te) && te.Timeout()
+}
+
+func (sr *ssReader) Read(p []byte) (int, error) {
+	for len(sr.buf) == 0 {
+		if sr.err != nil {
+			return 0, sr.err
+		}
+		if sr.done {
+			return 0, io.EOF
+		}
+		if err := sr.next(); err != nil {
+			if isTimeout(err) {
+				return 0, err
+			}
+			sr.err = err
+		}
+	}
+	n := copy(p, sr.buf)
+	sr.buf = sr.buf[n:]
+	return n, nil
+}
+
+// GobEncode implements the gob.GobEncoder interface.
+func (k SecretStreamKey) GobEncode() ([]byte, error) {
+	return encodeKeyGob(gobSecretStreamKey, k)
+}
+
+// GobDecode implements the gob.GobDecoder interface, rejecting anything that
+// is not a secretstream key of the right length.
+func (k *SecretStreamKey) GobDecode(data []byte) error {
+	raw, err := decodeKeyGob(data, gobSecretStreamKey, SecretStreamKeyLength, "secretstream key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, returning a
+// copy of the raw key.
+func (k SecretStreamKey) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), k...), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. The
+// data is length-checked and copied, never aliased.
+func (k *SecretStreamKey) UnmarshalBinary(data []byte) error {
+	raw, err := copyKey(data, SecretStreamKeyLength, "secretstream key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, encoding the
+// key as lowercase hex.
+func (k SecretStreamKey) MarshalText() ([]byte, error) {
+	return []byte(BinToHex(k)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, reversing
+// MarshalText.
+func (k *SecretStreamKey) UnmarshalText(text []byte) error {
+	raw, err := unmarshalKeyText(text, SecretStreamKeyLength, "secretstream key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// SecretStreamKeyPEMType is the PEM block type used by Encode with
+// EncodingPEM.
+const SecretStreamKeyPEMType = "NATRIUM SECRETSTREAM KEY"
+
+// Encode serializes the key in the given encoding, as raw bytes, hex, PEM or
+// base64. Every encoding holds the secret in the clear.
+func (k SecretStreamKey) Encode(enc Encoding) ([]byte, error) {
+	return encodeKey(k, enc, SecretStreamKeyPEMType, SecretStreamKeyLength, "secretstream key")
+}
+
+// DecodeSecretStreamKey parses a key written by Encode with the same encoding.
+// Data of the wrong length, or a PEM block of another type, gives an error.
+func DecodeSecretStreamKey(data []byte, enc Encoding) (SecretStreamKey, error) {
+	return decodeKey(data, enc, SecretStreamKeyPEMType, SecretStreamKeyLength, "secretstream key")
+}
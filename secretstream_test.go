@@ -0,0 +1,260 @@
+package natrium
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+func TestSecretStream(t *testing.T) {
+	key := GenerateSecretStreamKey()
+	enc, header := key.NewEncryptor()
+	c1 := enc.Push([]byte("Hello"), nil, SecretStreamTagMessage)
+	c2 := enc.Push([]byte("World"), nil, SecretStreamTagFinal)
+	dec := key.NewDecryptor(header)
+	p1, tag, err := dec.Pull(c1, nil)
+	if err != nil || string(p1) != "Hello" || tag != SecretStreamTagMessage {
+		t.FailNow()
+	}
+	p2, tag, err := dec.Pull(c2, nil)
+	if err != nil || string(p2) != "World" || tag != SecretStreamTagFinal {
+		t.FailNow()
+	}
+	if _, _, err := dec.Pull(c2, nil); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestSecretStreamHeader(t *testing.T) {
+	key := GenerateSecretStreamKey()
+	enc, header := key.NewEncryptor()
+	for _, bad := range [][]byte{nil, header[:1], header[:SecretStreamHeaderLength-1], append(header, 0)} {
+		if _, err := key.NewDecryptorChecked(bad); err == nil {
+			t.FailNow()
+		}
+	}
+	dec, err := key.NewDecryptorChecked(header)
+	if err != nil {
+		t.FailNow()
+	}
+	if p, _, err := dec.Pull(enc.Push([]byte("hi"), nil, SecretStreamTagFinal), nil); err != nil || string(p) != "hi" {
+		t.FailNow()
+	}
+	// a header of the right length but the wrong contents fails on the first
+	// message, not before
+	dec, err = key.NewDecryptorChecked(make([]byte, SecretStreamHeaderLength))
+	if err != nil {
+		t.FailNow()
+	}
+	if _, _, err := dec.Pull(enc.Push([]byte("hi"), nil, SecretStreamTagFinal), nil); err != ErrDecryptionFailed {
+		t.FailNow()
+	}
+}
+
+func TestSecretStreamReorder(t *testing.T) {
+	key := GenerateSecretStreamKey()
+	enc, header := key.NewEncryptor()
+	c1 := enc.Push([]byte("Hello"), nil, SecretStreamTagMessage)
+	c2 := enc.Push([]byte("World"), nil, SecretStreamTagFinal)
+	dec := key.NewDecryptor(header)
+	if _, _, err := dec.Pull(c2, nil); err == nil {
+		t.FailNow()
+	}
+	dec = key.NewDecryptor(header)
+	if _, _, err := dec.Pull(c1[:len(c1)-1], nil); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestSecretStreamAD(t *testing.T) {
+	key := GenerateSecretStreamKey()
+	enc, header := key.NewEncryptor()
+	c1 := enc.Push([]byte("Hello"), []byte("seq 1"), SecretStreamTagPush)
+	c2 := enc.Push([]byte("World"), []byte("seq 2"), SecretStreamTagFinal)
+	if bytes.Contains(c1, []byte("seq 1")) {
+		t.FailNow()
+	}
+	dec := key.NewDecryptor(header)
+	if _, _, err := dec.Pull(c1, []byte("seq 2")); err != ErrDecryptionFailed {
+		t.FailNow()
+	}
+	if _, _, err := dec.Pull(c1, nil); err != ErrDecryptionFailed {
+		t.FailNow()
+	}
+	p1, tag, err := dec.Pull(c1, []byte("seq 1"))
+	if err != nil || string(p1) != "Hello" || tag != SecretStreamTagPush {
+		t.FailNow()
+	}
+	p2, tag, err := dec.Pull(c2, []byte("seq 2"))
+	if err != nil || string(p2) != "World" || tag != SecretStreamTagFinal {
+		t.FailNow()
+	}
+}
+
+func TestSecretStreamRekey(t *testing.T) {
+	key := GenerateSecretStreamKey()
+	enc, header := key.NewEncryptor()
+	c1 := enc.Push([]byte("one"), nil, SecretStreamTagMessage)
+	enc.Rekey()
+	c2 := enc.Push([]byte("two"), nil, SecretStreamTagRekey)
+	c3 := enc.Push([]byte("three"), nil, SecretStreamTagFinal)
+	dec := key.NewDecryptor(header)
+	if p, _, err := dec.Pull(c1, nil); err != nil || string(p) != "one" {
+		t.FailNow()
+	}
+	dec.Rekey()
+	if p, tag, err := dec.Pull(c2, nil); err != nil || string(p) != "two" || tag != SecretStreamTagRekey {
+		t.FailNow()
+	}
+	if p, _, err := dec.Pull(c3, nil); err != nil || string(p) != "three" {
+		t.FailNow()
+	}
+	// a decryptor that misses the explicit rekey falls out of sync
+	dec = key.NewDecryptor(header)
+	dec.Pull(c1, nil)
+	if _, _, err := dec.Pull(c2, nil); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestSecretStreamWriter(t *testing.T) {
+	key := GenerateSecretStreamKey()
+	var sink bytes.Buffer
+	w, header, err := key.NewWriter(&sink)
+	if err != nil {
+		t.FailNow()
+	}
+	message := RandomBytes(40000)
+	for i := 0; i < len(message); i += 1000 {
+		w.Write(message[i : i+1000])
+	}
+	if w.Close() != nil {
+		t.FailNow()
+	}
+	if _, err := w.Write(message); err == nil {
+		t.FailNow()
+	}
+	// 16 KiB, 16 KiB, then the rest in the final frame
+	dec := key.NewDecryptor(header)
+	var plain []byte
+	var tags []byte
+	for sink.Len() > 0 {
+		length := sink.Next(4)
+		frame := sink.Next(int(binary.BigEndian.Uint32(length)))
+		p, tag, err := dec.Pull(frame, length)
+		if err != nil {
+			t.FailNow()
+		}
+		plain = append(plain, p...)
+		tags = append(tags, tag)
+	}
+	if !bytes.Equal(plain, message) {
+		t.FailNow()
+	}
+	if !bytes.Equal(tags, []byte{SecretStreamTagMessage, SecretStreamTagMessage,
+		SecretStreamTagFinal}) {
+		t.FailNow()
+	}
+}
+
+func TestSecretStreamReader(t *testing.T) {
+	key := GenerateSecretStreamKey()
+	var sink bytes.Buffer
+	w, header, _ := key.NewWriterSize(&sink, 100)
+	message := RandomBytes(1234)
+	w.Write(message)
+	w.Close()
+	stream := sink.Bytes()
+	r, err := key.NewReader(bytes.NewReader(stream), header)
+	if err != nil {
+		t.FailNow()
+	}
+	plain, err := io.ReadAll(r)
+	if err != nil || !bytes.Equal(plain, message) {
+		t.FailNow()
+	}
+	// cut off the final frame
+	r, _ = key.NewReader(bytes.NewReader(stream[:len(stream)-50]), header)
+	if _, err := io.ReadAll(r); err != ErrSecretStreamTruncated {
+		t.FailNow()
+	}
+	// cut exactly at a frame boundary
+	r, _ = key.NewReader(bytes.NewReader(stream[:4+100+SecretStreamOverhead]), header)
+	if _, err := io.ReadAll(r); err != ErrSecretStreamTruncated {
+		t.FailNow()
+	}
+	// flip a bit in the second frame
+	tampered := append([]byte(nil), stream...)
+	tampered[2*(4+100+SecretStreamOverhead)-1] ^= 1
+	r, _ = key.NewReader(bytes.NewReader(tampered), header)
+	if _, err := io.ReadAll(r); err == nil || err == ErrSecretStreamTruncated {
+		t.FailNow()
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string { return "i/o timeout" }
+func (timeoutError) Timeout() bool { return true }
+
+// stutterReader hands out at most 7 bytes per Read, failing every other call
+// with a timeout.
+type stutterReader struct {
+	r     io.Reader
+	calls int
+}
+
+func (sr *stutterReader) Read(p []byte) (int, error) {
+	sr.calls++
+	if sr.calls%2 == 0 {
+		return 0, timeoutError{}
+	}
+	if len(p) > 7 {
+		p = p[:7]
+	}
+	return sr.r.Read(p)
+}
+
+func TestSecretStreamReaderTimeout(t *testing.T) {
+	key := GenerateSecretStreamKey()
+	var sink bytes.Buffer
+	w, header, _ := key.NewWriterSize(&sink, 100)
+	message := RandomBytes(345)
+	w.Write(message)
+	w.Close()
+	r, _ := key.NewReader(&stutterReader{r: bytes.NewReader(sink.Bytes())}, header)
+	var got []byte
+	buf := make([]byte, 64)
+	timeouts := 0
+	for {
+		n, err := r.Read(buf)
+		got = append(got, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if _, ok := err.(timeoutError); ok {
+			timeouts++
+			continue
+		}
+		if err != nil {
+			t.FailNow()
+		}
+	}
+	if timeouts == 0 || !bytes.Equal(got, message) {
+		t.FailNow()
+	}
+}
+
+func TestSecretStreamKeyEncode(t *testing.T) {
+	key := GenerateSecretStreamKey()
+	checkEncoding(t, []encodingCase{
+		{key, SecretStreamKeyPEMType, key.Encode, func(b []byte, e Encoding) ([]byte, error) { return DecodeSecretStreamKey(b, e) }},
+	})
+}
+
+func TestSecretStreamKeyGob(t *testing.T) {
+	key := GenerateSecretStreamKey()
+	var got SecretStreamKey
+	checkGob(t, key, key, &got, func() []byte { return got })
+}
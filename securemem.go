@@ -0,0 +1,134 @@
+package natrium
+
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #include <stdio.h>
+// #include <sodium.h>
+import "C"
+import (
+	"errors"
+	"unsafe"
+)
+
+// SecureBuffer is a buffer allocated outside the Go heap with sodium_malloc.
+// It is surrounded by guard pages, locked into memory so it is never swapped
+// out, and can be protected against all access while not in use.
+//
+// The buffer is only released by Free, and leaks if Free is never called.
+// There is deliberately no finalizer: the garbage collector cannot see the
+// slices returned by Bytes, so it could free the memory while one of them is
+// still in use.
+type SecureBuffer struct {
+	ptr  unsafe.Pointer
+	size int
+}
+
+// NewSecureBuffer allocates a SecureBuffer of the given size.
+func NewSecureBuffer(size int) (*SecureBuffer, error) {
+	return newSecureBuffer(C.sodium_malloc(C.size_t(size)), size)
+}
+
+// NewSecureArray allocates a SecureBuffer holding count elements of the given
+// size, failing rather than overflowing if count*size is too large.
+func NewSecureArray(count, size int) (*SecureBuffer, error) {
+	return newSecureBuffer(C.sodium_allocarray(C.size_t(count), C.size_t(size)), count*size)
+}
+
+var errSecureBufferFreed = errors.New("secure buffer has been freed")
+
+func newSecureBuffer(ptr unsafe.Pointer, size int) (*SecureBuffer, error) {
+	if ptr == nil {
+		return nil, errors.New("sodium_malloc could not allocate secure memory")
+	}
+	return &SecureBuffer{ptr, size}, nil
+}
+
+// Bytes returns a slice aliasing the buffer. The slice must not be used after
+// Free, and accessing it while the buffer is locked with Lock faults.
+func (sb *SecureBuffer) Bytes() []byte {
+	if sb.ptr == nil || sb.size == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(sb.ptr), sb.size)
+}
+
+// Lock makes the buffer inaccessible; any access faults until Unlock or
+// ReadOnly is called. Lock, ReadOnly and Unlock return an error once the
+// buffer has been freed.
+func (sb *SecureBuffer) Lock() error {
+	if sb.ptr == nil {
+		return errSecureBufferFreed
+	}
+	if C.sodium_mprotect_noaccess(sb.ptr) != 0 {
+		return errors.New("sodium_mprotect_noaccess failed")
+	}
+	return nil
+}
+
+// ReadOnly makes the buffer readable but not writable.
+func (sb *SecureBuffer) ReadOnly() error {
+	if sb.ptr == nil {
+		return errSecureBufferFreed
+	}
+	if C.sodium_mprotect_readonly(sb.ptr) != 0 {
+		return errors.New("sodium_mprotect_readonly failed")
+	}
+	return nil
+}
+
+// Unlock makes the buffer readable and writable again.
+func (sb *SecureBuffer) Unlock() error {
+	if sb.ptr == nil {
+		return errSecureBufferFreed
+	}
+	if C.sodium_mprotect_readwrite(sb.ptr) != 0 {
+		return errors.New("sodium_mprotect_readwrite failed")
+	}
+	return nil
+}
+
+// Free zeroes and releases the buffer, even if it is locked. Calling it more
+// than once is harmless.
+func (sb *SecureBuffer) Free() {
+	if sb.ptr == nil {
+		return
+	}
+	C.sodium_free(sb.ptr)
+	sb.ptr = nil
+}
+
+// Lock locks the memory backing b into RAM with sodium_mlock, so that it is
+// never swapped to disk, and excludes it from core dumps where the platform
+// allows. The amount of memory a process may lock is limited, by
+// RLIMIT_MEMLOCK on Linux and the BSDs and by the working set size on
+// Windows, and the error from the operating system is returned when the
+// limit is hit. Locking works on whole pages, so neighbouring data may be
+// locked as well.
+func Lock(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	rv, err := C.sodium_mlock(unsafe.Pointer(&b[0]), C.size_t(len(b)))
+	if rv != 0 {
+		if err == nil {
+			err = errors.New("sodium_mlock failed")
+		}
+		return err
+	}
+	return nil
+}
+
+// Unlock zeroes b and then unlocks the memory locked by Lock. Since the
+// contents are wiped, it should only be called once b is no longer needed.
+func Unlock(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	rv, err := C.sodium_munlock(unsafe.Pointer(&b[0]), C.size_t(len(b)))
+	if rv != 0 {
+		if err == nil {
+			err = errors.New("sodium_munlock failed")
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package natrium
+
+import (
+	"runtime"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestSecureBuffer(t *testing.T) {
+	sb, err := NewSecureBuffer(64)
+	if err != nil {
+		t.FailNow()
+	}
+	defer sb.Free()
+	buf := sb.Bytes()
+	if len(buf) != 64 {
+		t.FailNow()
+	}
+	RandBytes(buf)
+	copied := append([]byte(nil), buf...)
+	if sb.Lock() != nil || sb.ReadOnly() != nil {
+		t.FailNow()
+	}
+	if CTCompare(sb.Bytes(), copied) != 0 {
+		t.FailNow()
+	}
+	if sb.Unlock() != nil {
+		t.FailNow()
+	}
+	buf[0] ^= 1
+}
+
+func TestSecureArray(t *testing.T) {
+	sb, err := NewSecureArray(4, 16)
+	if err != nil || len(sb.Bytes()) != 64 {
+		t.FailNow()
+	}
+	sb.Free()
+	sb.Free()
+	if sb.Bytes() != nil {
+		t.FailNow()
+	}
+	if sb.Lock() == nil || sb.ReadOnly() == nil || sb.Unlock() == nil {
+		t.FailNow()
+	}
+}
+
+func TestLockSlice(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	if err := Lock(priv); err != nil {
+		t.Skip("cannot lock memory here:", err)
+	}
+	if priv.PublicKey().Verify([]byte("x"), priv.Sign([]byte("x"))) != nil {
+		t.FailNow()
+	}
+	if Unlock(priv) != nil || !isZero(priv) {
+		t.FailNow()
+	}
+	if Lock(nil) != nil || Unlock(nil) != nil {
+		t.FailNow()
+	}
+}
+
+func TestSecureBufferUnreachable(t *testing.T) {
+	// nothing frees the memory behind a slice from Bytes once the
+	// SecureBuffer itself is unreachable
+	var slices [][]byte
+	for i := 0; i < 16; i++ {
+		sb, err := NewSecureBuffer(128)
+		if err != nil {
+			t.FailNow()
+		}
+		slices = append(slices, sb.Bytes())
+	}
+	runtime.GC()
+	time.Sleep(10 * time.Millisecond)
+	runtime.GC()
+	for _, b := range slices {
+		RandBytes(b)
+		wipe(b)
+		(&SecureBuffer{unsafe.Pointer(&b[0]), len(b)}).Free()
+	}
+}
@@ -0,0 +1,115 @@
+package natrium
+
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #include <stdio.h>
+// #include <sodium.h>
+import "C"
+import "hash"
+
+// SHA256 returns the SHA-256 hash of a message.
+func SHA256(message []byte) []byte {
+	out := make([]byte, C.crypto_hash_sha256_BYTES)
+	rv := C.crypto_hash_sha256(g2cbt(out), g2cbt(message), C.ulonglong(len(message)))
+	if rv != 0 {
+		panic("crypto_hash_sha256 returned non-zero")
+	}
+	return out
+}
+
+// SHA512 returns the SHA-512 hash of a message.
+func SHA512(message []byte) []byte {
+	out := make([]byte, C.crypto_hash_sha512_BYTES)
+	rv := C.crypto_hash_sha512(g2cbt(out), g2cbt(message), C.ulonglong(len(message)))
+	if rv != 0 {
+		panic("crypto_hash_sha512 returned non-zero")
+	}
+	return out
+}
+
+type sha256Hasher struct {
+	state C.crypto_hash_sha256_state
+}
+
+// NewSHA256 creates an incremental SHA-256 hasher.
+func NewSHA256() hash.Hash {
+	toret := new(sha256Hasher)
+	toret.Reset()
+	return toret
+}
+
+func (sh *sha256Hasher) Write(b []byte) (int, error) {
+	rv := C.crypto_hash_sha256_update(&sh.state, g2cbt(b), C.ulonglong(len(b)))
+	if rv != 0 {
+		panic("crypto_hash_sha256_update returned non-zero")
+	}
+	return len(b), nil
+}
+
+func (sh *sha256Hasher) Sum(b []byte) []byte {
+	tmp := *sh
+	out := make([]byte, C.crypto_hash_sha256_BYTES)
+	rv := C.crypto_hash_sha256_final(&tmp.state, g2cbt(out))
+	if rv != 0 {
+		panic("crypto_hash_sha256_final returned non-zero")
+	}
+	return append(b, out...)
+}
+
+func (sh *sha256Hasher) Reset() {
+	rv := C.crypto_hash_sha256_init(&sh.state)
+	if rv != 0 {
+		panic("crypto_hash_sha256_init returned non-zero")
+	}
+}
+
+func (sh *sha256Hasher) Size() int {
+	return C.crypto_hash_sha256_BYTES
+}
+
+func (sh *sha256Hasher) BlockSize() int {
+	return 64
+}
+
+type sha512Hasher struct {
+	state C.crypto_hash_sha512_state
+}
+
+// NewSHA512 creates an incremental SHA-512 hasher.
+func NewSHA512() hash.Hash {
+	toret := new(sha512Hasher)
+	toret.Reset()
+	return toret
+}
+
+func (sh *sha512Hasher) Write(b []byte) (int, error) {
+	rv := C.crypto_hash_sha512_update(&sh.state, g2cbt(b), C.ulonglong(len(b)))
+	if rv != 0 {
+		panic("crypto_hash_sha512_update returned non-zero")
+	}
+	return len(b), nil
+}
+
+func (sh *sha512Hasher) Sum(b []byte) []byte {
+	tmp := *sh
+	out := make([]byte, C.crypto_hash_sha512_BYTES)
+	rv := C.crypto_hash_sha512_final(&tmp.state, g2cbt(out))
+	if rv != 0 {
+		panic("crypto_hash_sha512_final returned non-zero")
+	}
+	return append(b, out...)
+}
+
+func (sh *sha512Hasher) Reset() {
+	rv := C.crypto_hash_sha512_init(&sh.state)
+	if rv != 0 {
+		panic("crypto_hash_sha512_init returned non-zero")
+	}
+}
+
+func (sh *sha512Hasher) Size() int {
+	return C.crypto_hash_sha512_BYTES
+}
+
+func (sh *sha512Hasher) BlockSize() int {
+	return 128
+}
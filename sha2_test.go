@@ -0,0 +1,29 @@
+package natrium
+
+import "testing"
+
+func TestSHA2(t *testing.T) {
+	abc := []byte("abc")
+	if HexEncode(SHA256(abc)) !=
+		"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.FailNow()
+	}
+	if HexEncode(SHA512(abc)) != "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a"+
+		"2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f" {
+		t.FailNow()
+	}
+	h256 := NewSHA256()
+	h512 := NewSHA512()
+	h256.Write([]byte("a"))
+	h512.Write([]byte("a"))
+	h256.Write([]byte("bc"))
+	h512.Write([]byte("bc"))
+	if CTCompare(h256.Sum(nil), SHA256(abc)) != 0 || CTCompare(h512.Sum(nil), SHA512(abc)) != 0 {
+		t.FailNow()
+	}
+	h256.Reset()
+	if HexEncode(h256.Sum(nil)) !=
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,113 @@
+package natrium
+
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #include <stdio.h>
+// #include <sodium.h>
+import "C"
+import (
+	"encoding/binary"
+	"sync"
+)
+
+// ShortHashKeyLength is the length of the key passed to ShortHash.
+const ShortHashKeyLength int = C.crypto_shorthash_KEYBYTES
+
+// ShortHashLength is the length of the output of ShortHash.
+const ShortHashLength int = C.crypto_shorthash_BYTES
+
+// ShortHash uses the keyed SipHash-2-4 algorithm to compute a short hash of a
+// message, suitable for hash tables that must resist hash-flooding attacks.
+// It is not a cryptographic hash and must not be used as one.
+func ShortHash(message, key []byte) []byte {
+	if len(key) != ShortHashKeyLength {
+		panic("short hash key has the wrong length")
+	}
+	out := make([]byte, ShortHashLength)
+	rv := C.crypto_shorthash(g2cbt(out), g2cbt(message), C.ulonglong(len(message)), g2cbt(key))
+	if rv != 0 {
+		panic("crypto_shorthash returned non-zero")
+	}
+	return out
+}
+
+// ShortHashUint64 is like ShortHash, but returns the result as an integer.
+func ShortHashUint64(message, key []byte) uint64 {
+	return binary.LittleEndian.Uint64(ShortHash(message, key))
+}
+
+// ShortHash128KeyLength is the length of the key passed to ShortHash128.
+const ShortHash128KeyLength int = C.crypto_shorthash_siphashx24_KEYBYTES
+
+// ShortHash128Length is the length of the output of ShortHash128.
+const ShortHash128Length int = C.crypto_shorthash_siphashx24_BYTES
+
+// ShortHash128 is like ShortHash, but uses SipHash-2-4 with a 128-bit output
+// (SipHashx24) for when 64 bits are too few to rule out collisions.
+func ShortHash128(message, key []byte) []byte {
+	if len(key) != ShortHash128KeyLength {
+		panic("short hash key has the wrong length")
+	}
+	out := make([]byte, ShortHash128Length)
+	rv := C.crypto_shorthash_siphashx24(g2cbt(out), g2cbt(message), C.ulonglong(len(message)),
+		g2cbt(key))
+	if rv != 0 {
+		panic("crypto_shorthash_siphashx24 returned non-zero")
+	}
+	return out
+}
+
+// TokenSet is a set of secret tokens, such as API keys, that can be checked
+// for membership without leaking their contents through timing. It is safe
+// for concurrent use.
+//
+// A map[string]bool leaks twice: the hash of a guess determines which bucket
+// is searched, and the final string comparison stops at the first differing
+// byte, so an attacker who can time many lookups can recover a token byte by
+// byte. TokenSet instead buckets tokens by SipHash under a random key chosen
+// when the set is made, so bucket choice reveals nothing to someone who does
+// not know the key, and compares candidates with MemCmp, which takes the same
+// time wherever the bytes differ. Only the length of a guess and the number
+// of tokens sharing its bucket can affect timing. Tokens should therefore be
+// long random strings of a fixed length, which also makes guessing them
+// hopeless in the first place.
+type TokenSet struct {
+	mu      sync.RWMutex
+	key     []byte
+	buckets map[uint64][][]byte
+}
+
+// NewTokenSet returns an empty TokenSet.
+func NewTokenSet() *TokenSet {
+	return &TokenSet{
+		key:     RandomBytes(ShortHashKeyLength),
+		buckets: make(map[uint64][][]byte),
+	}
+}
+
+// Add adds a copy of token to the set. Adding a token twice is harmless.
+func (ts *TokenSet) Add(token []byte) {
+	h := ShortHashUint64(token, ts.key)
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	for _, candidate := range ts.buckets[h] {
+		if MemCmp(candidate, token) {
+			return
+		}
+	}
+	ts.buckets[h] = append(ts.buckets[h], append([]byte(nil), token...))
+}
+
+// Contains reports whether token is in the set.
+func (ts *TokenSet) Contains(token []byte) bool {
+	h := ShortHashUint64(token, ts.key)
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	found := false
+	for _, candidate := range ts.buckets[h] {
+		// no early exit, so a match takes as long as a miss
+		if MemCmp(candidate, token) {
+			found = true
+		}
+	}
+	return found
+}
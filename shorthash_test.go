@@ -0,0 +1,60 @@
+package natrium
+
+import "testing"
+
+func TestShortHash(t *testing.T) {
+	key := make([]byte, ShortHashKeyLength)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	// first vector from the SipHash reference implementation
+	if HexEncode(ShortHash(nil, key)) != "310e0edd47db6f72" {
+		t.FailNow()
+	}
+	if ShortHashUint64(nil, key) != 0x726fdb47dd0e0e31 {
+		t.FailNow()
+	}
+}
+
+func TestShortHash128(t *testing.T) {
+	key := make([]byte, ShortHash128KeyLength)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	// first vector of the reference implementation's 128-bit table
+	if HexEncode(ShortHash128(nil, key)) != "a3817f04ba25a8e66df67214c7550293" {
+		t.FailNow()
+	}
+	if len(ShortHash128([]byte("Hello World"), key)) != ShortHash128Length {
+		t.FailNow()
+	}
+}
+
+func TestTokenSet(t *testing.T) {
+	ts := NewTokenSet()
+	tokens := [][]byte{RandomBytes(32), RandomBytes(32), []byte("short")}
+	for _, token := range tokens {
+		ts.Add(token)
+	}
+	ts.Add(tokens[0])
+	if len(ts.buckets[ShortHashUint64(tokens[0], ts.key)]) != 1 {
+		t.FailNow()
+	}
+	for _, token := range tokens {
+		if !ts.Contains(token) || !ts.Contains(append([]byte(nil), token...)) {
+			t.FailNow()
+		}
+	}
+	almost := append([]byte(nil), tokens[0]...)
+	almost[31] ^= 1
+	for _, absent := range [][]byte{nil, RandomBytes(32), almost, tokens[0][:31], []byte("shorter")} {
+		if ts.Contains(absent) {
+			t.FailNow()
+		}
+	}
+	// the set keeps its own copy
+	tokens[1][0] ^= 1
+	if ts.Contains(tokens[1]) {
+		t.FailNow()
+	}
+}
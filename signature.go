@@ -1,68 +1,249 @@
 package natrium
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"unsafe"
 )
 
 // #cgo darwin CFLAGS: -I/usr/local/include
-// #cgo darwin LDFLAGS: /usr/local/lib/libsodium.a
-// #cgo linux windows android LDFLAGS: -Wl,-Bstatic -lsodium -Wl,-Bdynamic
 // #include <stdio.h>
 // #include <sodium.h>
 import "C"
 
 // EdDSAPrivate represents an Ed25519 private key.
+//
+// Like every key type in this package, it is a byte slice, so assigning it to
+// another variable shares the underlying bytes: modifying or destroying one
+// copy silently changes the other. Use Clone to get a separate copy, for
+// instance before handing the key to a goroutine that may outlive the caller.
+//
+// Signing only reads the key: libsodium keeps no state between calls to
+// crypto_sign_detached, so any number of goroutines may call Sign, SignSafe
+// and the other one-shot methods with the same EdDSAPrivate at once. What is
+// not safe is to Destroy the key, or otherwise write to its bytes, while it is
+// in use, or to pass the same dst to concurrent SignInto calls.
+// A Signer, by contrast, holds the state of one signature in progress and
+// belongs to a single goroutine; create one per goroutine with NewSigner.
 type EdDSAPrivate []byte
 
-// EdDSAPublic represents an Ed25519 public key.
+// EdDSAPublic represents an Ed25519 public key. Like EdDSAPrivate, it can be
+// used to verify from many goroutines at once, but a Verifier cannot.
 type EdDSAPublic []byte
 
+// String returns the key in hex, prefixed with its kind.
 func (k EdDSAPublic) String() string {
 	return fmt.Sprintf("dsapub:%x", []byte(k))
 }
 
+// String returns a redacted placeholder so the key is not printed by accident.
 func (k EdDSAPrivate) String() string {
+	return redacted("dsaprv", k)
+}
+
+// Format implements fmt.Formatter, printing String for every verb.
+func (k EdDSAPrivate) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, k.String())
+}
+
+// HexString returns the key in hex after a "dsaprv:" prefix, the format
+// EdDSAPrivateFromHex reads. Unlike String, it exposes the whole secret.
+func (k EdDSAPrivate) HexString() string {
 	return fmt.Sprintf("dsaprv:%x", []byte(k))
 }
 
 // EdDSAPublicLength is the length of an EdDSA public key.
-var EdDSAPublicLength = C.crypto_sign_PUBLICKEYBYTES
+const EdDSAPublicLength int = C.crypto_sign_PUBLICKEYBYTES
 
 // EdDSAPrivateLength is the length of an EdDSA private key.
-var EdDSAPrivateLength = C.crypto_sign_SECRETKEYBYTES
+const EdDSAPrivateLength int = C.crypto_sign_SECRETKEYBYTES
 
 // EdDSASignatureLength is the length of an EdDSA signature.
-var EdDSASignatureLength = C.crypto_sign_BYTES
+const EdDSASignatureLength int = C.crypto_sign_BYTES
+
+// EdDSASeedLength is the length of the seed accepted by EdDSAGenerateKeyFromSeed.
+const EdDSASeedLength int = C.crypto_sign_SEEDBYTES
+
+// EdDSAPublicArray is an EdDSAPublic of fixed size, for code that wants the
+// compiler to rule out keys of the wrong length.
+type EdDSAPublicArray [C.crypto_sign_PUBLICKEYBYTES]byte
+
+// EdDSAPrivateArray is the fixed-size counterpart of EdDSAPrivate.
+type EdDSAPrivateArray [C.crypto_sign_SECRETKEYBYTES]byte
+
+// From fills the array with the given public key, failing if it has the wrong
+// length.
+func (a *EdDSAPublicArray) From(k EdDSAPublic) error {
+	if len(k) != len(a) {
+		return keyLengthError("EdDSA public key", len(k), len(a))
+	}
+	copy(a[:], k)
+	return nil
+}
+
+// To returns the key as an EdDSAPublic, copied out of the array.
+func (a *EdDSAPublicArray) To() EdDSAPublic {
+	return append(EdDSAPublic(nil), a[:]...)
+}
+
+// From fills the array with the given private key, failing if it has the wrong
+// length.
+func (a *EdDSAPrivateArray) From(k EdDSAPrivate) error {
+	if len(k) != len(a) {
+		return keyLengthError("EdDSA private key", len(k), len(a))
+	}
+	copy(a[:], k)
+	return nil
+}
+
+// To returns the key as an EdDSAPrivate. The result is a copy, so destroying
+// it leaves the array alone.
+func (a *EdDSAPrivateArray) To() EdDSAPrivate {
+	return append(EdDSAPrivate(nil), a[:]...)
+}
+
+// NewEdDSAPublic checks that b has the length of an EdDSA public key and
+// returns a copy of it as one. Keys read off the wire or out of a config file
+// should go through here rather than being cast directly.
+func NewEdDSAPublic(b []byte) (EdDSAPublic, error) {
+	return copyKey(b, EdDSAPublicLength, "EdDSA public key")
+}
+
+// EdDSAPublicFromHex parses an EdDSA public key written in hex, as found in
+// configuration files. The "dsapub:" prefix printed by String is accepted but
+// not required.
+func EdDSAPublicFromHex(s string) (EdDSAPublic, error) {
+	return keyFromHex(s, "dsapub:", EdDSAPublicLength, "EdDSA public key")
+}
+
+// MustEdDSAPublicFromHex is like EdDSAPublicFromHex, but panics on error. It is meant for
+// initializing package-level variables from constants.
+func MustEdDSAPublicFromHex(s string) EdDSAPublic {
+	return mustKey(EdDSAPublicFromHex(s))
+}
+
+// NewEdDSAPrivate is like NewEdDSAPublic, but for private keys.
+func NewEdDSAPrivate(b []byte) (EdDSAPrivate, error) {
+	return copyKey(b, EdDSAPrivateLength, "EdDSA private key")
+}
+
+// EdDSAPrivateFromHex parses an EdDSA private key written in hex, with or
+// without the "dsaprv:" prefix. Decoding runs in constant time.
+func EdDSAPrivateFromHex(s string) (EdDSAPrivate, error) {
+	return keyFromHex(s, "dsaprv:", EdDSAPrivateLength, "EdDSA private key")
+}
+
+// MustEdDSAPrivateFromHex is like EdDSAPrivateFromHex, but panics on error.
+func MustEdDSAPrivateFromHex(s string) EdDSAPrivate {
+	return mustKey(EdDSAPrivateFromHex(s))
+}
+
+// Equal reports whether x is the same EdDSA public key, in constant time. Its
+// signature matches the Equal method of the standard library's key types.
+func (k EdDSAPublic) Equal(x crypto.PublicKey) bool {
+	other, ok := x.(EdDSAPublic)
+	return ok && MemCmp(k, other)
+}
+
+// Clone returns a copy of the public key that shares no memory with k.
+func (k EdDSAPublic) Clone() EdDSAPublic {
+	return append(EdDSAPublic(nil), k...)
+}
+
+// Valid reports whether k is the canonical encoding of a point on the Ed25519
+// curve, in the prime-order subgroup and not of small order. Like
+// BoxPublic.Valid, it only screens out malformed keys; it does not prove that
+// anyone holds the private key.
+func (k EdDSAPublic) Valid() bool {
+	return len(k) == EdDSAPublicLength && C.crypto_core_ed25519_is_valid_point(g2cbt(k)) == 1
+}
+
+// Fingerprint returns a short, stable summary of the public key for showing
+// in user interfaces and logs, as 16 colon-separated hex bytes in the manner
+// of SSH fingerprints. It is the 16-byte BLAKE2b of "natrium eddsa " followed
+// by the key, so the fingerprints of different keys effectively never
+// collide, and never match that of a box key with the same bytes.
+func (k EdDSAPublic) Fingerprint() string {
+	return fingerprint("natrium eddsa ", k)
+}
+
+// Equal reports, in constant time, whether x is the same EdDSA private key.
+func (k EdDSAPrivate) Equal(x crypto.PrivateKey) bool {
+	other, ok := x.(EdDSAPrivate)
+	return ok && MemCmp(k, other)
+}
+
+// Clone returns an independent copy of the private key, which stays intact
+// whatever later happens to k, including Destroy.
+func (k EdDSAPrivate) Clone() EdDSAPrivate {
+	return append(EdDSAPrivate(nil), k...)
+}
 
 // EdDSAGenerateKey generates an EdDSA private key. The public key
 // can be derived from the private key, so there is no issue.
 // Keys are represented by byte slices, and can be cast to and from them.
 func EdDSAGenerateKey() EdDSAPrivate {
+	// the same as crypto_sign_keypair, but with the seed from RandBytes
+	seed := RandomSeed()
+	defer wipe(seed)
+	return EdDSAGenerateKeyFromSeed(seed)
+}
+
+// EdDSADeriveKey derives an EdDSA private key from an arbitrary seed.
+func EdDSADeriveKey(seed []byte) EdDSAPrivate {
+	mustInit()
 	priv := make([]byte, EdDSAPrivateLength)
 	publ := make([]byte, EdDSAPublicLength)
-	rv := C.crypto_sign_keypair((*C.uchar)(&publ[0]), (*C.uchar)(&priv[0]))
+	hash := SecureHash(seed, nil)
+	defer wipe(hash)
+	rv := C.crypto_sign_seed_keypair(g2cbt(publ), g2cbt(priv), g2cbt(hash[:EdDSASeedLength]))
 	if rv != 0 {
+		wipe(priv)
 		panic("crypto_sign_keypair returned non-zero")
 	}
 	return priv
 }
 
-// EdDSADeriveKey derives an EdDSA private key from an arbitrary seed.
-func EdDSADeriveKey(seed []byte) EdDSAPrivate {
+// RandomSeed returns a fresh random seed of EdDSASeedLength bytes for
+// EdDSAGenerateKeyFromSeed. Storing the seed instead of the private key halves
+// the size of a backup, and the seed must be protected just as carefully.
+func RandomSeed() []byte {
+	return RandomBytes(EdDSASeedLength)
+}
+
+// EdDSAGenerateKeyFromSeed deterministically generates an EdDSA private key
+// from a seed of exactly EdDSASeedLength bytes. Unlike EdDSADeriveKey, the
+// seed is used directly, so the same seed always gives the same key as any
+// other libsodium-based implementation would.
+func EdDSAGenerateKeyFromSeed(seed []byte) EdDSAPrivate {
+	mustInit()
+	if len(seed) != EdDSASeedLength {
+		panic("EdDSA seed has the wrong length")
+	}
 	priv := make([]byte, EdDSAPrivateLength)
 	publ := make([]byte, EdDSAPublicLength)
-	seed = SecureHash(seed, nil)[:C.crypto_sign_SEEDBYTES]
 	rv := C.crypto_sign_seed_keypair(g2cbt(publ), g2cbt(priv), g2cbt(seed))
 	if rv != 0 {
-		panic("crypto_sign_keypair returned non-zero")
+		wipe(priv)
+		panic("crypto_sign_seed_keypair returned non-zero")
 	}
 	return priv
 }
 
 // PublicKey obtains the public component of an EdDSA private key.
 func (k EdDSAPrivate) PublicKey() EdDSAPublic {
+	if len(k) != EdDSAPrivateLength {
+		panic("EdDSA private key has the wrong length")
+	}
 	toret := make([]byte, EdDSAPublicLength)
 	rv := C.crypto_sign_ed25519_sk_to_pk((*C.uchar)(&toret[0]),
 		(*C.uchar)(&k[0]))
@@ -72,23 +253,183 @@ func (k EdDSAPrivate) PublicKey() EdDSAPublic {
 	return toret
 }
 
+// Destroy wipes the private key from memory, after which signing with it
+// fails: SignSafe, SignInto and AppendSignature return an error, and Sign,
+// SignAttached, SignMulti and NewSigner panic. Slices sharing the key's memory
+// are wiped with it, but copies made with Clone or Seed are not.
+func (k EdDSAPrivate) Destroy() {
+	wipe(k)
+}
+
+var errEdDSADestroyed = errors.New("EdDSA private key has been destroyed")
+
+// checkKey returns an error unless k can sign: it must have the right length
+// and not have been wiped by Destroy. Rejecting an all-zero key loses nothing,
+// since its public half is a point of small order that no generated key has.
+func (k EdDSAPrivate) checkKey() error {
+	if len(k) != EdDSAPrivateLength {
+		return keyLengthError("EdDSA private key", len(k), EdDSAPrivateLength)
+	}
+	if isZero(k) {
+		return errEdDSADestroyed
+	}
+	return nil
+}
+
+// EnableAutoWipe moves the key into a fresh allocation of its own, which is
+// zeroed with sodium_memzero once the garbage collector finds it unreachable,
+// and returns the moved key. k itself is wiped, so the caller should use the
+// returned key in its place and drop every other reference to k.
+//
+// Moving the key is what makes this safe for any k: a finalizer can only guard
+// a whole Go heap allocation, so one set on a key in the middle of a larger
+// buffer would wait for the entire buffer, and one set on a SecureBuffer or C
+// memory would abort the program. The wipe is still only a best-effort
+// backstop for Destroy. The runtime does not promise to run finalizers
+// promptly, or at all before the program exits, and copies of the bytes made
+// with Clone, Seed or by the caller are not wiped.
+func (k EdDSAPrivate) EnableAutoWipe() EdDSAPrivate {
+	return autoWipe(k, nil)
+}
+
+// autoWipe implements EnableAutoWipe. done, if not nil, is called by the
+// finalizer once the key has been zeroed, so tests can see it happen.
+func autoWipe(k EdDSAPrivate, done func(p *[EdDSAPrivateLength]byte)) EdDSAPrivate {
+	if len(k) != EdDSAPrivateLength {
+		panic("EdDSA private key has the wrong length")
+	}
+	p := new([EdDSAPrivateLength]byte)
+	copy(p[:], k)
+	wipe(k)
+	runtime.SetFinalizer(p, func(p *[EdDSAPrivateLength]byte) {
+		C.sodium_memzero(unsafe.Pointer(p), C.size_t(EdDSAPrivateLength))
+		if done != nil {
+			done(p)
+		}
+	})
+	return p[:]
+}
+
+// Seed returns a fresh copy of the seed from which the private key was
+// generated. Passing it to EdDSAGenerateKeyFromSeed gives back the same key.
+func (k EdDSAPrivate) Seed() []byte {
+	if len(k) != EdDSAPrivateLength {
+		panic("EdDSA private key has the wrong length")
+	}
+	toret := make([]byte, EdDSASeedLength)
+	rv := C.crypto_sign_ed25519_sk_to_seed(g2cbt(toret), g2cbt(k))
+	if rv != 0 {
+		panic("crypto_sign_ed25519_sk_to_seed returned non-zero")
+	}
+	return toret
+}
+
 // Sign signs a message using the given EdDSA private key, returning the signature.
 func (k EdDSAPrivate) Sign(message []byte) []byte {
-	signature := make([]byte, EdDSASignatureLength)
+	signature, err := k.SignSafe(message)
+	if err != nil {
+		panic(err.Error())
+	}
+	return signature
+}
+
+// SignSafe is like Sign, but returns an error instead of panicking when the
+// private key is malformed or signing fails.
+func (k EdDSAPrivate) SignSafe(message []byte) ([]byte, error) {
+	return k.SignInto(nil, message)
+}
+
+// SignInto is like SignSafe, but writes the signature into dst when it has
+// room for EdDSASignatureLength bytes, returning dst resliced to the
+// signature. A smaller dst is ignored and a new slice allocated instead, so a
+// scratch buffer can be reused across calls without any allocation.
+func (k EdDSAPrivate) SignInto(dst, message []byte) ([]byte, error) {
+	if err := k.checkKey(); err != nil {
+		return nil, err
+	}
+	if cap(dst) < EdDSASignatureLength {
+		dst = make([]byte, EdDSASignatureLength)
+	}
+	signature := dst[:EdDSASignatureLength]
 	rv := C.crypto_sign_detached(
-		(*C.uchar)(&signature[0]),
+		g2cbt(signature),
 		nil,
-		(*C.uchar)(&message[0]),
+		g2cbt(message),
 		C.ulonglong(len(message)),
 		(*C.uchar)(&k[0]))
 	if rv != 0 {
-		panic("crypto_sign_detached returned non-zero")
+		return nil, errors.New("crypto_sign_detached returned non-zero")
 	}
-	return signature
+	return signature, nil
+}
+
+// AppendSignature appends the signature of message to dst and returns the
+// extended slice, growing dst if needed, like the append built-in. On error,
+// dst is returned unchanged.
+func (k EdDSAPrivate) AppendSignature(dst, message []byte) ([]byte, error) {
+	n := len(dst)
+	if cap(dst)-n < EdDSASignatureLength {
+		grown := make([]byte, n, n+EdDSASignatureLength)
+		copy(grown, dst)
+		dst = grown
+	}
+	if _, err := k.SignInto(dst[n:], message); err != nil {
+		return dst[:n], err
+	}
+	return dst[:n+EdDSASignatureLength], nil
+}
+
+// Public returns the public component of the private key. Together with
+// CryptoSigner, it lets an EdDSAPrivate interoperate with the standard
+// library's crypto.Signer interface.
+func (k EdDSAPrivate) Public() crypto.PublicKey {
+	return k.PublicKey()
+}
+
+type stdSigner struct {
+	key EdDSAPrivate
+}
+
+func (ss stdSigner) Public() crypto.PublicKey {
+	return ss.key.PublicKey()
+}
+
+func (ss stdSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts != nil && opts.HashFunc() != crypto.Hash(0) {
+		return nil, errors.New("EdDSA cannot sign a prehashed digest")
+	}
+	return ss.key.SignSafe(digest)
+}
+
+// CryptoSigner wraps the private key in an object implementing crypto.Signer.
+// As with Ed25519 in the standard library, the digest passed to its Sign
+// method is really the whole message, and opts.HashFunc() must be zero. The
+// rand argument is ignored, since EdDSA signing is deterministic.
+func (k EdDSAPrivate) CryptoSigner() crypto.Signer {
+	return stdSigner{k}
+}
+
+// SignAttached signs a message using the given EdDSA private key, returning
+// the signature and the message combined into one blob that can be checked
+// with EdDSAPublic.Open.
+func (k EdDSAPrivate) SignAttached(message []byte) []byte {
+	if err := k.checkKey(); err != nil {
+		panic(err.Error())
+	}
+	signed := make([]byte, len(message)+EdDSASignatureLength)
+	rv := C.crypto_sign(g2cbt(signed), nil, g2cbt(message),
+		C.ulonglong(len(message)), g2cbt(k))
+	if rv != 0 {
+		panic("crypto_sign returned non-zero")
+	}
+	return signed
 }
 
 // ToECDH converts an EdDSA private key deterministically to a ECDH private key.
 func (k EdDSAPrivate) ToECDH() ECDHPrivate {
+	if len(k) != EdDSAPrivateLength {
+		panic("EdDSA private key has the wrong length")
+	}
 	out := make([]byte, ECDHKeyLength)
 	rv := C.crypto_sign_ed25519_sk_to_curve25519(g2cbt(out), g2cbt(k))
 	if rv != 0 {
@@ -97,32 +438,358 @@ func (k EdDSAPrivate) ToECDH() ECDHPrivate {
 	return out
 }
 
+// ToCurve25519 converts an EdDSA private key deterministically to a box
+// private key, whose public key is the one EdDSAPublic.ToCurve25519 derives
+// from the same identity.
+func (k EdDSAPrivate) ToCurve25519() BoxPrivate {
+	if len(k) != EdDSAPrivateLength {
+		panic("EdDSA private key has the wrong length")
+	}
+	out := make([]byte, C.crypto_scalarmult_curve25519_BYTES)
+	rv := C.crypto_sign_ed25519_sk_to_curve25519(g2cbt(out), g2cbt(k))
+	if rv != 0 {
+		panic("crypto_sign_ed25519_sk_to_curve25519 returned non-zero")
+	}
+	return out
+}
+
+// FromStdPublicKey converts a public key from the standard library's
+// crypto/ed25519 package. The two encodings are identical, so this is a copy.
+func FromStdPublicKey(publ ed25519.PublicKey) EdDSAPublic {
+	return append(EdDSAPublic(nil), publ...)
+}
+
+// StdPublicKey converts the public key to one usable with crypto/ed25519.
+func (k EdDSAPublic) StdPublicKey() ed25519.PublicKey {
+	return append(ed25519.PublicKey(nil), k...)
+}
+
+// FromStdPrivateKey converts a private key from crypto/ed25519. The key is
+// rebuilt from its seed rather than copied, so that its public half is
+// guaranteed to be consistent.
+func FromStdPrivateKey(priv ed25519.PrivateKey) EdDSAPrivate {
+	return EdDSAGenerateKeyFromSeed(priv.Seed())
+}
+
+// StdPrivateKey converts the private key to one usable with crypto/ed25519,
+// likewise going through the seed.
+func (k EdDSAPrivate) StdPrivateKey() ed25519.PrivateKey {
+	return ed25519.NewKeyFromSeed(k.Seed())
+}
+
+// MarshalJSON implements the MarshalJSON interface. The private key is encoded
+// in full, as a base64 string exactly like EdDSAPublic, so any struct holding
+// an EdDSAPrivate will expose the secret when marshaled. Only marshal private
+// keys into storage that is itself protected.
+func (k EdDSAPrivate) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]byte(k))
+}
+
+// UnmarshalJSON implements the UnmarshalJSON interface, reversing MarshalJSON.
+func (k *EdDSAPrivate) UnmarshalJSON(data []byte) error {
+	raw, err := unmarshalKeyJSON(data, EdDSAPrivateLength, "EdDSA private key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
 // MarshalJSON implements the MarshalJSON interface.
 func (k EdDSAPublic) MarshalJSON() ([]byte, error) {
 	return json.Marshal([]byte(k))
 }
 
+// UnmarshalJSON implements the UnmarshalJSON interface, reversing MarshalJSON.
+func (k *EdDSAPublic) UnmarshalJSON(data []byte) error {
+	raw, err := unmarshalKeyJSON(data, EdDSAPublicLength, "EdDSA public key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// PEM block types used by MarshalPEM.
+const (
+	EdDSAPublicPEMType  = "NATRIUM EDDSA PUBLIC KEY"
+	EdDSAPrivatePEMType = "NATRIUM EDDSA PRIVATE KEY"
+)
+
+// MarshalPEM encodes the public key as a PEM block of type
+// EdDSAPublicPEMType.
+func (k EdDSAPublic) MarshalPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: EdDSAPublicPEMType, Bytes: k})
+}
+
+// ParseEdDSAPublicPEM decodes a public key encoded by MarshalPEM. It is
+// DecodeEdDSAPublic with EncodingPEM.
+func ParseEdDSAPublicPEM(data []byte) (EdDSAPublic, error) {
+	return DecodeEdDSAPublic(data, EncodingPEM)
+}
+
+// MarshalPEM encodes the private key as a PEM block of type
+// EdDSAPrivatePEMType. The block is not encrypted.
+func (k EdDSAPrivate) MarshalPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: EdDSAPrivatePEMType, Bytes: k})
+}
+
+// ParseEdDSAPrivatePEM decodes a private key encoded by MarshalPEM, like
+// DecodeEdDSAPrivate with EncodingPEM.
+func ParseEdDSAPrivatePEM(data []byte) (EdDSAPrivate, error) {
+	return DecodeEdDSAPrivate(data, EncodingPEM)
+}
+
+// Encode serializes the public key in the given encoding, which covers in
+// one method what MarshalBinary, MarshalText and MarshalPEM do separately,
+// plus base64. PEM blocks have type EdDSAPublicPEMType.
+func (k EdDSAPublic) Encode(enc Encoding) ([]byte, error) {
+	return encodeKey(k, enc, EdDSAPublicPEMType, EdDSAPublicLength, "EdDSA public key")
+}
+
+// DecodeEdDSAPublic parses a public key written by Encode with the same
+// encoding. Data of the wrong length, or a PEM block of another type, gives an
+// error.
+func DecodeEdDSAPublic(data []byte, enc Encoding) (EdDSAPublic, error) {
+	return decodeKey(data, enc, EdDSAPublicPEMType, EdDSAPublicLength, "EdDSA public key")
+}
+
+// Encode serializes the private key in the given encoding, like
+// EdDSAPublic.Encode. Every encoding holds the secret in the clear.
+func (k EdDSAPrivate) Encode(enc Encoding) ([]byte, error) {
+	return encodeKey(k, enc, EdDSAPrivatePEMType, EdDSAPrivateLength, "EdDSA private key")
+}
+
+// DecodeEdDSAPrivate parses a private key written by Encode with the same
+// encoding.
+func DecodeEdDSAPrivate(data []byte, enc Encoding) (EdDSAPrivate, error) {
+	return decodeKey(data, enc, EdDSAPrivatePEMType, EdDSAPrivateLength, "EdDSA private key")
+}
+
 // Verify verifies a signature and a message using a public key. If there is
 // a problem, then a non-nil value would be returned. A nil value means
-// everything is fine.
+// everything is fine. Malformed keys and signatures are reported as errors
+// rather than panics, so Verify is safe to call on untrusted input without
+// checking anything first: a signature of any length other than
+// EdDSASignatureLength, including an empty one, gives ErrSignatureInvalid,
+// and an empty or nil message is verified like any other.
+//
+// Verification is strict and cofactorless, as in libsodium: S must be
+// canonical, R and the public key must not be of small order, and
+// [S]B = R + [k]A must hold exactly. See VerifyCofactored for the more
+// lenient check some other implementations use.
 func (k EdDSAPublic) Verify(message []byte, signature []byte) error {
+	if len(k) != EdDSAPublicLength {
+		return keyLengthError("EdDSA public key", len(k), EdDSAPublicLength)
+	}
 	if len(signature) != EdDSASignatureLength {
-		panic(fmt.Sprintf("Signature passed has the wrong length (%v != %v)",
-			len(signature), EdDSASignatureLength))
+		return fmt.Errorf("%w: signature is %v bytes instead of %v", ErrSignatureInvalid,
+			len(signature), EdDSASignatureLength)
 	}
 	rv := C.crypto_sign_verify_detached(
 		(*C.uchar)(&signature[0]),
-		(*C.uchar)(&message[0]),
+		g2cbt(message),
 		C.ulonglong(len(message)),
 		(*C.uchar)(&k[0]))
 	if rv != 0 {
-		return errors.New("EdDSA signature is forged")
+		return ErrSignatureInvalid
 	}
 	return nil
 }
 
+// VerifyChecked is Verify under a name that says it checks its input: it
+// never panics, reports a signature of the wrong length, empty or too long,
+// as ErrSignatureInvalid, and verifies an empty message like any other. It
+// exists for code that wants that guarantee spelled out at the call site.
+func (k EdDSAPublic) VerifyChecked(message, signature []byte) error {
+	return k.Verify(message, signature)
+}
+
+// VerifyCofactored is like Verify, but checks the cofactored equation
+// [8][S]B = [8]R + [8][k]A, as batch verifiers and some other Ed25519
+// implementations do. It accepts everything Verify does, and also signatures
+// whose R has a small-order component, which Verify rejects. Use it only to
+// match the acceptance rules of a counterparty that requires it; S must still
+// be canonical and the public key must not be of small order.
+func (k EdDSAPublic) VerifyCofactored(message []byte, signature []byte) error {
+	if len(k) != EdDSAPublicLength {
+		return keyLengthError("EdDSA public key", len(k), EdDSAPublicLength)
+	}
+	if len(signature) != EdDSASignatureLength {
+		return fmt.Errorf("%w: signature is %v bytes instead of %v", ErrSignatureInvalid,
+			len(signature), EdDSASignatureLength)
+	}
+	r, s := signature[:Ed25519PointLength], signature[Ed25519PointLength:]
+	if !ed25519CanonicalScalar(s) {
+		return ErrSignatureInvalid
+	}
+	h := NewSHA512()
+	h.Write(r)
+	h.Write(k)
+	h.Write(message)
+	kh, _ := Ed25519ScalarReduce(h.Sum(nil))
+	r8, ok := ed25519MulCofactor(r)
+	if !ok {
+		return ErrSignatureInvalid
+	}
+	a8, ok := ed25519MulCofactor(k)
+	if !ok || !Ed25519IsValidPoint(a8) {
+		// a small-order public key, whose signatures prove nothing
+		return ErrSignatureInvalid
+	}
+	eight := make([]byte, Ed25519ScalarLength)
+	eight[0] = 8
+	s8, _ := Ed25519ScalarMul(s, eight)
+	lhs, err := Ed25519ScalarMultBase(s8)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+	ka8, err := Ed25519ScalarMult(kh, a8)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+	rhs, ok := ed25519AddUnchecked(r8, ka8)
+	if !ok || !MemCmp(lhs, rhs) {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+// VerifyAny checks a signature against each of several public keys, such as
+// the old and new keys during a rotation, and returns the first one under
+// which it verifies. Every key is tried whatever the outcome, so the time
+// taken does not reveal which one matched. If none does, or publics is empty,
+// the error is ErrSignatureInvalid; a key of the wrong length simply never
+// matches.
+func VerifyAny(publics []EdDSAPublic, message, signature []byte) (EdDSAPublic, error) {
+	match := -1
+	for i, publ := range publics {
+		if publ.Verify(message, signature) == nil && match < 0 {
+			match = i
+		}
+	}
+	if match < 0 {
+		return nil, ErrSignatureInvalid
+	}
+	return publics[match], nil
+}
+
+// SignMulti is like Sign, but signs the concatenation of parts, for messages
+// assembled from several buffers. The signature is identical to the one Sign
+// gives for the concatenation, and can be checked with Verify or VerifyMulti.
+// Plain Ed25519 hashes the message twice and libsodium has no incremental form
+// of it, so the parts are joined into one buffer before signing.
+func (k EdDSAPrivate) SignMulti(parts ...[]byte) []byte {
+	return k.Sign(bytes.Join(parts, nil))
+}
+
+// VerifyMulti is like Verify, but checks a signature over the concatenation
+// of parts, which it joins into one buffer first. It accepts exactly what
+// Verify does.
+func (k EdDSAPublic) VerifyMulti(signature []byte, parts ...[]byte) error {
+	return k.Verify(bytes.Join(parts, nil), signature)
+}
+
+// ed25519AddUnchecked adds two points that need only be on the curve, unlike
+// Ed25519PointAdd, which insists on the prime-order subgroup.
+func ed25519AddUnchecked(p, q []byte) ([]byte, bool) {
+	if len(p) != Ed25519PointLength || len(q) != Ed25519PointLength {
+		return nil, false
+	}
+	toret := make([]byte, Ed25519PointLength)
+	return toret, C.crypto_core_ed25519_add(g2cbt(toret), g2cbt(p), g2cbt(q)) == 0
+}
+
+// ed25519CanonicalPoint reports whether p is the canonical encoding of a
+// curve point; adding the identity re-encodes it canonically.
+func ed25519CanonicalPoint(p []byte) bool {
+	identity := make([]byte, Ed25519PointLength)
+	identity[0] = 1
+	sum, ok := ed25519AddUnchecked(p, identity)
+	return ok && MemCmp(sum, p)
+}
+
+// ed25519CanonicalScalar reports whether s is a fully reduced scalar, as the
+// S half of a signature must be.
+func ed25519CanonicalScalar(s []byte) bool {
+	reduced, err := Ed25519ScalarReduce(append(append([]byte(nil), s...), make([]byte, 32)...))
+	return err == nil && MemCmp(reduced, s)
+}
+
+// ed25519SmallOrder reports whether p is not a point at all or is one of the
+// eight points of small order, for which [8]p is the identity, encoded as 1.
+func ed25519SmallOrder(p []byte) bool {
+	p8, ok := ed25519MulCofactor(p)
+	return !ok || (p8[0] == 1 && isZero(p8[1:]))
+}
+
+// SignatureCanonical reports whether signature is encoded canonically, so
+// that no other byte string could carry the same signature. That makes
+// signatures usable as unique identifiers, as consensus protocols and
+// deduplication need, and lets junk be discarded before looking up any key.
+// The checks are:
+//
+//   - the signature is EdDSASignatureLength bytes long;
+//   - S, the second half, is fully reduced modulo the group order l, so
+//     S+l is not accepted as well;
+//   - R, the first half, is the canonical encoding of a curve point, with
+//     its y coordinate below 2^255-19;
+//   - R is not one of the eight points of small order.
+//
+// Verify in the linked libsodium already rejects every signature that fails
+// these checks, the non-canonical R implicitly because it can never equal the
+// point Verify recomputes, so this adds no security to a signature that is
+// verified anyway. Canonical encoding stops third parties from turning one
+// valid signature into another, but the key holder can still make many valid
+// signatures of the same message, so a signature identifies a signing, not a
+// message.
+func SignatureCanonical(signature []byte) bool {
+	if len(signature) != EdDSASignatureLength {
+		return false
+	}
+	r, s := signature[:Ed25519PointLength], signature[Ed25519PointLength:]
+	return ed25519CanonicalScalar(s) && !ed25519SmallOrder(r) && ed25519CanonicalPoint(r)
+}
+
+// ed25519MulCofactor returns [8]p by doubling three times, which clears any
+// small-order component of p.
+func ed25519MulCofactor(p []byte) ([]byte, bool) {
+	ok := true
+	for i := 0; i < 3 && ok; i++ {
+		p, ok = ed25519AddUnchecked(p, p)
+	}
+	return p, ok
+}
+
+// VerifyOK is like Verify, but simply reports whether the signature is valid.
+// It never panics either, so it can be used directly in an if statement.
+func (k EdDSAPublic) VerifyOK(message []byte, signature []byte) bool {
+	return k.Verify(message, signature) == nil
+}
+
+// Open verifies a combined signed message produced by SignAttached, returning
+// the message only if the signature is valid.
+func (k EdDSAPublic) Open(signedMessage []byte) ([]byte, error) {
+	if len(k) != EdDSAPublicLength {
+		return nil, keyLengthError("EdDSA public key", len(k), EdDSAPublicLength)
+	}
+	if len(signedMessage) < EdDSASignatureLength {
+		return nil, fmt.Errorf("%w: signed message too short", ErrSignatureInvalid)
+	}
+	message := make([]byte, len(signedMessage)-EdDSASignatureLength)
+	rv := C.crypto_sign_open(g2cbt(message), nil, g2cbt(signedMessage),
+		C.ulonglong(len(signedMessage)), g2cbt(k))
+	if rv != 0 {
+		return nil, ErrSignatureInvalid
+	}
+	return message, nil
+}
+
 // ToECDH converts an EdDSA public key deterministically to a ECDH public key
 func (k EdDSAPublic) ToECDH() ECDHPublic {
+	if len(k) != EdDSAPublicLength {
+		panic("EdDSA public key has the wrong length")
+	}
 	out := make([]byte, ECDHKeyLength)
 	rv := C.crypto_sign_ed25519_pk_to_curve25519(g2cbt(out), g2cbt(k))
 	if rv != 0 {
@@ -130,3 +797,368 @@ func (k EdDSAPublic) ToECDH() ECDHPublic {
 	}
 	return out
 }
+
+// ToCurve25519 converts an EdDSA public key to the birationally-equivalent
+// Montgomery point, usable as a box public key. This lets a single identity
+// key be used for both signing and encryption. An error is returned if the
+// point cannot be converted, for example because it has low order.
+func (k EdDSAPublic) ToCurve25519() (BoxPublic, error) {
+	if len(k) != EdDSAPublicLength {
+		return nil, keyLengthError("EdDSA public key", len(k), EdDSAPublicLength)
+	}
+	out := make([]byte, BoxPublicLength)
+	rv := C.crypto_sign_ed25519_pk_to_curve25519(g2cbt(out), g2cbt(k))
+	if rv != 0 {
+		return nil, errors.New("EdDSA public key cannot be converted to Curve25519")
+	}
+	return out, nil
+}
+
+// VerifyBatch verifies many signatures in parallel, spreading the work over
+// GOMAXPROCS goroutines. The i-th entry of the result reports whether
+// signatures[i] is a valid signature of messages[i] by publics[i]. An error is
+// returned only if the three slices have different lengths.
+func VerifyBatch(publics []EdDSAPublic, messages [][]byte, signatures [][]byte) ([]bool, error) {
+	if len(publics) != len(messages) || len(messages) != len(signatures) {
+		return nil, errors.New("VerifyBatch needs slices of equal length")
+	}
+	toret := make([]bool, len(publics))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if len(publics[i]) != EdDSAPublicLength ||
+					len(signatures[i]) != EdDSASignatureLength {
+					continue
+				}
+				toret[i] = publics[i].Verify(messages[i], signatures[i]) == nil
+			}
+		}()
+	}
+	for i := range publics {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return toret, nil
+}
+
+// GobEncode implements the gob.GobEncoder interface.
+func (k EdDSAPublic) GobEncode() ([]byte, error) {
+	return encodeKeyGob(gobEdDSAPublic, k)
+}
+
+// GobDecode implements the gob.GobDecoder interface, rejecting anything that
+// is not an EdDSA public key of the right length.
+func (k *EdDSAPublic) GobDecode(data []byte) error {
+	raw, err := decodeKeyGob(data, gobEdDSAPublic, EdDSAPublicLength, "EdDSA public key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// GobEncode implements the gob.GobEncoder interface.
+func (k EdDSAPrivate) GobEncode() ([]byte, error) {
+	return encodeKeyGob(gobEdDSAPrivate, k)
+}
+
+// GobDecode implements the gob.GobDecoder interface, rejecting anything that
+// is not an EdDSA private key of the right length.
+func (k *EdDSAPrivate) GobDecode(data []byte) error {
+	raw, err := decodeKeyGob(data, gobEdDSAPrivate, EdDSAPrivateLength, "EdDSA private key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, returning a
+// copy of the raw key.
+func (k EdDSAPublic) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), k...), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. The
+// data is length-checked and copied, never aliased.
+func (k *EdDSAPublic) UnmarshalBinary(data []byte) error {
+	raw, err := copyKey(data, EdDSAPublicLength, "EdDSA public key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, returning a
+// copy of the raw key.
+func (k EdDSAPrivate) MarshalBinary() ([]byte, error) {
+	return append([]byte(nil), k...), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. The
+// data is length-checked and copied, never aliased.
+func (k *EdDSAPrivate) UnmarshalBinary(data []byte) error {
+	raw, err := copyKey(data, EdDSAPrivateLength, "EdDSA private key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, encoding the
+// key as lowercase hex.
+func (k EdDSAPublic) MarshalText() ([]byte, error) {
+	return []byte(BinToHex(k)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, reversing
+// MarshalText.
+func (k *EdDSAPublic) UnmarshalText(text []byte) error {
+	raw, err := unmarshalKeyText(text, EdDSAPublicLength, "EdDSA public key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, encoding the
+// key as lowercase hex.
+func (k EdDSAPrivate) MarshalText() ([]byte, error) {
+	return []byte(BinToHex(k)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, reversing
+// MarshalText.
+func (k *EdDSAPrivate) UnmarshalText(text []byte) error {
+	raw, err := unmarshalKeyText(text, EdDSAPrivateLength, "EdDSA private key")
+	if err != nil {
+		return err
+	}
+	*k = raw
+	return nil
+}
+
+// EdDSAKeyPair bundles an EdDSA public key with its private key, so that the
+// two cannot be mismatched and the public key is not derived over and over.
+// Private may be nil for a pair that can only verify.
+type EdDSAKeyPair struct {
+	Public  EdDSAPublic  `json:"public"`
+	Private EdDSAPrivate `json:"private,omitempty"`
+}
+
+// GenerateEdDSAKeyPair generates a fresh EdDSA key pair.
+func GenerateEdDSAKeyPair() EdDSAKeyPair {
+	priv := EdDSAGenerateKey()
+	return EdDSAKeyPair{Public: priv.PublicKey(), Private: priv}
+}
+
+// GenerateEdDSAKeys generates n fresh EdDSA key pairs at once. The private
+// keys share a single allocation, which is cheaper than n separate ones; each
+// can still be destroyed on its own, or moved out with EnableAutoWipe.
+func GenerateEdDSAKeys(n int) ([]EdDSAKeyPair, error) {
+	if n < 0 || n > int(^uint(0)>>1)/EdDSAPrivateLength {
+		return nil, fmt.Errorf("cannot generate %v EdDSA keys", n)
+	}
+	return fillEdDSAKeys(make([]byte, n*EdDSAPrivateLength), n)
+}
+
+// GenerateEdDSAKeysSecure is like GenerateEdDSAKeys, but places the private
+// keys in one SecureBuffer, so that they are locked into memory and guarded
+// without the cost of a page-aligned allocation per key. The returned destroy
+// function wipes and frees them all at once, and clears the Private field of
+// every pair in the returned slice.
+//
+// A private key copied out of the slice before destroy must not be used
+// afterwards: its memory is unmapped rather than just zeroed, so touching it
+// crashes the program with a fault that cannot be recovered from, instead of
+// silently reading zeros. As with any SecureBuffer, destroy must be called, or
+// the memory is never freed. EnableAutoWipe would move a key out of the
+// locked memory and onto the Go heap, which defeats the point.
+func GenerateEdDSAKeysSecure(n int) (pairs []EdDSAKeyPair, destroy func(), err error) {
+	if n < 0 {
+		return nil, nil, fmt.Errorf("cannot generate %v EdDSA keys in secure memory", n)
+	}
+	if n == 0 {
+		return []EdDSAKeyPair{}, func() {}, nil
+	}
+	sb, err := NewSecureArray(n, EdDSAPrivateLength)
+	if err != nil {
+		return nil, nil, err
+	}
+	pairs, err = fillEdDSAKeys(sb.Bytes(), n)
+	if err != nil {
+		sb.Free()
+		return nil, nil, err
+	}
+	destroy = func() {
+		for i := range pairs {
+			pairs[i].Private = nil
+		}
+		sb.Free()
+	}
+	return pairs, destroy, nil
+}
+
+// fillEdDSAKeys generates n key pairs whose private keys are written straight
+// into consecutive slots of buf, wiping all of buf if any generation fails.
+func fillEdDSAKeys(buf []byte, n int) ([]EdDSAKeyPair, error) {
+	mustInit()
+	pairs := make([]EdDSAKeyPair, n)
+	seed := make([]byte, EdDSASeedLength)
+	defer wipe(seed)
+	for i := range pairs {
+		priv := buf[i*EdDSAPrivateLength : (i+1)*EdDSAPrivateLength : (i+1)*EdDSAPrivateLength]
+		publ := make([]byte, EdDSAPublicLength)
+		RandBytes(seed)
+		if C.crypto_sign_seed_keypair(g2cbt(publ), g2cbt(priv), g2cbt(seed)) != 0 {
+			wipe(buf)
+			return nil, errors.New("crypto_sign_seed_keypair returned non-zero")
+		}
+		pairs[i] = EdDSAKeyPair{Public: publ, Private: priv}
+	}
+	return pairs, nil
+}
+
+// Sign signs a message with the private key, failing if the pair has none.
+func (kp EdDSAKeyPair) Sign(message []byte) ([]byte, error) {
+	if kp.Private == nil {
+		return nil, errors.New("EdDSA key pair has no private key")
+	}
+	return kp.Private.SignSafe(message)
+}
+
+// Verify checks a signature against the public key, like EdDSAPublic.Verify.
+func (kp EdDSAKeyPair) Verify(message, signature []byte) error {
+	return kp.Public.Verify(message, signature)
+}
+
+// check makes sure a decoded pair holds a public key and, if it has a private
+// key too, that the two belong together.
+func (kp EdDSAKeyPair) check() error {
+	if len(kp.Public) != EdDSAPublicLength {
+		return errors.New("EdDSA key pair has no public key")
+	}
+	if kp.Private != nil && !kp.Public.Equal(kp.Private.PublicKey()) {
+		return errors.New("EdDSA key pair has mismatched keys")
+	}
+	return nil
+}
+
+// UnmarshalJSON implements the UnmarshalJSON interface, rejecting a pair
+// without a public key or whose keys do not match.
+func (kp *EdDSAKeyPair) UnmarshalJSON(data []byte) error {
+	type plain EdDSAKeyPair
+	var decoded plain
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	if err := EdDSAKeyPair(decoded).check(); err != nil {
+		return err
+	}
+	*kp = EdDSAKeyPair(decoded)
+	return nil
+}
+
+// MarshalPEM encodes the public key followed, if present, by the private key,
+// each as its own PEM block.
+func (kp EdDSAKeyPair) MarshalPEM() []byte {
+	out := kp.Public.MarshalPEM()
+	if kp.Private != nil {
+		out = append(out, kp.Private.MarshalPEM()...)
+	}
+	return out
+}
+
+// ParseEdDSAKeyPairPEM decodes a key pair encoded by EdDSAKeyPair.MarshalPEM.
+func ParseEdDSAKeyPairPEM(data []byte) (EdDSAKeyPair, error) {
+	var kp EdDSAKeyPair
+	block, rest := pem.Decode(data)
+	if block == nil {
+		return kp, errors.New("no PEM block found while looking for EdDSA key pair")
+	}
+	publ, err := ParseEdDSAPublicPEM(pem.EncodeToMemory(block))
+	if err != nil {
+		return kp, err
+	}
+	kp.Public = publ
+	if block, _ := pem.Decode(rest); block != nil {
+		priv, err := ParseEdDSAPrivatePEM(pem.EncodeToMemory(block))
+		if err != nil {
+			return kp, err
+		}
+		kp.Private = priv
+	}
+	return kp, kp.check()
+}
+
+// SignedMessage is a self-contained envelope holding a message, its EdDSA
+// signature and the public key that made it, ready to be sent as JSON.
+//
+// Verify only proves that the message was signed by the embedded public key,
+// which anyone can generate. The key must still be checked against one that
+// is trusted independently, for instance pinned in configuration, before the
+// message is believed to come from anyone in particular.
+type SignedMessage struct {
+	PublicKey EdDSAPublic `json:"public_key"`
+	Message   []byte      `json:"message"`
+	Signature []byte      `json:"signature"`
+}
+
+// NewSignedMessage signs message and packages it with the signature and k's
+// public key. The message is copied.
+func (k EdDSAPrivate) NewSignedMessage(message []byte) SignedMessage {
+	return SignedMessage{
+		PublicKey: k.PublicKey(),
+		Message:   append([]byte(nil), message...),
+		Signature: k.Sign(message),
+	}
+}
+
+// Verify checks the signature against the embedded public key, returning
+// ErrSignatureInvalid if it does not match.
+func (sm SignedMessage) Verify() error {
+	return sm.PublicKey.Verify(sm.Message, sm.Signature)
+}
+
+var errMalformedToken = errors.New("malformed token")
+
+// MakeToken returns a compact signed token: the payload and its signature,
+// each in unpadded URL-safe base64, joined by a dot. The signature covers the
+// raw payload bytes. The payload is signed but not encrypted, so anyone can
+// read it.
+func (k EdDSAPrivate) MakeToken(payload []byte) string {
+	return BinToBase64URL(payload) + "." + BinToBase64URL(k.Sign(payload))
+}
+
+// ParseToken checks a token produced by MakeToken against k and returns its
+// payload. A token that is not two base64 parts separated by a dot is
+// rejected as malformed, saying which part is at fault, while one whose
+// signature does not verify gives ErrSignatureInvalid. There is no expiry or
+// audience: any such claims must be inside the payload and checked by the
+// caller.
+func (k EdDSAPublic) ParseToken(token string) ([]byte, error) {
+	dot := strings.IndexByte(token, '.')
+	if dot < 0 {
+		return nil, fmt.Errorf("%w: no '.' separating payload and signature", errMalformedToken)
+	}
+	payload, err := Base64URLToBin(token[:dot])
+	if err != nil {
+		return nil, fmt.Errorf("%w: payload is not valid base64", errMalformedToken)
+	}
+	signature, err := Base64URLToBin(token[dot+1:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature is not valid base64", errMalformedToken)
+	}
+	if err := k.Verify(payload, signature); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
@@ -1,6 +1,20 @@
 package natrium
 
-import "testing"
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
 
 func TestSignatureNormal(t *testing.T) {
 	priv := EdDSAGenerateKey()
@@ -23,3 +37,1078 @@ func TestSignatureFail(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestSignatureUntrustedInput(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	publ := priv.PublicKey()
+	message := []byte("Hello World")
+	signature := priv.Sign(message)
+	for _, bad := range [][]byte{nil, {}, signature[:EdDSASignatureLength-1],
+		append(append([]byte(nil), signature...), 0)} {
+		if err := publ.Verify(message, bad); !errors.Is(err, ErrSignatureInvalid) {
+			t.FailNow()
+		}
+		if err := publ.VerifyChecked(message, bad); !errors.Is(err, ErrSignatureInvalid) {
+			t.FailNow()
+		}
+	}
+	for _, empty := range [][]byte{nil, {}} {
+		if publ.Verify(empty, priv.Sign(empty)) != nil || publ.VerifyChecked(empty, priv.Sign(empty)) != nil {
+			t.FailNow()
+		}
+		if err := publ.Verify(empty, signature); !errors.Is(err, ErrSignatureInvalid) {
+			t.FailNow()
+		}
+	}
+	if publ.VerifyChecked(message, signature) != nil {
+		t.FailNow()
+	}
+	if err := EdDSAPublic(nil).Verify(message, signature); !errors.Is(err, ErrInvalidKeyLength) {
+		t.FailNow()
+	}
+}
+
+func TestSignatureCryptoSigner(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	message := []byte("Hello World")
+	signer := priv.CryptoSigner()
+	signature, err := signer.Sign(nil, message, crypto.Hash(0))
+	if err != nil {
+		t.FailNow()
+	}
+	if signer.Public().(EdDSAPublic).Verify(message, signature) != nil {
+		t.FailNow()
+	}
+	_, err = signer.Sign(nil, message, crypto.SHA512)
+	if err == nil {
+		t.FailNow()
+	}
+	// a bad key is an error, not a panic, for callers like crypto/tls
+	if _, err := priv[:10].CryptoSigner().Sign(nil, message, crypto.Hash(0)); !errors.Is(err, ErrInvalidKeyLength) {
+		t.FailNow()
+	}
+}
+
+func TestSignatureFromSeed(t *testing.T) {
+	seed := make([]byte, EdDSASeedLength)
+	RandBytes(seed)
+	a := EdDSAGenerateKeyFromSeed(seed)
+	b := EdDSAGenerateKeyFromSeed(seed)
+	if CTCompare(a, b) != 0 || CTCompare(a.PublicKey(), b.PublicKey()) != 0 {
+		t.FailNow()
+	}
+}
+
+func TestRandomSeed(t *testing.T) {
+	seed := RandomSeed()
+	if len(seed) != EdDSASeedLength || MemCmp(seed, RandomSeed()) {
+		t.FailNow()
+	}
+	message := []byte("Hello World")
+	sig := EdDSAGenerateKeyFromSeed(seed).Sign(message)
+	if EdDSAGenerateKeyFromSeed(seed).PublicKey().Verify(message, sig) != nil {
+		t.FailNow()
+	}
+}
+
+func TestSignatureSeedRoundTrip(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	seed := priv.Seed()
+	if len(seed) != EdDSASeedLength {
+		t.FailNow()
+	}
+	if CTCompare(EdDSAGenerateKeyFromSeed(seed), priv) != 0 {
+		t.FailNow()
+	}
+}
+
+func TestSignatureToCurve25519(t *testing.T) {
+	publ := EdDSAGenerateKey().PublicKey()
+	boxpub, err := publ.ToCurve25519()
+	if err != nil || len(boxpub) != BoxPublicLength {
+		t.FailNow()
+	}
+	if CTCompare(boxpub, publ.ToECDH()) != 0 {
+		t.FailNow()
+	}
+}
+
+func TestSignaturePrivateToCurve25519(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	boxpriv := priv.ToCurve25519()
+	if len(boxpriv) != BoxPrivateLength {
+		t.FailNow()
+	}
+	boxpub, err := priv.PublicKey().ToCurve25519()
+	if err != nil {
+		t.FailNow()
+	}
+	if CTCompare(boxpriv.PublicKey(), boxpub) != 0 {
+		t.FailNow()
+	}
+	// short keys panic in Go with a message instead of reading past the end
+	for _, convert := range []func(){
+		func() { priv[:32].ToCurve25519() },
+		func() { EdDSAPrivate(nil).ToCurve25519() },
+		func() { priv[:32].PublicKey() },
+		func() { EdDSAPrivate(nil).PublicKey() },
+		func() { priv[:32].ToECDH() },
+		func() { priv.PublicKey()[:16].ToECDH() },
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "wrong length") {
+					t.FailNow()
+				}
+			}()
+			convert()
+		}()
+	}
+}
+
+func TestSignatureAttached(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	message := []byte("Hello World")
+	signed := priv.SignAttached(message)
+	opened, err := priv.PublicKey().Open(signed)
+	if err != nil || string(opened) != string(message) {
+		t.FailNow()
+	}
+	signed[len(signed)-1] ^= 1
+	opened, err = priv.PublicKey().Open(signed)
+	if err == nil || opened != nil {
+		t.FailNow()
+	}
+	for _, publ := range []EdDSAPublic{nil, priv.PublicKey()[:16]} {
+		if _, err := publ.Open(signed); !errors.Is(err, ErrInvalidKeyLength) {
+			t.FailNow()
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.FailNow()
+		}
+	}()
+	priv[:32].SignAttached(message)
+}
+
+func TestSignatureJSON(t *testing.T) {
+	publ := EdDSAGenerateKey().PublicKey()
+	bts, err := json.Marshal(publ)
+	if err != nil {
+		t.FailNow()
+	}
+	var decoded EdDSAPublic
+	err = json.Unmarshal(bts, &decoded)
+	if err != nil || CTCompare(decoded, publ) != 0 {
+		t.FailNow()
+	}
+	if json.Unmarshal([]byte(`"aGVsbG8="`), &decoded) == nil {
+		t.FailNow()
+	}
+}
+
+func TestSignaturePrivateJSON(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	bts, err := json.Marshal(priv)
+	if err != nil {
+		t.FailNow()
+	}
+	var decoded EdDSAPrivate
+	err = json.Unmarshal(bts, &decoded)
+	if err != nil || CTCompare(decoded, priv) != 0 {
+		t.FailNow()
+	}
+	if json.Unmarshal([]byte(`"aGVsbG8="`), &decoded) == nil {
+		t.FailNow()
+	}
+}
+
+func batchFixture(n int) ([]EdDSAPublic, [][]byte, [][]byte) {
+	publics := make([]EdDSAPublic, n)
+	messages := make([][]byte, n)
+	signatures := make([][]byte, n)
+	for i := 0; i < n; i++ {
+		priv := EdDSAGenerateKey()
+		publics[i] = priv.PublicKey()
+		messages[i] = make([]byte, 100)
+		RandBytes(messages[i])
+		signatures[i] = priv.Sign(messages[i])
+	}
+	return publics, messages, signatures
+}
+
+func TestVerifyBatch(t *testing.T) {
+	publics, messages, signatures := batchFixture(50)
+	messages[7][0] ^= 1
+	signatures[20] = signatures[20][:10]
+	results, err := VerifyBatch(publics, messages, signatures)
+	if err != nil {
+		t.FailNow()
+	}
+	for i, ok := range results {
+		if ok != (i != 7 && i != 20) {
+			t.FailNow()
+		}
+	}
+	if _, err := VerifyBatch(publics, messages[1:], signatures); err == nil {
+		t.FailNow()
+	}
+}
+
+func BenchmarkVerifyBatch(b *testing.B) {
+	publics, messages, signatures := batchFixture(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		VerifyBatch(publics, messages, signatures)
+	}
+}
+
+func BenchmarkVerifyBatch_Loop(b *testing.B) {
+	publics, messages, signatures := batchFixture(1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := range publics {
+			publics[j].Verify(messages[j], signatures[j])
+		}
+	}
+}
+
+func TestSignatureNoPanic(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	message := []byte("Hello World")
+	if _, err := EdDSAPrivate(priv[:10]).SignSafe(message); err == nil {
+		t.FailNow()
+	}
+	signature, err := priv.SignSafe(message)
+	if err != nil {
+		t.FailNow()
+	}
+	if priv.PublicKey().Verify(message, signature[:20]) == nil {
+		t.FailNow()
+	}
+	if EdDSAPublic(nil).Verify(message, signature) == nil {
+		t.FailNow()
+	}
+}
+
+func TestSignatureEmptyMessage(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	publ := priv.PublicKey()
+	signature := priv.Sign(nil)
+	if publ.Verify([]byte{}, signature) != nil {
+		t.FailNow()
+	}
+	if !ed25519.Verify(publ.StdPublicKey(), nil, signature) {
+		t.FailNow()
+	}
+	if publ.Verify(nil, priv.Sign([]byte("x"))) == nil {
+		t.FailNow()
+	}
+}
+
+func TestSignatureDestroy(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	clone := priv.Clone()
+	priv.Destroy()
+	for _, b := range priv {
+		if b != 0 {
+			t.FailNow()
+		}
+	}
+	message := []byte("Hello World")
+	if _, err := priv.SignSafe(message); err == nil {
+		t.FailNow()
+	}
+	if _, err := priv.AppendSignature(nil, message); err == nil {
+		t.FailNow()
+	}
+	for _, sign := range []func(){
+		func() { priv.Sign(message) },
+		func() { priv.SignAttached(message) },
+		func() { priv.SignMulti(message) },
+		func() { priv.NewSigner() },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.FailNow()
+				}
+			}()
+			sign()
+		}()
+	}
+	if clone.PublicKey().Verify(message, clone.Sign(message)) != nil {
+		t.FailNow()
+	}
+}
+
+func TestSignatureArray(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	var privArr EdDSAPrivateArray
+	if privArr.From(priv) != nil {
+		t.FailNow()
+	}
+	var publArr EdDSAPublicArray
+	if publArr.From(priv.PublicKey()) != nil {
+		t.FailNow()
+	}
+	message := []byte("Hello World")
+	if publArr.To().Verify(message, privArr.To().Sign(message)) != nil {
+		t.FailNow()
+	}
+	if publArr.From(EdDSAPublic(priv)) == nil {
+		t.FailNow()
+	}
+}
+
+func TestNewEdDSAKeys(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	raw := []byte(priv.PublicKey())
+	publ, err := NewEdDSAPublic(raw)
+	if err != nil {
+		t.FailNow()
+	}
+	raw[0] ^= 1
+	if publ[0] == raw[0] {
+		t.FailNow()
+	}
+	if _, err := NewEdDSAPublic(raw[:31]); err == nil {
+		t.FailNow()
+	}
+	if _, err := NewEdDSAPrivate(priv); err != nil {
+		t.FailNow()
+	}
+	if _, err := NewEdDSAPrivate(priv[:32]); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestSignatureEqual(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	publ := priv.PublicKey()
+	if !publ.Equal(priv.PublicKey()) || !priv.Equal(EdDSAPrivate(append([]byte(nil), priv...))) {
+		t.FailNow()
+	}
+	other := EdDSAGenerateKey()
+	if publ.Equal(other.PublicKey()) || priv.Equal(other) {
+		t.FailNow()
+	}
+	if publ.Equal(publ[:16]) || publ.Equal(BoxPublic(publ)) {
+		t.FailNow()
+	}
+	var _ interface{ Equal(crypto.PublicKey) bool } = publ
+}
+
+func TestSignatureStdInterop(t *testing.T) {
+	stdPubl, stdPriv, _ := ed25519.GenerateKey(nil)
+	message := []byte("Hello World")
+	if FromStdPublicKey(stdPubl).Verify(message, ed25519.Sign(stdPriv, message)) != nil {
+		t.FailNow()
+	}
+	priv := FromStdPrivateKey(stdPriv)
+	if !ed25519.Verify(stdPubl, message, priv.Sign(message)) {
+		t.FailNow()
+	}
+	ours := EdDSAGenerateKey()
+	if !ed25519.Verify(ours.PublicKey().StdPublicKey(), message, ours.Sign(message)) {
+		t.FailNow()
+	}
+	if !bytes.Equal(ed25519.Sign(ours.StdPrivateKey(), message), ours.Sign(message)) {
+		t.FailNow()
+	}
+}
+
+func TestSignaturePEM(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	publ := priv.PublicKey()
+	gotPubl, err := ParseEdDSAPublicPEM(publ.MarshalPEM())
+	if err != nil || !publ.Equal(gotPubl) {
+		t.FailNow()
+	}
+	gotPriv, err := ParseEdDSAPrivatePEM(priv.MarshalPEM())
+	if err != nil || !priv.Equal(gotPriv) {
+		t.FailNow()
+	}
+	if _, err := ParseEdDSAPublicPEM(priv.MarshalPEM()); err == nil {
+		t.FailNow()
+	}
+	if _, err := ParseEdDSAPrivatePEM([]byte("garbage")); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestSignatureGob(t *testing.T) {
+	type identity struct {
+		Public  EdDSAPublic
+		Private EdDSAPrivate
+		Box     BoxPublic
+	}
+	priv := EdDSAGenerateKey()
+	orig := identity{priv.PublicKey(), priv, BoxGenerateKey().PublicKey()}
+	var buf bytes.Buffer
+	if gob.NewEncoder(&buf).Encode(orig) != nil {
+		t.FailNow()
+	}
+	var got identity
+	if gob.NewDecoder(&buf).Decode(&got) != nil {
+		t.FailNow()
+	}
+	if !bytes.Equal(got.Public, orig.Public) || !bytes.Equal(got.Private, orig.Private) ||
+		!bytes.Equal(got.Box, orig.Box) {
+		t.FailNow()
+	}
+	var wrong EdDSAPublic
+	if wrong.GobDecode(append([]byte{gobBoxPublic}, orig.Box...)) == nil {
+		t.FailNow()
+	}
+}
+
+func TestSignatureBinary(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	var keys []encoding.BinaryMarshaler
+	keys = append(keys, priv, priv.PublicKey())
+	data, _ := keys[1].MarshalBinary()
+	var publ EdDSAPublic
+	if publ.UnmarshalBinary(data) != nil || !publ.Equal(priv.PublicKey()) {
+		t.FailNow()
+	}
+	var wrong EdDSAPrivate
+	if wrong.UnmarshalBinary(data) == nil {
+		t.FailNow()
+	}
+}
+
+func TestSignInto(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	message := []byte("Hello World")
+	scratch := make([]byte, 0, 100)
+	signature, err := priv.SignInto(scratch, message)
+	if err != nil || &signature[0] != &scratch[:1][0] {
+		t.FailNow()
+	}
+	if !bytes.Equal(signature, priv.Sign(message)) {
+		t.FailNow()
+	}
+	signature, err = priv.SignInto(make([]byte, 10), message)
+	if err != nil || priv.PublicKey().Verify(message, signature) != nil {
+		t.FailNow()
+	}
+}
+
+func BenchmarkSign(b *testing.B) {
+	priv := EdDSAGenerateKey()
+	message := make([]byte, 64)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		priv.Sign(message)
+	}
+}
+
+func BenchmarkSignInto(b *testing.B) {
+	priv := EdDSAGenerateKey()
+	message := make([]byte, 64)
+	scratch := make([]byte, EdDSASignatureLength)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		priv.SignInto(scratch, message)
+	}
+}
+
+func TestAppendSignature(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	message := []byte("Hello World")
+	frame, err := priv.AppendSignature([]byte("header"), message)
+	if err != nil || len(frame) != 6+EdDSASignatureLength || string(frame[:6]) != "header" {
+		t.FailNow()
+	}
+	if priv.PublicKey().Verify(message, frame[6:]) != nil {
+		t.FailNow()
+	}
+	if _, err := EdDSAPrivate(priv[:5]).AppendSignature(nil, message); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestVerifyOK(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	publ := priv.PublicKey()
+	signature := priv.Sign([]byte("Hello World"))
+	if !publ.VerifyOK([]byte("Hello World"), signature) {
+		t.FailNow()
+	}
+	if publ.VerifyOK([]byte("Hello Wortd"), signature) || publ.VerifyOK([]byte("Hello World"), signature[1:]) {
+		t.FailNow()
+	}
+	if EdDSAPublic(nil).VerifyOK(nil, nil) {
+		t.FailNow()
+	}
+}
+
+func TestEdDSAKeyPair(t *testing.T) {
+	kp := GenerateEdDSAKeyPair()
+	signature, err := kp.Sign([]byte("Hello World"))
+	if err != nil || kp.Verify([]byte("Hello World"), signature) != nil {
+		t.FailNow()
+	}
+	encoded, _ := json.Marshal(kp)
+	var decoded EdDSAKeyPair
+	if json.Unmarshal(encoded, &decoded) != nil || !decoded.Private.Equal(kp.Private) {
+		t.FailNow()
+	}
+	public := EdDSAKeyPair{Public: kp.Public}
+	encoded, _ = json.Marshal(public)
+	if bytes.Contains(encoded, []byte("private")) {
+		t.FailNow()
+	}
+	if _, err := public.Sign([]byte("Hello World")); err == nil {
+		t.FailNow()
+	}
+	fromPEM, err := ParseEdDSAKeyPairPEM(kp.MarshalPEM())
+	if err != nil || !fromPEM.Public.Equal(kp.Public) || !fromPEM.Private.Equal(kp.Private) {
+		t.FailNow()
+	}
+	fromPEM, err = ParseEdDSAKeyPairPEM(public.MarshalPEM())
+	if err != nil || fromPEM.Private != nil {
+		t.FailNow()
+	}
+	mismatched := EdDSAKeyPair{Public: kp.Public, Private: EdDSAGenerateKey()}
+	encoded, _ = json.Marshal(mismatched)
+	if json.Unmarshal(encoded, &decoded) == nil {
+		t.FailNow()
+	}
+}
+
+func TestEdDSAClone(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	alias := priv
+	clone := priv.Clone()
+	if !clone.Equal(priv) {
+		t.FailNow()
+	}
+	clone[0] ^= 1
+	if clone.Equal(priv) || !alias.Equal(priv) {
+		t.FailNow()
+	}
+	clone[0] ^= 1
+	priv.Destroy()
+	if !isZero(alias) || isZero(clone) {
+		t.FailNow()
+	}
+	pub := clone.PublicKey()
+	if pubc := pub.Clone(); !pubc.Equal(pub) || &pubc[0] == &pub[0] {
+		t.FailNow()
+	}
+}
+
+func TestEdDSAFromHex(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	pub := priv.PublicKey()
+	for _, s := range []string{pub.String(), BinToHex(pub)} {
+		parsed, err := EdDSAPublicFromHex(s)
+		if err != nil || !parsed.Equal(pub) {
+			t.FailNow()
+		}
+	}
+	if !MustEdDSAPrivateFromHex(priv.HexString()).Equal(priv) {
+		t.FailNow()
+	}
+	if _, err := EdDSAPublicFromHex(BinToHex(pub[1:])); !errors.Is(err, ErrInvalidKeyLength) {
+		t.FailNow()
+	}
+	if _, err := EdDSAPublicFromHex("boxpub:" + BinToHex(pub)); err == nil {
+		t.FailNow()
+	}
+	defer func() {
+		if recover() == nil {
+			t.FailNow()
+		}
+	}()
+	MustEdDSAPublicFromHex("zz")
+}
+
+func TestEdDSAValid(t *testing.T) {
+	pub := EdDSAGenerateKey().PublicKey()
+	if !pub.Valid() || pub[1:].Valid() {
+		t.FailNow()
+	}
+	// the identity point, and a non-canonical encoding of y = p
+	for _, s := range []string{
+		"0100000000000000000000000000000000000000000000000000000000000000",
+		"edffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+	} {
+		if MustEdDSAPublicFromHex(s).Valid() {
+			t.FailNow()
+		}
+	}
+}
+
+func TestSignedMessage(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	sm := priv.NewSignedMessage([]byte("Hello World"))
+	if sm.Verify() != nil || !sm.PublicKey.Equal(priv.PublicKey()) {
+		t.FailNow()
+	}
+	encoded, err := json.Marshal(sm)
+	if err != nil {
+		t.FailNow()
+	}
+	var decoded SignedMessage
+	if json.Unmarshal(encoded, &decoded) != nil || decoded.Verify() != nil ||
+		string(decoded.Message) != "Hello World" {
+		t.FailNow()
+	}
+	decoded.Message[0] ^= 1
+	if err := decoded.Verify(); !errors.Is(err, ErrSignatureInvalid) {
+		t.FailNow()
+	}
+	// swapping in another key does not verify either
+	sm.PublicKey = EdDSAGenerateKey().PublicKey()
+	if sm.Verify() == nil {
+		t.FailNow()
+	}
+	if json.Unmarshal([]byte(`{"public_key":"AAAA","message":"","signature":""}`), &decoded) == nil {
+		t.FailNow()
+	}
+}
+
+func TestVerifyCofactored(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	publ := priv.PublicKey()
+	message := []byte("Hello World")
+	signature := priv.Sign(message)
+	if publ.VerifyCofactored(message, signature) != nil ||
+		publ.VerifyCofactored([]byte("Hello Wortd"), signature) == nil {
+		t.FailNow()
+	}
+	// forge a signature whose R carries a point of order 8; it is valid under
+	// the cofactored equation but not the strict one
+	h := SHA512(priv.Seed())
+	h[0] &= 248
+	h[31] &= 127
+	h[31] |= 64
+	a, _ := Ed25519ScalarReduce(append(h[:32], make([]byte, 32)...))
+	if aB, _ := Ed25519ScalarMultBase(a); !bytes.Equal(aB, publ) {
+		t.FailNow()
+	}
+	torsion, _ := HexToBin("26e8958fc2b227b045c3f489f2ef98f0d5dfac05d3c63339b13802886d53fc05")
+	nonce := Ed25519ScalarRandom()
+	rB, _ := Ed25519ScalarMultBase(nonce)
+	r, ok := ed25519AddUnchecked(rB, torsion)
+	if !ok {
+		t.FailNow()
+	}
+	k, _ := Ed25519ScalarReduce(SHA512(append(append(append([]byte(nil), r...), publ...), message...)))
+	ka, _ := Ed25519ScalarMul(k, a)
+	s, _ := Ed25519ScalarAdd(nonce, ka)
+	forged := append(r, s...)
+	if publ.VerifyCofactored(message, forged) != nil {
+		t.FailNow()
+	}
+	if err := publ.Verify(message, forged); !errors.Is(err, ErrSignatureInvalid) {
+		t.FailNow()
+	}
+	if publ.VerifyMulti(forged, message) == nil {
+		t.FailNow()
+	}
+	// a non-canonical S is rejected by both
+	bad := append([]byte(nil), signature...)
+	bad[63] |= 0xf0
+	if publ.VerifyCofactored(message, bad) == nil || publ.Verify(message, bad) == nil {
+		t.FailNow()
+	}
+	if publ.VerifyCofactored(message, signature[1:]) == nil {
+		t.FailNow()
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	zero := make([]byte, 32)
+	if EdDSAPublic(zero).Fingerprint() != "9f:56:0d:b0:e4:0b:a3:db:73:b1:37:b0:03:d7:e4:e8" ||
+		BoxPublic(zero).Fingerprint() != "57:51:ab:21:cc:8a:8e:19:09:5d:72:d2:a7:0e:d2:71" {
+		t.FailNow()
+	}
+	a := EdDSAGenerateKey().PublicKey()
+	b := EdDSAGenerateKey().PublicKey()
+	if a.Fingerprint() != a.Clone().Fingerprint() || a.Fingerprint() == b.Fingerprint() {
+		t.FailNow()
+	}
+}
+
+func TestEdDSAAutoWipe(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	orig := priv.Clone()
+	moved := priv.EnableAutoWipe()
+	if !isZero(priv) || !bytes.Equal(moved, orig) || cap(moved) != EdDSAPrivateLength {
+		t.FailNow()
+	}
+	sb, err := NewSecureBuffer(EdDSAPrivateLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sb.Free()
+	copy(sb.Bytes(), orig)
+	if !bytes.Equal(EdDSAPrivate(sb.Bytes()).EnableAutoWipe(), orig) {
+		t.FailNow()
+	}
+	wiped := make(chan []byte, 1)
+	func() {
+		autoWipe(EdDSAGenerateKey(), func(p *[EdDSAPrivateLength]byte) {
+			wiped <- append([]byte(nil), p[:]...)
+		})
+	}()
+	for i := 0; i < 50; i++ {
+		runtime.GC()
+		select {
+		case b := <-wiped:
+			if !isZero(b) {
+				t.FailNow()
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Skip("finalizer did not run")
+}
+
+func TestToken(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	token := priv.MakeToken([]byte(`{"sub":"alice"}`))
+	payload, err := priv.PublicKey().ParseToken(token)
+	if err != nil || string(payload) != `{"sub":"alice"}` {
+		t.FailNow()
+	}
+	if _, err := EdDSAGenerateKey().PublicKey().ParseToken(token); err != ErrSignatureInvalid {
+		t.FailNow()
+	}
+	forged := BinToBase64URL([]byte(`{"sub":"mallory"}`)) + token[strings.IndexByte(token, '.'):]
+	if _, err := priv.PublicKey().ParseToken(forged); err != ErrSignatureInvalid {
+		t.FailNow()
+	}
+	for _, bad := range []string{"", "nodot", "a+b." + BinToBase64URL(priv.Sign(nil)), BinToBase64URL(nil) + ".a=="} {
+		if _, err := priv.PublicKey().ParseToken(bad); !errors.Is(err, errMalformedToken) {
+			t.FailNow()
+		}
+	}
+	if _, err := priv.PublicKey().ParseToken(BinToBase64URL(nil) + ".AAAA"); !errors.Is(err, ErrSignatureInvalid) {
+		t.FailNow()
+	}
+}
+
+func TestVerifyAny(t *testing.T) {
+	keys := []EdDSAPrivate{EdDSAGenerateKey(), EdDSAGenerateKey(), EdDSAGenerateKey()}
+	message := []byte("Hello World")
+	// each round retires the oldest key and adds a new one
+	for round := 0; round < 3; round++ {
+		publics := []EdDSAPublic{keys[0].PublicKey(), keys[1].PublicKey(), keys[2].PublicKey()}
+		for _, priv := range keys {
+			publ, err := VerifyAny(publics, message, priv.Sign(message))
+			if err != nil || !publ.Equal(priv.PublicKey()) {
+				t.FailNow()
+			}
+		}
+		retired := keys[0]
+		keys = append(keys[1:], EdDSAGenerateKey())
+		publics = []EdDSAPublic{keys[0].PublicKey(), keys[1].PublicKey(), keys[2].PublicKey()}
+		if _, err := VerifyAny(publics, message, retired.Sign(message)); err != ErrSignatureInvalid {
+			t.FailNow()
+		}
+	}
+	if _, err := VerifyAny(nil, message, keys[0].Sign(message)); err != ErrSignatureInvalid {
+		t.FailNow()
+	}
+	both := []EdDSAPublic{keys[0].PublicKey()[1:], keys[0].PublicKey()}
+	if publ, err := VerifyAny(both, message, keys[0].Sign(message)); err != nil || !publ.Equal(both[1]) {
+		t.FailNow()
+	}
+}
+
+func TestSignMulti(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	publ := priv.PublicKey()
+	a, b, c := []byte("header"), RandomBytes(1000), []byte("trailer")
+	whole := append(append(append([]byte(nil), a...), b...), c...)
+	sig := priv.SignMulti(a, b, c)
+	if !bytes.Equal(sig, priv.Sign(whole)) || publ.Verify(whole, sig) != nil {
+		t.FailNow()
+	}
+	if !bytes.Equal(priv.SignMulti(), priv.Sign(nil)) || !bytes.Equal(priv.SignMulti(whole[:7], whole[7:]), sig) {
+		t.FailNow()
+	}
+	if publ.VerifyMulti(sig, a, b, c) != nil || publ.VerifyMulti(sig, whole) != nil {
+		t.FailNow()
+	}
+	if publ.VerifyMulti(sig, a, c, b) != ErrSignatureInvalid || publ.VerifyMulti(sig[1:], whole) == nil {
+		t.FailNow()
+	}
+	if EdDSAGenerateKey().PublicKey().VerifyMulti(sig, whole) != ErrSignatureInvalid {
+		t.FailNow()
+	}
+	for _, i := range []int{0, 31, 32, 63} {
+		bad := append([]byte(nil), sig...)
+		bad[i] ^= 1
+		if publ.VerifyMulti(bad, whole) == nil {
+			t.FailNow()
+		}
+	}
+	// S+l is accepted by neither
+	l, _ := HexToBin("edd3f55c1a631258d69cf7a2def9de1400000000000000000000000000000010")
+	high := append([]byte(nil), sig...)
+	carry := 0
+	for i := 0; i < 32; i++ {
+		v := int(high[32+i]) + int(l[i]) + carry
+		high[32+i], carry = byte(v), v>>8
+	}
+	if publ.Verify(whole, high) == nil || publ.VerifyMulti(high, whole) == nil {
+		t.FailNow()
+	}
+}
+
+func TestVerifyMultiTorsionKey(t *testing.T) {
+	// a public key A+T, with T of order 8, is outside the prime-order
+	// subgroup; Verify accepts signatures under it whenever [k]T vanishes
+	priv := EdDSAGenerateKey()
+	h := SHA512(priv.Seed())
+	h[0] &= 248
+	h[31] &= 127
+	h[31] |= 64
+	a, _ := Ed25519ScalarReduce(append(h[:32], make([]byte, 32)...))
+	torsion, _ := HexToBin("26e8958fc2b227b045c3f489f2ef98f0d5dfac05d3c63339b13802886d53fc05")
+	mixed, ok := ed25519AddUnchecked(priv.PublicKey(), torsion)
+	if !ok || Ed25519IsValidPoint(mixed) {
+		t.FailNow()
+	}
+	publ := EdDSAPublic(mixed)
+	accepted, rejected := 0, 0
+	for i := 0; i < 128; i++ {
+		message := RandomBytes(16)
+		nonce := Ed25519ScalarRandom()
+		r, _ := Ed25519ScalarMultBase(nonce)
+		k, _ := Ed25519ScalarReduce(SHA512(append(append(append([]byte(nil), r...), publ...), message...)))
+		ka, _ := Ed25519ScalarMul(k, a)
+		s, _ := Ed25519ScalarAdd(nonce, ka)
+		signature := append(r, s...)
+		verr := publ.Verify(message, signature)
+		if (verr == nil) != (publ.VerifyMulti(signature, message[:5], message[5:]) == nil) {
+			t.FailNow()
+		}
+		if verr == nil {
+			accepted++
+		} else {
+			rejected++
+		}
+	}
+	if accepted == 0 || rejected == 0 {
+		t.FailNow()
+	}
+}
+
+func TestSignatureCanonical(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	message := []byte("Hello World")
+	signature := priv.Sign(message)
+	if !SignatureCanonical(signature) || SignatureCanonical(signature[1:]) {
+		t.FailNow()
+	}
+	// the malleable variant with S+l verifies under neither
+	l, _ := HexToBin("edd3f55c1a631258d69cf7a2def9de1400000000000000000000000000000010")
+	malleable := append([]byte(nil), signature...)
+	carry := 0
+	for i := 0; i < 32; i++ {
+		v := int(malleable[32+i]) + int(l[i]) + carry
+		malleable[32+i], carry = byte(v), v>>8
+	}
+	if SignatureCanonical(malleable) || priv.PublicKey().Verify(message, malleable) == nil {
+		t.FailNow()
+	}
+	s := signature[32:]
+	for _, r := range []string{
+		// the identity, a point of order 8, and y = p+3 for a point with y = 3
+		"0100000000000000000000000000000000000000000000000000000000000000",
+		"26e8958fc2b227b045c3f489f2ef98f0d5dfac05d3c63339b13802886d53fc05",
+		"f0ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+	} {
+		bad, _ := HexToBin(r)
+		if SignatureCanonical(append(bad, s...)) {
+			t.FailNow()
+		}
+	}
+	three := make([]byte, 32)
+	three[0] = 3
+	if !SignatureCanonical(append(three, s...)) {
+		t.FailNow()
+	}
+}
+
+func TestGenerateEdDSAKeys(t *testing.T) {
+	pairs, err := GenerateEdDSAKeys(10)
+	if err != nil || len(pairs) != 10 {
+		t.FailNow()
+	}
+	secure, destroy, err := GenerateEdDSAKeysSecure(10)
+	if err != nil || len(secure) != 10 {
+		t.FailNow()
+	}
+	defer destroy()
+	message := []byte("Hello World")
+	for i, pair := range append(pairs, secure...) {
+		if !pair.Public.Equal(pair.Private.PublicKey()) || pair.Public.Verify(message, pair.Private.Sign(message)) != nil {
+			t.FailNow()
+		}
+		if i > 0 && pair.Public.Equal(pairs[0].Public) {
+			t.FailNow()
+		}
+	}
+	// destroying one key leaves its neighbours alone
+	pairs[3].Private.Destroy()
+	if !isZero(pairs[3].Private) || isZero(pairs[2].Private) || isZero(pairs[4].Private) {
+		t.FailNow()
+	}
+	if empty, err := GenerateEdDSAKeys(0); err != nil || len(empty) != 0 {
+		t.FailNow()
+	}
+	if _, err := GenerateEdDSAKeys(-1); err == nil {
+		t.FailNow()
+	}
+	if empty, destroy, err := GenerateEdDSAKeysSecure(0); err != nil || len(empty) != 0 {
+		t.FailNow()
+	} else {
+		destroy()
+	}
+	if _, _, err := GenerateEdDSAKeysSecure(-1); err == nil {
+		t.FailNow()
+	}
+	secure, destroy, err = GenerateEdDSAKeysSecure(2)
+	if err != nil {
+		t.FailNow()
+	}
+	destroy()
+	destroy()
+	if secure[0].Private != nil || secure[1].Private != nil {
+		t.FailNow()
+	}
+}
+
+func TestGenerateEdDSAKeysSecureGC(t *testing.T) {
+	// without destroy, nothing tracked by the collector refers to the buffer,
+	// which must then stay allocated rather than be freed under the keys (the
+	// buffer is leaked, which is the documented cost of dropping destroy)
+	pairs, _, err := GenerateEdDSAKeysSecure(2)
+	if err != nil {
+		t.FailNow()
+	}
+	runtime.GC()
+	runtime.GC()
+	message := []byte("Hello World")
+	if pairs[1].Public.Verify(message, pairs[1].Private.Sign(message)) != nil {
+		t.FailNow()
+	}
+}
+
+func BenchmarkGenerateEdDSAKeys(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		GenerateEdDSAKeys(100)
+	}
+}
+
+func BenchmarkGenerateEdDSAKeys_Secure(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_, destroy, _ := GenerateEdDSAKeysSecure(100)
+		destroy()
+	}
+}
+
+func BenchmarkGenerateEdDSAKeys_Loop(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 100; j++ {
+			GenerateEdDSAKeyPair()
+		}
+	}
+}
+
+func BenchmarkGenerateEdDSAKeys_SecureLoop(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 100; j++ {
+			sb, _ := NewSecureBuffer(EdDSAPrivateLength)
+			copy(sb.Bytes(), EdDSAGenerateKey())
+			sb.Free()
+		}
+	}
+}
+
+func TestEdDSAPrivateString(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	if priv.String() != "dsaprv:<redacted,len=64>" {
+		t.FailNow()
+	}
+	for _, verb := range []string{"%v", "%s", "%x", "%X", "%d", "%#v", "%+v", "%q"} {
+		out := fmt.Sprintf(verb, priv)
+		if strings.Contains(strings.ToLower(out), BinToHex(priv)) || out != priv.String() {
+			t.FailNow()
+		}
+	}
+	if priv.HexString() != "dsaprv:"+BinToHex(priv) {
+		t.FailNow()
+	}
+}
+
+func TestEdDSAEncode(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	publ := priv.PublicKey()
+	checkEncoding(t, []encodingCase{
+		{publ, EdDSAPublicPEMType, publ.Encode, func(b []byte, e Encoding) ([]byte, error) { return DecodeEdDSAPublic(b, e) }},
+		{priv, EdDSAPrivatePEMType, priv.Encode, func(b []byte, e Encoding) ([]byte, error) { return DecodeEdDSAPrivate(b, e) }},
+	})
+	if _, err := EdDSAPublic(priv).Encode(EncodingHex); !errors.Is(err, ErrInvalidKeyLength) {
+		t.FailNow()
+	}
+	if EncodingPEM.String() != "PEM" || Encoding(99).String() != "Encoding(99)" {
+		t.FailNow()
+	}
+}
+
+func TestSignatureConcurrent(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	publ := priv.PublicKey()
+	const workers, rounds = 16, 50
+	signatures := make([][][]byte, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			signer := priv.NewSigner()
+			for i := 0; i < rounds; i++ {
+				message := []byte(fmt.Sprint(i))
+				signatures[w] = append(signatures[w], priv.Sign(message))
+				signer.Write(message)
+			}
+			signed, _ := signer.Sign()
+			signatures[w] = append(signatures[w], signed)
+		}(w)
+	}
+	wg.Wait()
+	// each goroutine checks the signatures made by another, concurrently;
+	// FailNow may not be called off the test goroutine, so failures are
+	// reported with Errorf
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			theirs := signatures[(w+1)%workers]
+			verifier := publ.NewVerifier()
+			for i := 0; i < rounds; i++ {
+				message := []byte(fmt.Sprint(i))
+				// Ed25519 is deterministic, so every goroutine got the same bytes
+				if publ.Verify(message, theirs[i]) != nil || !bytes.Equal(theirs[i], signatures[w][i]) {
+					t.Errorf("worker %v: signature %v did not verify", w, i)
+				}
+				verifier.Write(message)
+			}
+			if verifier.Verify(theirs[rounds]) != nil {
+				t.Errorf("worker %v: streamed signature did not verify", w)
+			}
+		}(w)
+	}
+	wg.Wait()
+}
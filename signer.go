@@ -0,0 +1,266 @@
+package natrium
+
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #include <stdio.h>
+// #include <sodium.h>
+import "C"
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var errStateFinalized = errors.New("multi-part signature state already finalized")
+
+// Signer incrementally computes an EdDSA signature over everything written to
+// it. It implements io.Writer, so a large input can simply be io.Copy'd in.
+//
+// Multi-part signing uses the Ed25519ph (prehashed) mode, so signatures
+// produced by a Signer must be checked with a Verifier rather than
+// EdDSAPublic.Verify.
+//
+// A Signer is not safe for concurrent use. Goroutines that sign in parallel
+// should each have their own, which is cheap, since the key is shared rather
+// than copied.
+type Signer struct {
+	state C.crypto_sign_state
+	key   EdDSAPrivate
+	done  bool
+}
+
+// NewSigner creates a Signer that signs with the given private key. A key of
+// the wrong length makes Sign return an error wrapping ErrInvalidKeyLength,
+// while a key wiped by Destroy panics at once.
+func (k EdDSAPrivate) NewSigner() *Signer {
+	if len(k) == EdDSAPrivateLength && isZero(k) {
+		panic(errEdDSADestroyed.Error())
+	}
+	toret := &Signer{key: k}
+	rv := C.crypto_sign_init(&toret.state)
+	if rv != 0 {
+		panic("crypto_sign_init returned non-zero")
+	}
+	return toret
+}
+
+// Write adds more data to the message being signed.
+func (s *Signer) Write(b []byte) (int, error) {
+	if s.done {
+		return 0, errStateFinalized
+	}
+	rv := C.crypto_sign_update(&s.state, g2cbt(b), C.ulonglong(len(b)))
+	if rv != 0 {
+		panic("crypto_sign_update returned non-zero")
+	}
+	return len(b), nil
+}
+
+// Sign returns the signature of everything written so far. A Signer can only
+// be used once; calling Sign again returns an error.
+func (s *Signer) Sign() ([]byte, error) {
+	if s.done {
+		return nil, errStateFinalized
+	}
+	s.done = true
+	if err := s.key.checkKey(); err != nil {
+		return nil, err
+	}
+	signature := make([]byte, EdDSASignatureLength)
+	rv := C.crypto_sign_final_create(&s.state, g2cbt(signature), nil, g2cbt(s.key))
+	if rv != 0 {
+		panic("crypto_sign_final_create returned non-zero")
+	}
+	return signature, nil
+}
+
+// signerStateVersion starts every serialized Signer state, in case the format
+// ever has to change.
+const signerStateVersion = 1
+
+// signerStateLength is the length of a serialized Signer state: the version,
+// then the eight SHA-512 chaining words, the two words of the bit count and the
+// 128-byte block buffer.
+const signerStateLength = 1 + 8*8 + 2*8 + 128
+
+// MarshalState serializes the progress of the Signer, so that signing can
+// resume later, even in another process, with RestoreSigner. The Signer can
+// go on being used afterwards.
+//
+// The state is the SHA-512 state of the message so far, including up to 127
+// of its most recent bytes in the clear, so it is as sensitive as the message
+// itself. Above all it must be protected from tampering, since whoever can
+// alter the state can make the key holder sign a message of their choosing:
+// store it sealed with an AEAD, never as plain bytes on disk. The private key
+// is not included and must be supplied again to RestoreSigner.
+func (s *Signer) MarshalState() ([]byte, error) {
+	if s.done {
+		return nil, errStateFinalized
+	}
+	hs := &s.state.hs
+	out := make([]byte, signerStateLength)
+	out[0] = signerStateVersion
+	for i := 0; i < 8; i++ {
+		binary.BigEndian.PutUint64(out[1+8*i:], uint64(hs.state[i]))
+	}
+	binary.BigEndian.PutUint64(out[65:], uint64(hs.count[0]))
+	binary.BigEndian.PutUint64(out[73:], uint64(hs.count[1]))
+	for i := 0; i < 128; i++ {
+		out[81+i] = byte(hs.buf[i])
+	}
+	return out, nil
+}
+
+// RestoreSigner creates a Signer that signs with k, picking up where the
+// Signer whose MarshalState produced state left off. An error is returned if
+// state is not a serialized Signer state.
+func (k EdDSAPrivate) RestoreSigner(state []byte) (*Signer, error) {
+	if len(state) != signerStateLength || state[0] != signerStateVersion {
+		return nil, errors.New("malformed Signer state")
+	}
+	toret := k.NewSigner()
+	hs := &toret.state.hs
+	for i := 0; i < 8; i++ {
+		hs.state[i] = C.uint64_t(binary.BigEndian.Uint64(state[1+8*i:]))
+	}
+	hs.count[0] = C.uint64_t(binary.BigEndian.Uint64(state[65:]))
+	hs.count[1] = C.uint64_t(binary.BigEndian.Uint64(state[73:]))
+	if hs.count[1]%8 != 0 {
+		return nil, errors.New("malformed Signer state")
+	}
+	for i := 0; i < 128; i++ {
+		hs.buf[i] = C.uint8_t(state[81+i])
+	}
+	return toret, nil
+}
+
+// Verifier incrementally checks a signature produced by a Signer. Like Signer,
+// it implements io.Writer, and must not be shared between goroutines.
+type Verifier struct {
+	state C.crypto_sign_state
+	key   EdDSAPublic
+	done  bool
+}
+
+// NewVerifier creates a Verifier that checks signatures against the given
+// public key. A key of the wrong length makes Verify return an error wrapping
+// ErrInvalidKeyLength.
+func (k EdDSAPublic) NewVerifier() *Verifier {
+	toret := &Verifier{key: k}
+	rv := C.crypto_sign_init(&toret.state)
+	if rv != 0 {
+		panic("crypto_sign_init returned non-zero")
+	}
+	return toret
+}
+
+// Write adds more data to the message being verified.
+func (v *Verifier) Write(b []byte) (int, error) {
+	if v.done {
+		return 0, errStateFinalized
+	}
+	rv := C.crypto_sign_update(&v.state, g2cbt(b), C.ulonglong(len(b)))
+	if rv != 0 {
+		panic("crypto_sign_update returned non-zero")
+	}
+	return len(b), nil
+}
+
+// Verify checks the signature against everything written so far, returning
+// nil if it is valid. A Verifier can only be used once; calling Verify again
+// returns an error.
+func (v *Verifier) Verify(signature []byte) error {
+	if v.done {
+		return errStateFinalized
+	}
+	v.done = true
+	if len(v.key) != EdDSAPublicLength {
+		return keyLengthError("EdDSA public key", len(v.key), EdDSAPublicLength)
+	}
+	if len(signature) != EdDSASignatureLength {
+		return fmt.Errorf("%w: signature is %v bytes instead of %v", ErrSignatureInvalid,
+			len(signature), EdDSASignatureLength)
+	}
+	rv := C.crypto_sign_final_verify(&v.state, g2cbt(signature), g2cbt(v.key))
+	if rv != 0 {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+// SignReader signs everything read from r until EOF with Ed25519ph, as if it
+// had been copied into a Signer. An error from r is returned unchanged.
+func (k EdDSAPrivate) SignReader(r io.Reader) ([]byte, error) {
+	s := k.NewSigner()
+	if _, err := io.Copy(s, r); err != nil {
+		return nil, err
+	}
+	return s.Sign()
+}
+
+// VerifyReader checks a signature produced by a Signer over everything read
+// from r until EOF, without buffering it. An error from r is returned as-is,
+// so it can be told apart from a forged signature.
+func (k EdDSAPublic) VerifyReader(r io.Reader, signature []byte) error {
+	v := k.NewVerifier()
+	if _, err := io.Copy(v, r); err != nil {
+		return err
+	}
+	return v.Verify(signature)
+}
+
+// SignFile signs the contents of the file at path with SignReader, reading it
+// in pieces rather than all at once. The file is closed before SignFile
+// returns, and an error opening or reading it is returned unchanged.
+func (k EdDSAPrivate) SignFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return k.SignReader(f)
+}
+
+// VerifyFile checks a signature made by SignFile, SignReader or a Signer
+// against the contents of the file at path. As with VerifyReader, a forged
+// signature gives ErrSignatureInvalid and a failure to open or read the file
+// gives the *os.PathError or read error, so errors.Is tells the two apart.
+// The file is always closed.
+func (k EdDSAPublic) VerifyFile(path string, signature []byte) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return k.VerifyReader(f, signature)
+}
+
+// SignEd25519ph signs message with Ed25519ph in one call, giving the same
+// signature as a Signer that has had message written to it. Ed25519ph
+// signatures are not interchangeable with those from Sign: each only checks
+// with its own verification function.
+//
+// message is the message itself, not a digest of it. libsodium computes the
+// SHA-512 prehash internally and has no way to accept one computed elsewhere,
+// so passing a digest hashes it a second time, and the signature then matches
+// neither the original message nor what other Ed25519ph implementations
+// produce from that digest.
+func (k EdDSAPrivate) SignEd25519ph(message []byte) []byte {
+	s := k.NewSigner()
+	s.Write(message)
+	signature, err := s.Sign()
+	if err != nil {
+		panic(err.Error())
+	}
+	return signature
+}
+
+// VerifyEd25519ph checks an Ed25519ph signature produced by SignEd25519ph or
+// a Signer, returning nil if it is valid. As with SignEd25519ph, message is
+// the full message, which is hashed here, not a digest of it.
+func (k EdDSAPublic) VerifyEd25519ph(message, signature []byte) error {
+	v := k.NewVerifier()
+	v.Write(message)
+	return v.Verify(signature)
+}
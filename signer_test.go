@@ -0,0 +1,222 @@
+package natrium
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSignerStreaming(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	message := make([]byte, 100000)
+	RandBytes(message)
+	signer := priv.NewSigner()
+	io.Copy(signer, bytes.NewReader(message))
+	signature, err := signer.Sign()
+	if err != nil {
+		t.FailNow()
+	}
+	verifier := priv.PublicKey().NewVerifier()
+	for i := 0; i < len(message); i += 999 {
+		end := i + 999
+		if end > len(message) {
+			end = len(message)
+		}
+		verifier.Write(message[i:end])
+	}
+	if verifier.Verify(signature) != nil {
+		t.FailNow()
+	}
+}
+
+func TestSignerFinalized(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	signer := priv.NewSigner()
+	signer.Write([]byte("Hello World"))
+	signature, _ := signer.Sign()
+	if _, err := signer.Sign(); err == nil {
+		t.FailNow()
+	}
+	if _, err := signer.Write([]byte("more")); err == nil {
+		t.FailNow()
+	}
+	verifier := priv.PublicKey().NewVerifier()
+	verifier.Write([]byte("Hello World"))
+	if verifier.Verify(signature) != nil {
+		t.FailNow()
+	}
+	if verifier.Verify(signature) == nil {
+		t.FailNow()
+	}
+}
+
+func TestVerifierFail(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	signer := priv.NewSigner()
+	signer.Write([]byte("Hello World"))
+	signature, _ := signer.Sign()
+	verifier := priv.PublicKey().NewVerifier()
+	verifier.Write([]byte("Hello Wortd"))
+	if verifier.Verify(signature) == nil {
+		t.FailNow()
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestSignerKeyLength(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	for _, bad := range []EdDSAPrivate{nil, priv[:32]} {
+		signer := bad.NewSigner()
+		signer.Write([]byte("Hello World"))
+		if _, err := signer.Sign(); !errors.Is(err, ErrInvalidKeyLength) {
+			t.FailNow()
+		}
+	}
+	for _, bad := range []EdDSAPublic{nil, priv.PublicKey()[:31]} {
+		verifier := bad.NewVerifier()
+		verifier.Write([]byte("Hello World"))
+		if err := verifier.Verify(make([]byte, EdDSASignatureLength)); !errors.Is(err, ErrInvalidKeyLength) {
+			t.FailNow()
+		}
+	}
+}
+
+func TestVerifyReader(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	message := RandomBytes(50000)
+	signer := priv.NewSigner()
+	signer.Write(message)
+	signature, _ := signer.Sign()
+	publ := priv.PublicKey()
+	if publ.VerifyReader(bytes.NewReader(message), signature) != nil {
+		t.FailNow()
+	}
+	message[0] ^= 1
+	if publ.VerifyReader(bytes.NewReader(message), signature) == nil {
+		t.FailNow()
+	}
+	if publ.VerifyReader(failingReader{}, signature) != io.ErrClosedPipe {
+		t.FailNow()
+	}
+}
+
+func TestSignEd25519ph(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	publ := priv.PublicKey()
+	message := []byte("Hello World")
+	signature := priv.SignEd25519ph(message)
+	if publ.VerifyEd25519ph(message, signature) != nil {
+		t.FailNow()
+	}
+	signer := priv.NewSigner()
+	signer.Write(message)
+	streamed, _ := signer.Sign()
+	if !bytes.Equal(streamed, signature) {
+		t.FailNow()
+	}
+	// Ed25519ph and plain Ed25519 do not mix
+	if publ.Verify(message, signature) == nil {
+		t.FailNow()
+	}
+	if publ.VerifyEd25519ph(message, priv.Sign(message)) == nil {
+		t.FailNow()
+	}
+	// a digest passed in is hashed again, so it signs something else
+	if publ.VerifyEd25519ph(message, priv.SignEd25519ph(SHA512(message))) == nil {
+		t.FailNow()
+	}
+}
+
+func TestSignReader(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	message := RandomBytes(70000)
+	signature, err := priv.SignReader(bytes.NewReader(message))
+	if err != nil {
+		t.FailNow()
+	}
+	if priv.PublicKey().VerifyReader(bytes.NewReader(message), signature) != nil {
+		t.FailNow()
+	}
+	if _, err := priv.SignReader(failingReader{}); err != io.ErrClosedPipe {
+		t.FailNow()
+	}
+}
+
+func TestSignerResume(t *testing.T) {
+	priv := EdDSAGenerateKey()
+	message := RandomBytes(100001)
+	signer := priv.NewSigner()
+	signer.Write(message[:54321])
+	state, err := signer.MarshalState()
+	if err != nil {
+		t.FailNow()
+	}
+	resumed, err := priv.RestoreSigner(state)
+	if err != nil {
+		t.FailNow()
+	}
+	resumed.Write(message[54321:])
+	signature, _ := resumed.Sign()
+	if !bytes.Equal(signature, priv.SignEd25519ph(message)) || priv.PublicKey().VerifyEd25519ph(message, signature) != nil {
+		t.FailNow()
+	}
+	// the original can carry on too
+	signer.Write(message[54321:])
+	if again, _ := signer.Sign(); !bytes.Equal(again, signature) {
+		t.FailNow()
+	}
+	if _, err := signer.MarshalState(); err == nil {
+		t.FailNow()
+	}
+	if _, err := priv.RestoreSigner(state[1:]); err == nil {
+		t.FailNow()
+	}
+	state[0] = 2
+	if _, err := priv.RestoreSigner(state); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestSignFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "message")
+	message := RandomBytes(100000)
+	if os.WriteFile(path, message, 0600) != nil {
+		t.FailNow()
+	}
+	priv := EdDSAGenerateKey()
+	publ := priv.PublicKey()
+	signature, err := priv.SignFile(path)
+	if err != nil || publ.VerifyFile(path, signature) != nil {
+		t.FailNow()
+	}
+	if publ.VerifyReader(bytes.NewReader(message), signature) != nil {
+		t.FailNow()
+	}
+	message[500] ^= 1
+	if os.WriteFile(path, message, 0600) != nil {
+		t.FailNow()
+	}
+	if err := publ.VerifyFile(path, signature); !errors.Is(err, ErrSignatureInvalid) {
+		t.FailNow()
+	}
+	missing := filepath.Join(dir, "missing")
+	if err := publ.VerifyFile(missing, signature); !os.IsNotExist(err) || errors.Is(err, ErrSignatureInvalid) {
+		t.FailNow()
+	}
+	if _, err := priv.SignFile(missing); !os.IsNotExist(err) {
+		t.FailNow()
+	}
+	// a directory opens but cannot be read
+	if err := publ.VerifyFile(dir, signature); err == nil || errors.Is(err, ErrSignatureInvalid) {
+		t.FailNow()
+	}
+}
@@ -1,8 +1,6 @@
 package natrium
 
 // #cgo darwin CFLAGS: -I/usr/local/include
-// #cgo darwin LDFLAGS: /usr/local/lib/libsodium.a
-// #cgo linux windows android LDFLAGS: -Wl,-Bstatic -lsodium -Wl,-Bdynamic
 // #include <stdio.h>
 // #include <sodium.h>
 import "C"
@@ -44,8 +42,103 @@ func (ctx *natrStream) XORKeyStream(dst, src []byte) {
 	ctx.buffer = make([]byte, 16384)
 	buffhand := (*C.uchar)(&ctx.buffer[0])
 	C.crypto_stream_chacha20_xor_ic(buffhand, buffhand,
-		C.ulonglong(16384), (*C.uchar)(&ctx.nonce[0]),
-		C.uint64_t(ctx.blockcnt), (*C.uchar)(&ctx.key[0]))
+		C.ulonglong(16384), (*C.uchar)(g2cbt(ctx.nonce[:])),
+		C.uint64_t(ctx.blockcnt), (*C.uchar)(g2cbt(ctx.key[:])))
 	ctx.blockcnt++
 	ctx.XORKeyStream(dst, src)
 }
+
+func checkXSalsa20(nonce, key []byte) {
+	if len(key) != C.crypto_stream_KEYBYTES {
+		panic("XSalsa20 key has the wrong length")
+	}
+	if len(nonce) != C.crypto_stream_NONCEBYTES {
+		panic("XSalsa20 nonce has the wrong length")
+	}
+}
+
+// StreamXSalsa20 fills out with XSalsa20 keystream for the given 24-byte nonce
+// and 32-byte key.
+//
+// A raw stream cipher provides NO authentication: an attacker can flip bits
+// of the plaintext undetected. SecretKey should be preferred for almost all
+// uses.
+func StreamXSalsa20(out, nonce, key []byte) {
+	checkXSalsa20(nonce, key)
+	rv := C.crypto_stream(g2cbt(out), C.ulonglong(len(out)), g2cbt(nonce), g2cbt(key))
+	if rv != 0 {
+		panic("crypto_stream returned non-zero")
+	}
+}
+
+// StreamXSalsa20XOR returns the message XORed with the XSalsa20 keystream for
+// the given nonce and key. It is its own inverse, and has the same lack of
+// authentication as StreamXSalsa20.
+func StreamXSalsa20XOR(message, nonce, key []byte) []byte {
+	checkXSalsa20(nonce, key)
+	out := make([]byte, len(message))
+	rv := C.crypto_stream_xor(g2cbt(out), g2cbt(message), C.ulonglong(len(message)),
+		g2cbt(nonce), g2cbt(key))
+	if rv != 0 {
+		panic("crypto_stream_xor returned non-zero")
+	}
+	return out
+}
+
+type xchachaStream struct {
+	key     [32]byte
+	nonce   [24]byte
+	counter uint64
+	block   [64]byte
+	used    int
+}
+
+// NewXChaCha20Stream creates an XChaCha20 streamer from a 32-byte key and a
+// 24-byte nonce. XORKeyStream may be called with buffers of any size; the
+// position within the keystream is tracked across calls. Like the other raw
+// stream ciphers, it provides no authentication.
+func NewXChaCha20Stream(key, nonce []byte) (cipher.Stream, error) {
+	if len(key) != C.crypto_stream_xchacha20_KEYBYTES {
+		return nil, keyLengthError("XChaCha20 key", len(key), C.crypto_stream_xchacha20_KEYBYTES)
+	}
+	if len(nonce) != C.crypto_stream_xchacha20_NONCEBYTES {
+		return nil, ErrInvalidNonceLength
+	}
+	toret := new(xchachaStream)
+	copy(toret.key[:], key)
+	copy(toret.nonce[:], nonce)
+	toret.used = len(toret.block)
+	return toret, nil
+}
+
+func (ctx *xchachaStream) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("output smaller than input")
+	}
+	// use up what is left of the current block
+	n := 0
+	for ; n < len(src) && ctx.used < len(ctx.block); n++ {
+		dst[n] = src[n] ^ ctx.block[ctx.used]
+		ctx.used++
+	}
+	// whole blocks go straight through libsodium
+	whole := (len(src) - n) / 64 * 64
+	if whole > 0 {
+		C.crypto_stream_xchacha20_xor_ic(g2cbt(dst[n:]), g2cbt(src[n:]), C.ulonglong(whole),
+			g2cbt(ctx.nonce[:]), C.uint64_t(ctx.counter), g2cbt(ctx.key[:]))
+		ctx.counter += uint64(whole / 64)
+		n += whole
+	}
+	// and the tail comes out of a fresh block
+	if n < len(src) {
+		ctx.block = [64]byte{}
+		C.crypto_stream_xchacha20_xor_ic(g2cbt(ctx.block[:]), g2cbt(ctx.block[:]), 64,
+			g2cbt(ctx.nonce[:]), C.uint64_t(ctx.counter), g2cbt(ctx.key[:]))
+		ctx.counter++
+		ctx.used = 0
+		for ; n < len(src); n++ {
+			dst[n] = src[n] ^ ctx.block[ctx.used]
+			ctx.used++
+		}
+	}
+}
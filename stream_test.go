@@ -42,3 +42,47 @@ func BenchmarkStream_AES(b *testing.B) {
 		lol.XORKeyStream(haha, haha)
 	}
 }
+
+func TestStreamXSalsa20(t *testing.T) {
+	key := RandomBytes(32)
+	nonce := RandomBytes(24)
+	message := []byte("Hello World")
+	keystream := make([]byte, len(message))
+	StreamXSalsa20(keystream, nonce, key)
+	ciphertext := StreamXSalsa20XOR(message, nonce, key)
+	for i := range message {
+		if ciphertext[i] != message[i]^keystream[i] {
+			t.FailNow()
+		}
+	}
+	if string(StreamXSalsa20XOR(ciphertext, nonce, key)) != "Hello World" {
+		t.FailNow()
+	}
+}
+
+func TestXChaCha20Stream(t *testing.T) {
+	key := RandomBytes(32)
+	nonce := RandomBytes(24)
+	whole, err := NewXChaCha20Stream(key, nonce)
+	if err != nil {
+		t.FailNow()
+	}
+	expected := make([]byte, 1000)
+	whole.XORKeyStream(expected, expected)
+	pieces, _ := NewXChaCha20Stream(key, nonce)
+	got := make([]byte, 1000)
+	for i, size := 0, 1; i < len(got); size = size*3 + 1 {
+		end := i + size
+		if end > len(got) {
+			end = len(got)
+		}
+		pieces.XORKeyStream(got[i:end], got[i:end])
+		i = end
+	}
+	if CTCompare(got, expected) != 0 {
+		t.FailNow()
+	}
+	if _, err := NewXChaCha20Stream(key, nonce[:8]); err == nil {
+		t.FailNow()
+	}
+}
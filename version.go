@@ -0,0 +1,23 @@
+package natrium
+
+// #cgo darwin CFLAGS: -I/usr/local/include
+// #include <stdio.h>
+// #include <sodium.h>
+import "C"
+
+// Version returns the version string of the linked libsodium, such as
+// "1.0.18". It is mostly useful in bug reports.
+func Version() string {
+	return C.GoString(C.sodium_version_string())
+}
+
+// VersionMajor returns the major library version of the linked libsodium.
+// Note that this is libsodium's ABI version, not the first number of Version.
+func VersionMajor() int {
+	return int(C.sodium_library_version_major())
+}
+
+// VersionMinor returns the minor library version of the linked libsodium.
+func VersionMinor() int {
+	return int(C.sodium_library_version_minor())
+}
@@ -0,0 +1,15 @@
+package natrium
+
+import "testing"
+
+func TestVersion(t *testing.T) {
+	if Version() == "" {
+		t.FailNow()
+	}
+	if VersionMajor() < 10 {
+		t.FailNow()
+	}
+	if VersionMinor() < 0 {
+		t.FailNow()
+	}
+}